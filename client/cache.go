@@ -0,0 +1,137 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"github.com/savageking-io/ogbuser/proto"
+	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
+)
+
+// watchRetryDelay is how long PermissionCache waits before subscribing again after the stream failed
+const watchRetryDelay = 5 * time.Second
+
+// permissionKey identifies a permission query
+type permissionKey struct {
+	permission string
+	domain     string
+	ownerId    int32
+	partyId    int32
+	guildId    int32
+}
+
+func keyOf(query *proto.PermissionQuery) permissionKey {
+	return permissionKey{
+		permission: query.Permission,
+		domain:     query.Domain,
+		ownerId:    query.ResourceOwnerId,
+		partyId:    query.PartyId,
+		guildId:    query.GuildId,
+	}
+}
+
+// PermissionCache keeps results of permission checks until the user service reports them stale over
+// WatchPermissions. Results are served from the cache only while the subscription is live, otherwise every
+// check goes to the service. The cache doesn't know group membership, so any change of groups drops it all
+type PermissionCache struct {
+	client     *Client
+	service    string
+	entries    map[int32]map[permissionKey]*proto.PermissionResult
+	generation uint64 // Increased by every invalidation, so results of checks racing with one are not stored
+	live       bool
+	mutex      sync.RWMutex
+}
+
+// NewPermissionCache creates a cache on top of the client. Service is the name of the subscriber
+// reported to the user service. The cache starts serving cached results once Run subscribes
+func NewPermissionCache(client *Client, service string) *PermissionCache {
+	return &PermissionCache{
+		client:  client,
+		service: service,
+		entries: make(map[int32]map[permissionKey]*proto.PermissionResult),
+	}
+}
+
+// Run subscribes to permission invalidations and subscribes again whenever the stream fails.
+// Blocks until ctx is done
+func (c *PermissionCache) Run(ctx context.Context) error {
+	for {
+		err := c.client.WatchPermissions(ctx, c.service, func(invalidation *proto.PermissionInvalidation) {
+			c.Invalidate(invalidation)
+			c.setLive(true)
+		})
+		// Invalidations are missed until the next subscription
+		c.setLive(false)
+		c.Flush()
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Errorf("User::PermissionCache: permission stream failed: %v", err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(watchRetryDelay):
+		}
+	}
+}
+
+// Check returns the result of the query for the user, from the cache when possible
+func (c *PermissionCache) Check(ctx context.Context, userId int32, query *proto.PermissionQuery) (*proto.PermissionResult, error) {
+	key := keyOf(query)
+
+	c.mutex.RLock()
+	live, generation := c.live, c.generation
+	result, ok := c.entries[userId][key]
+	c.mutex.RUnlock()
+	if live && ok {
+		return result, nil
+	}
+
+	results, err := c.client.CheckPermissions(ctx, []int32{userId}, []*proto.PermissionQuery{query})
+	if err != nil {
+		return nil, err
+	}
+	if len(results[userId]) != 1 {
+		return nil, fmt.Errorf("expected 1 result for user %d, got %d", userId, len(results[userId]))
+	}
+	result = results[userId][0]
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.live && c.generation == generation {
+		if c.entries[userId] == nil {
+			c.entries[userId] = make(map[permissionKey]*proto.PermissionResult)
+		}
+		c.entries[userId][key] = result
+	}
+	return result, nil
+}
+
+// Invalidate drops results the invalidation reports stale. Services that also consume
+// permissions.invalidated Kafka events can pass them here
+func (c *PermissionCache) Invalidate(invalidation *proto.PermissionInvalidation) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.generation++
+	if invalidation.All || len(invalidation.GroupIds) > 0 {
+		c.entries = make(map[int32]map[permissionKey]*proto.PermissionResult)
+		return
+	}
+	for _, userId := range invalidation.UserIds {
+		delete(c.entries, userId)
+	}
+}
+
+// Flush drops every cached result
+func (c *PermissionCache) Flush() {
+	c.Invalidate(&proto.PermissionInvalidation{All: true})
+}
+
+func (c *PermissionCache) setLive(live bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.live = live
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"github.com/savageking-io/ogbuser/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"testing"
+)
+
+// countingClient answers CheckPermissions with read access and counts the calls
+type countingClient struct {
+	proto.UserServiceClient
+	calls int
+}
+
+func (c *countingClient) CheckPermissions(ctx context.Context, in *proto.CheckPermissionsRequest, opts ...grpc.CallOption) (*proto.CheckPermissionsResponse, error) {
+	c.calls++
+	response := &proto.CheckPermissionsResponse{}
+	for _, userId := range in.UserIds {
+		response.Users = append(response.Users, &proto.UserPermissionResults{UserId: userId, Results: []*proto.PermissionResult{{Read: 1}}})
+	}
+	return response, nil
+}
+
+func TestPermissionCache_Check(t *testing.T) {
+	conn, err := grpc.NewClient("passthrough:///user", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	query := &proto.PermissionQuery{Permission: "chat", Domain: "global"}
+	tests := []struct {
+		name      string
+		live      bool
+		between   *proto.PermissionInvalidation
+		wantCalls int
+	}{
+		{"Not subscribed", false, nil, 2},
+		{"Cached", true, nil, 1},
+		{"User invalidated", true, &proto.PermissionInvalidation{UserIds: []int32{1}}, 2},
+		{"Other user invalidated", true, &proto.PermissionInvalidation{UserIds: []int32{2}}, 1},
+		{"Group invalidated", true, &proto.PermissionInvalidation{GroupIds: []int32{3}}, 2},
+		{"Everything invalidated", true, &proto.PermissionInvalidation{All: true}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &countingClient{}
+			cache := NewPermissionCache(&Client{conn: conn, client: fake}, "test")
+			cache.setLive(tt.live)
+
+			for i := 0; i < 2; i++ {
+				result, err := cache.Check(context.Background(), 1, query)
+				if err != nil {
+					t.Fatalf("Check() error = %v", err)
+				}
+				if result.Read != 1 {
+					t.Errorf("Check() = %+v, want read access", result)
+				}
+				if i == 0 && tt.between != nil {
+					cache.Invalidate(tt.between)
+				}
+			}
+			if fake.calls != tt.wantCalls {
+				t.Errorf("Check() called the service %d times, want %d", fake.calls, tt.wantCalls)
+			}
+		})
+	}
+}
@@ -150,3 +150,59 @@ func (c *Client) ValidateToken(ctx context.Context, token string) (bool, int32,
 	}
 	return result.IsValid, result.UserId, nil
 }
+
+// CheckPermissions is a helper function that checks many permissions of many users in one call.
+// Result maps user id to results in the order of queries. Users that failed are reported in the error
+// together with the results of the rest
+func (c *Client) CheckPermissions(ctx context.Context, userIds []int32, queries []*proto.PermissionQuery) (map[int32][]*proto.PermissionResult, error) {
+	log.Traceln("User::Client::CheckPermissions")
+	if c.conn == nil {
+		return nil, fmt.Errorf("connection is not initialized")
+	}
+	if c.client == nil {
+		return nil, fmt.Errorf("client is not initialized")
+	}
+
+	response, err := c.client.CheckPermissions(ctx, &proto.CheckPermissionsRequest{UserIds: userIds, Queries: queries})
+	if err != nil {
+		return nil, err
+	}
+	if response.Code != 0 {
+		return nil, fmt.Errorf("check failed with code %d: %s", response.Code, response.Error)
+	}
+
+	result := make(map[int32][]*proto.PermissionResult, len(response.Users))
+	var failed []error
+	for _, user := range response.Users {
+		if user.Code != 0 {
+			failed = append(failed, fmt.Errorf("user %d: code %d: %s", user.UserId, user.Code, user.Error))
+			continue
+		}
+		result[user.UserId] = user.Results
+	}
+	return result, errors.Join(failed...)
+}
+
+// WatchPermissions subscribes to permission invalidations and passes each of them to handler until the
+// stream ends or ctx is done. The first invalidation marks the start of the subscription and drops everything
+func (c *Client) WatchPermissions(ctx context.Context, service string, handler func(*proto.PermissionInvalidation)) error {
+	log.Traceln("User::Client::WatchPermissions")
+	if c.conn == nil {
+		return fmt.Errorf("connection is not initialized")
+	}
+	if c.client == nil {
+		return fmt.Errorf("client is not initialized")
+	}
+
+	stream, err := c.client.WatchPermissions(ctx, &proto.WatchPermissionsRequest{Service: service})
+	if err != nil {
+		return err
+	}
+	for {
+		invalidation, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		handler(invalidation)
+	}
+}
@@ -3,7 +3,7 @@ module github.com/savageking-io/ogbuser/client
 go 1.25.1
 
 require (
-	github.com/savageking-io/ogbuser/proto v0.4.0
+	github.com/savageking-io/ogbuser/proto v0.5.0
 	github.com/sirupsen/logrus v1.9.3
 	google.golang.org/grpc v1.75.1
 	google.golang.org/protobuf v1.36.10
@@ -0,0 +1,227 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+var (
+	ErrUserDeleted        = errors.New("user is already deleted")
+	ErrUserNotDeleted     = errors.New("user is not deleted")
+	ErrRestoreWindowEnded = errors.New("restore window has ended")
+	ErrUserMerged         = errors.New("user was merged into another account")
+	ErrErasurePending     = errors.New("user is pending erasure")
+)
+
+// Purge modes
+const (
+	PurgeModeAnonymize = "anonymize" // Keep users row with id, drop everything that identifies the person
+	PurgeModeDelete    = "delete"    // Remove users row and everything referencing it
+)
+
+// DeleteUser soft-deletes the user and revokes all sessions. actorId is 0 when deleted from CLI
+func (d *Database) DeleteUser(ctx context.Context, userId, actorId int32, reason string) (int64, error) {
+	log.Traceln("Database::DeleteUser:", userId)
+	if d.db == nil {
+		return 0, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var deletedAt *time.Time
+	query := `SELECT deleted_at FROM users WHERE id = $1 FOR UPDATE`
+	if err := tx.GetContext(ctx, &deletedAt, query, userId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrUserNotFound
+		}
+		return 0, err
+	}
+	if deletedAt != nil {
+		return 0, ErrUserDeleted
+	}
+
+	query = `UPDATE users SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, query, userId); err != nil {
+		return 0, err
+	}
+
+	query = `
+		UPDATE user_sessions SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND deleted_at IS NULL`
+	revoked, err := execAffected(ctx, tx, query, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	details := map[string]any{"reason": reason, "revoked_sessions": revoked}
+	if err := insertAuditLog(ctx, tx, actorId, AuditActionUserDeleted, userId, details); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return revoked, nil
+}
+
+// RestoreUser brings back a soft-deleted user if it was deleted less than window ago and hasn't been purged.
+// Users deleted by a merge can't be restored since their data now belongs to the merge target
+func (d *Database) RestoreUser(ctx context.Context, userId, actorId int32, window time.Duration) error {
+	log.Traceln("Database::RestoreUser:", userId)
+	if d.db == nil {
+		return fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	state := struct {
+		DeletedAt *time.Time `db:"deleted_at"`
+		PurgedAt  *time.Time `db:"purged_at"`
+	}{}
+	query := `SELECT deleted_at, purged_at FROM users WHERE id = $1 FOR UPDATE`
+	if err := tx.GetContext(ctx, &state, query, userId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if state.DeletedAt == nil {
+		return ErrUserNotDeleted
+	}
+	if state.PurgedAt != nil || time.Since(*state.DeletedAt) > window {
+		return ErrRestoreWindowEnded
+	}
+
+	var merged bool
+	query = `
+		SELECT EXISTS (
+			SELECT 1 FROM audit_log
+			WHERE action = $2 AND (details->>'source_user_id')::INTEGER = $1
+		)`
+	if err := tx.GetContext(ctx, &merged, query, userId, AuditActionUserMerged); err != nil {
+		return err
+	}
+	if merged {
+		return ErrUserMerged
+	}
+
+	var erasing bool
+	query = `SELECT EXISTS (SELECT 1 FROM erasure_requests WHERE user_id = $1 AND status = 'pending')`
+	if err := tx.GetContext(ctx, &erasing, query, userId); err != nil {
+		return err
+	}
+	if erasing {
+		return ErrErasurePending
+	}
+
+	query = `UPDATE users SET deleted_at = NULL, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, query, userId); err != nil {
+		return err
+	}
+
+	if err := insertAuditLog(ctx, tx, actorId, AuditActionUserRestored, userId, map[string]any{"deleted_at": state.DeletedAt}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// PurgeDeletedUsers removes personal data of up to limit users soft-deleted before cutoff.
+// Sessions, platforms, memberships and requests to change them, profiles and username history are
+// deleted in both modes.
+// In anonymize mode users row stays with a placeholder username, in delete mode it is removed
+// together with sanctions. Returns ids of purged users
+func (d *Database) PurgeDeletedUsers(ctx context.Context, cutoff time.Time, mode string, limit int) ([]int32, error) {
+	log.Traceln("Database::PurgeDeletedUsers:", cutoff, mode, limit)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+	if mode != PurgeModeAnonymize && mode != PurgeModeDelete {
+		return nil, fmt.Errorf("unknown purge mode %q", mode)
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Users pending erasure are handled by the erasure workflow.
+	// SKIP LOCKED lets several instances purge in parallel without waiting for each other
+	var ids []int32
+	query := `
+		SELECT id FROM users
+		WHERE deleted_at < $1 AND purged_at IS NULL
+		  AND NOT EXISTS (SELECT 1 FROM erasure_requests e WHERE e.user_id = users.id AND e.status = 'pending')
+		ORDER BY deleted_at
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`
+	if err := tx.SelectContext(ctx, &ids, query, cutoff, limit); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	if err := purgeUsers(ctx, tx, ids, mode); err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		if err := insertAuditLog(ctx, tx, 0, AuditActionUserPurged, id, map[string]any{"mode": mode}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// purgeUsers removes personal data of users inside the caller's transaction
+func purgeUsers(ctx context.Context, tx *sqlx.Tx, ids []int32, mode string) error {
+	queries := []string{
+		`DELETE FROM user_sessions WHERE user_id = ANY($1)`,
+		`DELETE FROM platforms WHERE user_id = ANY($1)`,
+		`DELETE FROM group_members WHERE user_id = ANY($1)`,
+		`DELETE FROM membership_requests WHERE user_id = ANY($1)`,
+		`DELETE FROM user_profiles WHERE user_id = ANY($1)`,
+		`DELETE FROM username_history WHERE user_id = ANY($1)`,
+	}
+	if mode == PurgeModeDelete {
+		queries = append(queries,
+			`DELETE FROM sanction_notes WHERE sanction_id IN (SELECT id FROM sanctions WHERE user_id = ANY($1))`,
+			`DELETE FROM sanctions WHERE user_id = ANY($1)`,
+			`DELETE FROM users WHERE id = ANY($1)`,
+		)
+	} else {
+		queries = append(queries, `
+			UPDATE users
+			SET username = 'deleted_' || id, email = NULL, password = NULL, guest_secret = NULL, is_guest = FALSE,
+			    purged_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			WHERE id = ANY($1)`)
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query, pq.Array(ids)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
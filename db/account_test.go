@@ -0,0 +1,206 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDatabase_PurgeDeletedUsers_MembershipRequests(t *testing.T) {
+	for _, mode := range []string{PurgeModeDelete, PurgeModeAnonymize} {
+		t.Run(mode, func(t *testing.T) {
+			d := migratedTestDatabase(t)
+			// User 2 was added to the special group on request of user 1, approved by user 3, and all of them
+			// were deleted since
+			setup := `
+				INSERT INTO users (username, password, email, deleted_at) VALUES
+					('requester', 'hash', 'requester@localhost', CURRENT_TIMESTAMP - INTERVAL '1 day'),
+					('subject', 'hash', 'subject@localhost', CURRENT_TIMESTAMP - INTERVAL '1 day'),
+					('approver', 'hash', 'approver@localhost', CURRENT_TIMESTAMP - INTERVAL '1 day');
+				INSERT INTO groups (name, is_special) VALUES ('Super Administrators', TRUE);
+				INSERT INTO membership_requests (group_id, user_id, change, status, requested_by, decided_by, decided_at)
+				VALUES (1, 2, 'add', 'approved', 1, 3, CURRENT_TIMESTAMP),
+				       (1, 1, 'remove', 'pending', 3, NULL, NULL);`
+			if _, err := d.db.Exec(setup); err != nil {
+				t.Fatal(err)
+			}
+
+			ids, err := d.PurgeDeletedUsers(context.Background(), time.Now(), mode, 10)
+			if err != nil {
+				t.Fatalf("PurgeDeletedUsers() error = %v", err)
+			}
+			if len(ids) != 3 {
+				t.Errorf("PurgeDeletedUsers() purged %v, want 3 users", ids)
+			}
+
+			var requests int
+			if err := d.db.Get(&requests, `SELECT COUNT(*) FROM membership_requests`); err != nil {
+				t.Fatal(err)
+			}
+			if requests != 0 {
+				t.Errorf("%d membership requests of purged users are left", requests)
+			}
+		})
+	}
+}
+
+func TestDatabase_DeleteUser(t *testing.T) {
+	d := migratedTestDatabase(t)
+	ctx := context.Background()
+	setup := `
+		INSERT INTO users (username, password, email) VALUES ('player', 'hash', 'player@localhost');
+		INSERT INTO user_sessions (user_id, token, platform_name) VALUES (1, 'web_token', 'web'), (1, 'steam_token', 'steam');`
+	if _, err := d.db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+
+	revoked, err := d.DeleteUser(ctx, 1, 0, "requested by the player")
+	if err != nil {
+		t.Fatalf("DeleteUser() error = %v", err)
+	}
+	if revoked != 2 {
+		t.Errorf("DeleteUser() revoked %d sessions, want 2", revoked)
+	}
+	if _, err := d.DeleteUser(ctx, 1, 0, ""); !errors.Is(err, ErrUserDeleted) {
+		t.Errorf("DeleteUser() of a deleted user error = %v, want %v", err, ErrUserDeleted)
+	}
+	if _, err := d.DeleteUser(ctx, 2, 0, ""); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("DeleteUser() of an unknown user error = %v, want %v", err, ErrUserNotFound)
+	}
+}
+
+func TestDatabase_RestoreUser(t *testing.T) {
+	tests := []struct {
+		name    string
+		userId  int32
+		wantErr error
+	}{
+		{"Within restore window", 2, nil},
+		{"Not deleted", 1, ErrUserNotDeleted},
+		{"Restore window ended", 3, ErrRestoreWindowEnded},
+		{"Merged", 4, ErrUserMerged},
+		{"Pending erasure", 5, ErrErasurePending},
+		{"Purged", 6, ErrRestoreWindowEnded},
+		{"Unknown user", 7, ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := migratedTestDatabase(t)
+			setup := `
+				INSERT INTO users (username, password, email, deleted_at, purged_at) VALUES
+					('active', 'hash', 'active@localhost', NULL, NULL),
+					('recent', 'hash', 'recent@localhost', CURRENT_TIMESTAMP - INTERVAL '1 day', NULL),
+					('old', 'hash', 'old@localhost', CURRENT_TIMESTAMP - INTERVAL '40 days', NULL),
+					('merged', 'hash', 'merged@localhost', CURRENT_TIMESTAMP - INTERVAL '1 day', NULL),
+					('erasing', 'hash', 'erasing@localhost', CURRENT_TIMESTAMP - INTERVAL '1 day', NULL),
+					('deleted_6', NULL, NULL, CURRENT_TIMESTAMP - INTERVAL '1 day', CURRENT_TIMESTAMP);
+				INSERT INTO audit_log (actor_id, action, target_user_id, details)
+				VALUES (0, 'user.merged', 1, '{"source_user_id": 4}');
+				INSERT INTO erasure_requests (user_id) VALUES (5);`
+			if _, err := d.db.Exec(setup); err != nil {
+				t.Fatal(err)
+			}
+
+			err := d.RestoreUser(context.Background(), tt.userId, 0, 30*24*time.Hour)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RestoreUser() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var deleted bool
+			if err := d.db.Get(&deleted, `SELECT deleted_at IS NOT NULL FROM users WHERE id = 2`); err != nil {
+				t.Fatal(err)
+			}
+			if deleted != (tt.wantErr != nil) {
+				t.Errorf("user 2 deleted = %v after RestoreUser() of %d", deleted, tt.userId)
+			}
+		})
+	}
+}
+
+func TestDatabase_PurgeDeletedUsers(t *testing.T) {
+	tests := []struct {
+		mode      string
+		wantUsers []string
+	}{
+		{PurgeModeAnonymize, []string{"active", "deleted_2", "recent", "erasing"}},
+		{PurgeModeDelete, []string{"active", "recent", "erasing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			d := migratedTestDatabase(t)
+			ctx := context.Background()
+			// Only user 2 is past retention and not pending erasure
+			setup := `
+				INSERT INTO users (username, password, email, deleted_at) VALUES
+					('active', 'hash', 'active@localhost', NULL),
+					('expired', 'hash', 'expired@localhost', CURRENT_TIMESTAMP - INTERVAL '40 days'),
+					('recent', 'hash', 'recent@localhost', CURRENT_TIMESTAMP - INTERVAL '1 day'),
+					('erasing', 'hash', 'erasing@localhost', CURRENT_TIMESTAMP - INTERVAL '40 days');
+				INSERT INTO erasure_requests (user_id) VALUES (4);
+				INSERT INTO groups (name) VALUES ('Players');
+				INSERT INTO group_members (group_id, user_id) VALUES (1, 1), (1, 2);
+				INSERT INTO platforms (user_id, platform_name, platform_user_id) VALUES (2, 'steam', 'steam_2');
+				INSERT INTO user_sessions (user_id, token, platform_name) VALUES (2, 'expired_token', 'web');
+				INSERT INTO user_profiles (user_id, display_name) VALUES (2, 'Expired');
+				INSERT INTO username_history (user_id, old_username, new_username, held_until)
+				VALUES (2, 'previous', 'expired', CURRENT_TIMESTAMP);
+				INSERT INTO sanctions (user_id, type, reason) VALUES (2, 'ban', 'cheating');
+				INSERT INTO sanction_notes (sanction_id, note) VALUES (1, 'aimbot');`
+			if _, err := d.db.Exec(setup); err != nil {
+				t.Fatal(err)
+			}
+
+			ids, err := d.PurgeDeletedUsers(ctx, time.Now().Add(-30*24*time.Hour), tt.mode, 10)
+			if err != nil {
+				t.Fatalf("PurgeDeletedUsers() error = %v", err)
+			}
+			if !slices.Equal(ids, []int32{2}) {
+				t.Errorf("PurgeDeletedUsers() = %v, want [2]", ids)
+			}
+
+			var users []string
+			if err := d.db.Select(&users, `SELECT username FROM users ORDER BY id`); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(users, tt.wantUsers) {
+				t.Errorf("users after purge = %v, want %v", users, tt.wantUsers)
+			}
+
+			var left int
+			query := `
+				SELECT (SELECT COUNT(*) FROM group_members WHERE user_id = 2) + (SELECT COUNT(*) FROM platforms) +
+				       (SELECT COUNT(*) FROM user_sessions) + (SELECT COUNT(*) FROM user_profiles) +
+				       (SELECT COUNT(*) FROM username_history)`
+			if err := d.db.Get(&left, query); err != nil {
+				t.Fatal(err)
+			}
+			if left != 0 {
+				t.Errorf("%d rows of personal data are left after purge", left)
+			}
+
+			var sanctions int
+			if err := d.db.Get(&sanctions, `SELECT COUNT(*) FROM sanctions`); err != nil {
+				t.Fatal(err)
+			}
+			if wantSanctions := map[string]int{PurgeModeAnonymize: 1, PurgeModeDelete: 0}[tt.mode]; sanctions != wantSanctions {
+				t.Errorf("%d sanctions are left after purge, want %d", sanctions, wantSanctions)
+			}
+
+			if tt.mode == PurgeModeAnonymize {
+				var purged struct {
+					Email    *string `db:"email"`
+					Password *string `db:"password"`
+					Purged   bool    `db:"purged"`
+				}
+				if err := d.db.Get(&purged, `SELECT email, password, purged_at IS NOT NULL AS purged FROM users WHERE id = 2`); err != nil {
+					t.Fatal(err)
+				}
+				if purged.Email != nil || purged.Password != nil || !purged.Purged {
+					t.Errorf("anonymized user keeps credentials or isn't marked purged")
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+)
+
+// Actions recorded in audit_log
+const (
+	AuditActionUserMerged        = "user.merged"
+	AuditActionSanctionIssued    = "sanction.issued"
+	AuditActionSanctionRevoked   = "sanction.revoked"
+	AuditActionUserDeleted       = "user.deleted"
+	AuditActionUserRestored      = "user.restored"
+	AuditActionUserPurged        = "user.purged"
+	AuditActionUserExported      = "user.exported"
+	AuditActionErasureRequested  = "user.erasure_requested"
+	AuditActionUserErased        = "user.erased"
+	AuditActionPermissionSet     = "group.permission_set"
+	AuditActionPermissionRemoved = "group.permission_removed"
+	AuditActionGroupCreated      = "group.created"
+	AuditActionGroupUpdated      = "group.updated"
+	AuditActionGroupDeleted      = "group.deleted"
+	AuditActionMemberAdded       = "group.member_added"
+	AuditActionMemberRemoved     = "group.member_removed"
+	AuditActionMemberExpired     = "group.member_expired"
+	AuditActionMembershipRequest = "group.membership_requested"
+	AuditActionMembershipApprove = "group.membership_approved"
+	AuditActionMembershipReject  = "group.membership_rejected"
+	AuditActionSuperuserBypass   = "permission.superuser_bypass"
+	AuditActionPolicyApplied     = "policy.applied"
+)
+
+// RecordAudit records an action that doesn't change any data, e.g. a permission check
+func (d *Database) RecordAudit(ctx context.Context, actorId int32, action string, targetUserId int32, details any) error {
+	if d.db == nil {
+		return fmt.Errorf("db is nil")
+	}
+	return insertAuditLog(ctx, d.db, actorId, action, targetUserId, details)
+}
+
+// insertAuditLog records an action inside the caller's transaction. Zero ids are stored as NULL
+func insertAuditLog(ctx context.Context, tx sqlx.ExecerContext, actorId int32, action string, targetUserId int32, details any) error {
+	payload, err := json.Marshal(details)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO audit_log (actor_id, action, target_user_id, details)
+		VALUES (NULLIF($1, 0), $2, NULLIF($3, 0), $4)`
+	_, err = tx.ExecContext(ctx, query, actorId, action, targetUserId, string(payload))
+	return err
+}
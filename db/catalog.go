@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+var (
+	ErrPermissionOwned   = errors.New("permission is registered by another service")
+	ErrUnknownPermission = errors.New("permission is not registered")
+)
+
+const catalogColumns = `id, service, name, domain, description, version, created_at, updated_at`
+
+// RegisterPermissions upserts permissions owned by the service. A changed description increases version
+// of the entry. Registering a name another service owns in the same domain fails the whole call.
+// Returns numbers of new and changed entries
+func (d *Database) RegisterPermissions(ctx context.Context, service string, permissions []schema.PermissionCatalogSchema) (int, int, error) {
+	log.Traceln("Database::RegisterPermissions:", service, len(permissions))
+	if d.db == nil {
+		return 0, 0, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer tx.Rollback()
+
+	registered, updated := 0, 0
+	for _, permission := range permissions {
+		var existing []schema.PermissionCatalogSchema
+		query := `SELECT ` + catalogColumns + ` FROM permission_catalog WHERE name = $1 AND domain = $2 FOR UPDATE`
+		if err := tx.SelectContext(ctx, &existing, query, permission.Name, permission.Domain); err != nil {
+			return 0, 0, err
+		}
+
+		if len(existing) == 0 {
+			query = `
+				INSERT INTO permission_catalog (service, name, domain, description)
+				VALUES ($1, $2, $3, $4)`
+			if _, err := tx.ExecContext(ctx, query, service, permission.Name, permission.Domain, permission.Description); err != nil {
+				return 0, 0, err
+			}
+			registered++
+			continue
+		}
+
+		if existing[0].Service != service {
+			return 0, 0, fmt.Errorf("%w: %s [%s] belongs to %s", ErrPermissionOwned, permission.Name, permission.Domain, existing[0].Service)
+		}
+		if existing[0].Description == permission.Description {
+			continue
+		}
+
+		query = `
+			UPDATE permission_catalog
+			SET description = $2, version = version + 1, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1`
+		if _, err := tx.ExecContext(ctx, query, existing[0].Id, permission.Description); err != nil {
+			return 0, 0, err
+		}
+		updated++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, 0, err
+	}
+
+	return registered, updated, nil
+}
+
+// LoadPermissionCatalog returns registered permissions ordered by service and name.
+// Empty service returns permissions of all services
+func (d *Database) LoadPermissionCatalog(ctx context.Context, service string) ([]schema.PermissionCatalogSchema, error) {
+	log.Traceln("Database::LoadPermissionCatalog:", service)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	var result []schema.PermissionCatalogSchema
+	query := `
+		SELECT ` + catalogColumns + `
+		FROM permission_catalog
+		WHERE $1 = '' OR service = $1
+		ORDER BY service, name, domain`
+	if err := d.db.SelectContext(ctx, &result, query, service); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SetGroupPermission creates or replaces the permission of the group. The permission has to be registered
+// in the catalog for the same domain, a wildcard grant has to cover at least one registered permission
+func (d *Database) SetGroupPermission(ctx context.Context, actorId int32, permission *schema.GroupPermissionSchema) error {
+	log.Traceln("Database::SetGroupPermission:", permission.GroupId, permission.Permission, permission.Domain)
+	if d.db == nil {
+		return fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := setGroupPermission(ctx, tx, actorId, permission); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// setGroupPermission upserts the permission inside the caller's transaction
+func setGroupPermission(ctx context.Context, tx *sqlx.Tx, actorId int32, permission *schema.GroupPermissionSchema) error {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM groups WHERE id = $1 AND deleted_at IS NULL)`
+	if err := tx.GetContext(ctx, &exists, query, permission.GroupId); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrGroupNotFound
+	}
+
+	// A wildcard grant has to cover at least one registered permission
+	query = `SELECT EXISTS (SELECT 1 FROM permission_catalog WHERE name = $1 AND domain = $2)`
+	name := permission.Permission
+	if strings.HasSuffix(name, "*") {
+		query = `SELECT EXISTS (SELECT 1 FROM permission_catalog WHERE left(name, length($1)) = $1 AND domain = $2)`
+		name = strings.TrimSuffix(name, "*")
+	}
+	if err := tx.GetContext(ctx, &exists, query, name, permission.Domain); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("%w: %s [%s]", ErrUnknownPermission, permission.Permission, permission.Domain)
+	}
+
+	query = `
+		INSERT INTO group_permissions (group_id, permission, domain, read, write, delete, deny_read, deny_write, deny_delete)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (group_id, permission, domain) WHERE deleted_at IS NULL
+		DO UPDATE SET read = EXCLUDED.read, write = EXCLUDED.write, delete = EXCLUDED.delete,
+		              deny_read = EXCLUDED.deny_read, deny_write = EXCLUDED.deny_write,
+		              deny_delete = EXCLUDED.deny_delete, updated_at = CURRENT_TIMESTAMP`
+	_, err := tx.ExecContext(ctx, query, permission.GroupId, permission.Permission, permission.Domain,
+		permission.Read, permission.Write, permission.Delete, permission.DenyRead, permission.DenyWrite, permission.DenyDelete)
+	if err != nil {
+		return err
+	}
+
+	details := map[string]any{
+		"group_id": permission.GroupId, "permission": permission.Permission, "domain": permission.Domain,
+		"read": permission.Read, "write": permission.Write, "delete": permission.Delete,
+		"deny_read": permission.DenyRead, "deny_write": permission.DenyWrite, "deny_delete": permission.DenyDelete,
+	}
+	return insertAuditLog(ctx, tx, actorId, AuditActionPermissionSet, 0, details)
+}
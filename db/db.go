@@ -6,7 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/jmoiron/sqlx"
-	"github.com/savageking-io/ogbcommon/dbinit"
+	"github.com/lib/pq"
 	"github.com/savageking-io/ogbuser/schema"
 	log "github.com/sirupsen/logrus"
 	"strings"
@@ -17,8 +17,18 @@ var (
 	ErrUserNotFound    = errors.New("user not found")
 	ErrGroupNotFound   = errors.New("group not found")
 	ErrSessionNotFound = errors.New("session not found")
+	ErrUsernameTaken   = errors.New("username is already taken")
+	ErrEmailTaken      = errors.New("email is already taken")
+	ErrNotGuest        = errors.New("user is not a guest")
+	ErrPlatformLinked  = errors.New("platform identity is already linked to another account")
+	ErrPlatformExists  = errors.New("platform is already linked to this account")
+	ErrPlatformMissing = errors.New("platform is not linked")
+	ErrLastLoginMethod = errors.New("can't unlink the last login method")
 )
 
+// groupColumns selects groups so that a missing parent is read as ParentId 0
+const groupColumns = `id, COALESCE(parent_id, 0) AS parent_id, name, description, is_special, created_at, updated_at, deleted_at`
+
 type PostgresConfig struct {
 	Hostname        string `yaml:"hostname"`
 	Port            uint16 `yaml:"port"`
@@ -29,7 +39,9 @@ type PostgresConfig struct {
 	MaxOpenCons     int    `yaml:"max_open_cons"`
 	MaxIdleCons     int    `yaml:"max_idle_cons"`
 	ConnMaxLifetime int    `yaml:"conn_max_lifetime"`
-	SourceFile      string `yaml:"source_file"`
+	AutoMigrate     bool   `yaml:"auto_migrate"` // Apply pending migrations on start instead of refusing to serve
+	DevFixtures     bool   `yaml:"dev_fixtures"` // Insert sample data into a database without users. Development only
+	Schema          string `yaml:"schema"`       // Schema to work in instead of the user's default search_path
 }
 
 type Database struct {
@@ -40,9 +52,9 @@ type Database struct {
 	password        string
 	database        string
 	sslMode         bool
+	schema          string
 	maxOpenCons     int
 	maxIdleCons     int
-	sourceFile      string
 	connMaxLifetime time.Duration
 }
 
@@ -57,10 +69,10 @@ func (d *Database) Init(conf *PostgresConfig) error {
 	d.password = conf.Password
 	d.database = conf.Database
 	d.sslMode = conf.SslMode
+	d.schema = conf.Schema
 	d.maxOpenCons = conf.MaxOpenCons
 	d.maxIdleCons = conf.MaxIdleCons
 	d.connMaxLifetime = time.Duration(conf.ConnMaxLifetime) * time.Second
-	d.sourceFile = conf.SourceFile
 
 	return nil
 }
@@ -71,13 +83,18 @@ func (d *Database) buildConnString() string {
 		sslMode = "require"
 	}
 
-	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s application_name=%s",
 		d.hostname,
 		d.port,
 		d.username,
 		d.password,
 		d.database,
-		sslMode)
+		sslMode,
+		ApplicationName)
+	if d.schema != "" {
+		connStr += " search_path=" + d.schema
+	}
+	return connStr
 }
 
 func (d *Database) Connect() error {
@@ -102,26 +119,7 @@ func (d *Database) Connect() error {
 	return nil
 }
 
-func (d *Database) TryPopulate() error {
-	if d.db == nil {
-		return fmt.Errorf("db is nil")
-	}
-	if d.sourceFile == "" {
-		log.Errorf("Database::TryPopulate No database source file provided. Cannot populate database")
-		return nil
-	}
-	// Populate db if it's empty
-	err := dbinit.VerifyTables([]string{"users", "platforms", "groups"}, d.db)
-	if err != nil {
-		log.Warnf("Table verification failed: %s", err.Error())
-		if err := dbinit.Populate(d.db, d.sourceFile); err != nil {
-			log.Errorf("Failed to populate database: %s", err.Error())
-			return err
-		}
-	}
-	return nil
-}
-
+// LoadGroups returns all groups that aren't deleted. Groups without a parent have ParentId 0
 func (d *Database) LoadGroups(ctx context.Context) ([]schema.GroupSchema, error) {
 	if d.db == nil {
 		return nil, fmt.Errorf("db is nil")
@@ -130,19 +128,18 @@ func (d *Database) LoadGroups(ctx context.Context) ([]schema.GroupSchema, error)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
 	query := `
-		SELECT id, parent_id, name, description, created_at, updated_at, deleted_at 
+		SELECT ` + groupColumns + `
 		FROM groups 
 		WHERE deleted_at IS NULL
+		ORDER BY id
 	`
 
 	var groups []schema.GroupSchema
-	err = tx.SelectContext(context.Background(), &groups, query)
+	err = tx.SelectContext(ctx, &groups, query)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrGroupNotFound
-		}
 		return nil, err
 	}
 
@@ -150,7 +147,7 @@ func (d *Database) LoadGroups(ctx context.Context) ([]schema.GroupSchema, error)
 		return nil, err
 	}
 
-	return nil, nil
+	return groups, nil
 }
 
 func (d *Database) LoadGroupById(ctx context.Context, id int32) (*schema.GroupSchema, error) {
@@ -164,7 +161,7 @@ func (d *Database) LoadGroupById(ctx context.Context, id int32) (*schema.GroupSc
 	}
 
 	query := `
-		SELECT id, parent_id, name, description, created_at, updated_at, deleted_at
+		SELECT ` + groupColumns + `
 		FROM groups
 		WHERE id = $1 AND deleted_at IS NULL
 	`
@@ -196,7 +193,8 @@ func (d *Database) LoadGroupPermissions(ctx context.Context, groupId int32) ([]s
 
 	var permissions []schema.GroupPermissionSchema
 	query := `
-		SELECT id, group_id, permission, read, write, delete, domain, created_at, updated_at, deleted_at 
+		SELECT id, group_id, permission, read, write, delete, deny_read, deny_write, deny_delete, domain,
+		       created_at, updated_at, deleted_at
 		FROM group_permissions 
 		WHERE group_id = $1 AND deleted_at IS NULL
 	`
@@ -229,7 +227,7 @@ func (d *Database) LoadUserById(ctx context.Context, id int32) (*schema.UserSche
 	result := &schema.UserSchema{}
 
 	query := `
-		SELECT id, username, password, email, created_at, updated_at, deleted_at
+		SELECT id, username, password, email, is_guest, guest_secret, created_at, updated_at, deleted_at
 		FROM users
 		WHERE id = $1 AND deleted_at IS NULL`
 
@@ -262,13 +260,13 @@ func (d *Database) LoadUserByUsername(ctx context.Context, username string) (*sc
 
 	result := &schema.UserSchema{}
 	query := `
-		SELECT id, username, password, email, created_at, updated_at, deleted_at
+		SELECT id, username, password, email, is_guest, guest_secret, created_at, updated_at, deleted_at
 		FROM users 
 		WHERE username = $1 AND deleted_at IS NULL`
 
 	if strings.Contains(username, "@") {
 		query = `
-			SELECT id, username, password, email, created_at, updated_at, deleted_at
+			SELECT id, username, password, email, is_guest, guest_secret, created_at, updated_at, deleted_at
 			FROM users 
 			WHERE email = $1 AND deleted_at IS NULL`
 	}
@@ -292,6 +290,12 @@ func (d *Database) LoadUserByUsername(ctx context.Context, username string) (*sc
 
 func (d *Database) LoadUserBySteamId(ctx context.Context, steamId string) (*schema.UserSchema, error) {
 	log.Traceln("Database::LoadUserBySteamId:", steamId)
+	return d.LoadUserByPlatform(ctx, "steam", steamId)
+}
+
+// LoadUserByPlatform returns the user that has the platform identity linked
+func (d *Database) LoadUserByPlatform(ctx context.Context, platform, platformUserId string) (*schema.UserSchema, error) {
+	log.Traceln("Database::LoadUserByPlatform:", platform, platformUserId)
 
 	if d.db == nil {
 		return nil, fmt.Errorf("database is not initialized")
@@ -301,11 +305,16 @@ func (d *Database) LoadUserBySteamId(ctx context.Context, steamId string) (*sche
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
 	result := &schema.UserSchema{}
-	query := ``
+	query := `
+		SELECT u.id, u.username, u.password, u.email, u.is_guest, u.guest_secret, u.created_at, u.updated_at, u.deleted_at
+		FROM users u
+		JOIN platforms p ON p.user_id = u.id
+		WHERE p.platform_name = $1 AND p.platform_user_id = $2 AND p.deleted_at IS NULL AND u.deleted_at IS NULL`
 
-	err = tx.GetContext(ctx, &result, query, steamId)
+	err = tx.GetContext(ctx, result, query, platform, platformUserId)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrUserNotFound
@@ -332,7 +341,7 @@ func (d *Database) GetUserSessionByToken(ctx context.Context, token string) (*sc
 	}
 
 	query := `
-		SELECT user_id, created_at, updated_at
+		SELECT user_id, platform_name, created_at, updated_at
 		FROM user_sessions
 		WHERE token = $1 AND deleted_at IS NULL`
 
@@ -352,7 +361,98 @@ func (d *Database) GetUserSessionByToken(ctx context.Context, token string) (*sc
 	return &session, nil
 }
 
-func (d *Database) GetUserGroupIds(ctx context.Context, userId int32) ([]int32, error) {
+// GetUserMemberships returns memberships of the user that are not deleted or expired
+func (d *Database) GetUserMemberships(ctx context.Context, userId int32) ([]schema.GroupMemberSchema, error) {
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	query := `
+		SELECT ` + groupMemberColumns + ` FROM group_members
+		WHERE user_id = $1 AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)`
+	var memberships []schema.GroupMemberSchema
+	if err := d.db.SelectContext(ctx, &memberships, query, userId); err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+func (d *Database) SaveUserSession(ctx context.Context, userId int32, token, platform string) (*schema.UserSessionSchema, error) {
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	session := &schema.UserSessionSchema{
+		UserId:       userId,
+		Token:        token,
+		CreatedAt:    time.Now(),
+		PlatformName: platform,
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	query := `
+		INSERT INTO user_sessions (user_id, token, created_at, platform_name) VALUES (:user_id, :token, :created_at, :platform_name)
+		`
+	_, err = tx.NamedExecContext(ctx, query, &session)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// CreateGuestUser inserts a new guest account identified by the hash of its device secret.
+// When groupId is not 0 the guest is added to that group in the same transaction
+func (d *Database) CreateGuestUser(ctx context.Context, username, secretHash string, groupId int32) (*schema.UserSchema, error) {
+	log.Traceln("Database::CreateGuestUser:", username)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result := &schema.UserSchema{}
+	query := `
+		INSERT INTO users (username, is_guest, guest_secret)
+		VALUES ($1, TRUE, $2)
+		RETURNING id, username, password, email, is_guest, guest_secret, created_at, updated_at, deleted_at`
+
+	err = tx.GetContext(ctx, result, query, username, secretHash)
+	if err != nil {
+		if isUniqueViolation(err, "users_username_key") {
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+
+	if groupId != 0 {
+		query = `INSERT INTO group_members (group_id, user_id) VALUES ($1, $2)`
+		if _, err := tx.ExecContext(ctx, query, groupId, result.Id); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// LoadUserByGuestSecret returns the guest account bound to the provided secret hash
+func (d *Database) LoadUserByGuestSecret(ctx context.Context, secretHash string) (*schema.UserSchema, error) {
+	log.Traceln("Database::LoadUserByGuestSecret")
 	if d.db == nil {
 		return nil, fmt.Errorf("db is nil")
 	}
@@ -361,43 +461,122 @@ func (d *Database) GetUserGroupIds(ctx context.Context, userId int32) ([]int32,
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	query := `SELECT group_id FROM group_members WHERE user_id = $1 AND deleted_at IS NULL`
-	var groupIds []int32
-	err = tx.SelectContext(ctx, &groupIds, query, userId)
+	result := &schema.UserSchema{}
+	query := `
+		SELECT id, username, password, email, is_guest, guest_secret, created_at, updated_at, deleted_at
+		FROM users
+		WHERE guest_secret = $1 AND is_guest = TRUE AND deleted_at IS NULL`
+
+	err = tx.GetContext(ctx, result, query, secretHash)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrGroupNotFound
+			return nil, ErrUserNotFound
 		}
 		return nil, err
 	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
-	return groupIds, nil
+
+	return result, nil
 }
 
-func (d *Database) SaveUserSession(ctx context.Context, userId int32, token, platform string) (*schema.UserSessionSchema, error) {
+// UpgradeGuestWithCredentials turns a guest into a regular account by attaching email and password.
+// Username is kept when empty. User id and everything linked to it stay untouched
+func (d *Database) UpgradeGuestWithCredentials(ctx context.Context, userId int32, username, email, passwordHash string) (*schema.UserSchema, error) {
+	log.Traceln("Database::UpgradeGuestWithCredentials:", userId)
 	if d.db == nil {
 		return nil, fmt.Errorf("db is nil")
 	}
 
-	session := &schema.UserSessionSchema{
-		UserId:       userId,
-		Token:        token,
-		CreatedAt:    time.Now(),
-		PlatformName: platform,
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if username != "" {
+		if err := checkUsernameAvailable(ctx, tx, userId, username); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &schema.UserSchema{}
+	query := `
+		UPDATE users
+		SET username = COALESCE(NULLIF($2, ''), username), email = $3, password = $4,
+		    is_guest = FALSE, guest_secret = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND is_guest = TRUE AND deleted_at IS NULL
+		RETURNING id, username, password, email, is_guest, guest_secret, created_at, updated_at, deleted_at`
+
+	err = tx.GetContext(ctx, result, query, userId, username, email, passwordHash)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotGuest
+		}
+		if isUniqueViolation(err, "users_username_key") {
+			return nil, ErrUsernameTaken
+		}
+		if isUniqueViolation(err, "users_email_key") {
+			return nil, ErrEmailTaken
+		}
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpgradeGuestWithPlatform turns a guest into a regular account by linking a verified platform identity.
+// Returns ErrPlatformLinked if the identity already belongs to another account
+func (d *Database) UpgradeGuestWithPlatform(ctx context.Context, userId int32, platform, platformUserId string) (*schema.UserSchema, error) {
+	log.Traceln("Database::UpgradeGuestWithPlatform:", userId, platform)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
 	}
 
 	tx, err := d.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
+
+	var owners []int32
 	query := `
-		INSERT INTO user_sessions (user_id, token, created_at, platform_name) VALUES (:user_id, :token, :created_at, :platform_name)
-		`
-	_, err = tx.NamedExecContext(ctx, query, &session)
+		SELECT user_id FROM platforms
+		WHERE platform_name = $1 AND platform_user_id = $2 AND deleted_at IS NULL`
+	if err := tx.SelectContext(ctx, &owners, query, platform, platformUserId); err != nil {
+		return nil, err
+	}
+	if len(owners) > 0 {
+		return nil, ErrPlatformLinked
+	}
+
+	result := &schema.UserSchema{}
+	query = `
+		UPDATE users
+		SET is_guest = FALSE, guest_secret = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND is_guest = TRUE AND deleted_at IS NULL
+		RETURNING id, username, password, email, is_guest, guest_secret, created_at, updated_at, deleted_at`
+	err = tx.GetContext(ctx, result, query, userId)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotGuest
+		}
+		return nil, err
+	}
+
+	query = `INSERT INTO platforms (user_id, platform_name, platform_user_id) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, query, userId, platform, platformUserId); err != nil {
+		if isUniqueViolation(err, "platforms_identity_key") {
+			return nil, ErrPlatformLinked
+		}
 		return nil, err
 	}
 
@@ -405,5 +584,185 @@ func (d *Database) SaveUserSession(ctx context.Context, userId int32, token, pla
 		return nil, err
 	}
 
-	return session, nil
+	return result, nil
+}
+
+// execAffected executes query within tx and returns number of affected rows
+func execAffected(ctx context.Context, tx *sqlx.Tx, query string, args ...any) (int64, error) {
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// isUniqueViolation reports whether err was caused by the named unique constraint
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
+
+// LoadUserPlatforms returns platform identities linked to the user
+func (d *Database) LoadUserPlatforms(ctx context.Context, userId int32) ([]schema.PlatformSchema, error) {
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		SELECT id, user_id, platform_name, platform_user_id, created_at, updated_at, deleted_at
+		FROM platforms
+		WHERE user_id = $1 AND deleted_at IS NULL
+		ORDER BY id`
+
+	var platforms []schema.PlatformSchema
+	if err := tx.SelectContext(ctx, &platforms, query, userId); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return platforms, nil
+}
+
+// LinkPlatform attaches a verified platform identity to the user.
+// Returns ErrPlatformLinked if the identity belongs to another account and
+// ErrPlatformExists if the user already has an identity on this platform
+func (d *Database) LinkPlatform(ctx context.Context, userId int32, platform, platformUserId string) (*schema.PlatformSchema, error) {
+	log.Traceln("Database::LinkPlatform:", userId, platform)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var owners []int32
+	query := `
+		SELECT user_id FROM platforms
+		WHERE platform_name = $1 AND platform_user_id = $2 AND deleted_at IS NULL`
+	if err := tx.SelectContext(ctx, &owners, query, platform, platformUserId); err != nil {
+		return nil, err
+	}
+	for _, owner := range owners {
+		if owner != userId {
+			return nil, ErrPlatformLinked
+		}
+	}
+
+	// A previously unlinked row is revived instead of inserting a second one for the same platform
+	result := &schema.PlatformSchema{}
+	query = `
+		INSERT INTO platforms (user_id, platform_name, platform_user_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, platform_name) DO UPDATE
+		SET platform_user_id = EXCLUDED.platform_user_id, deleted_at = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE platforms.deleted_at IS NOT NULL
+		RETURNING id, user_id, platform_name, platform_user_id, created_at, updated_at, deleted_at`
+	err = tx.GetContext(ctx, result, query, userId, platform, platformUserId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrPlatformExists
+		}
+		if isUniqueViolation(err, "platforms_identity_key") {
+			return nil, ErrPlatformLinked
+		}
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UnlinkPlatform detaches platform identity from the user.
+// Returns ErrLastLoginMethod if the user would have no way to log in afterwards
+func (d *Database) UnlinkPlatform(ctx context.Context, userId int32, platform string) error {
+	log.Traceln("Database::UnlinkPlatform:", userId, platform)
+	if d.db == nil {
+		return fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the user row so concurrent unlinks can't both pass the check below
+	var methods struct {
+		Password  bool `db:"has_password"`
+		Guest     bool `db:"has_guest_secret"`
+		Platforms int  `db:"platforms"`
+		Target    int  `db:"target"`
+	}
+	query := `
+		SELECT u.password IS NOT NULL AS has_password, u.guest_secret IS NOT NULL AS has_guest_secret,
+		       (SELECT COUNT(*) FROM platforms p WHERE p.user_id = u.id AND p.deleted_at IS NULL) AS platforms,
+		       (SELECT COUNT(*) FROM platforms p WHERE p.user_id = u.id AND p.platform_name = $2 AND p.deleted_at IS NULL) AS target
+		FROM users u
+		WHERE u.id = $1 AND u.deleted_at IS NULL
+		FOR UPDATE`
+	if err := tx.GetContext(ctx, &methods, query, userId, platform); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return err
+	}
+
+	if methods.Target == 0 {
+		return ErrPlatformMissing
+	}
+
+	remaining := methods.Platforms - 1
+	if methods.Password {
+		remaining++
+	}
+	if methods.Guest {
+		remaining++
+	}
+	if remaining == 0 {
+		return ErrLastLoginMethod
+	}
+
+	query = `
+		UPDATE platforms SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND platform_name = $2 AND deleted_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, userId, platform); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokeUserSessions soft-deletes all active sessions of the user and returns how many were revoked
+func (d *Database) RevokeUserSessions(ctx context.Context, userId int32) (int64, error) {
+	log.Traceln("Database::RevokeUserSessions:", userId)
+	if d.db == nil {
+		return 0, fmt.Errorf("db is nil")
+	}
+
+	query := `
+		UPDATE user_sessions SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND deleted_at IS NULL`
+	result, err := d.db.ExecContext(ctx, query, userId)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
@@ -0,0 +1,48 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"os"
+	"testing"
+	"time"
+)
+
+// testDatabase connects to OGBUSER_TEST_POSTGRES, a key=value connection string, inside a new schema that is
+// dropped when the test ends. Skips the test when the variable isn't set
+func testDatabase(t *testing.T) *Database {
+	dsn := os.Getenv("OGBUSER_TEST_POSTGRES")
+	if dsn == "" {
+		t.Skip("OGBUSER_TEST_POSTGRES is not set")
+	}
+
+	admin, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := fmt.Sprintf("ogbuser_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + name); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = admin.Exec(`DROP SCHEMA ` + name + ` CASCADE`)
+		_ = admin.Close()
+	})
+
+	conn, err := sqlx.Connect("postgres", dsn+" search_path="+name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return &Database{db: conn}
+}
+
+// migratedTestDatabase is testDatabase with every migration applied
+func migratedTestDatabase(t *testing.T) *Database {
+	d := testDatabase(t)
+	if _, err := d.MigrateUp(context.Background()); err != nil {
+		t.Fatalf("MigrateUp() error = %v", err)
+	}
+	return d
+}
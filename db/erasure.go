@@ -0,0 +1,240 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+var (
+	ErrErasureRequested      = errors.New("erasure is already requested for this user")
+	ErrErasureNotFound       = errors.New("erasure request not found")
+	ErrUnknownErasureService = errors.New("service is not part of this erasure request")
+)
+
+const erasureColumns = `id, user_id, status, reason, requested_by, created_at, completed_at`
+
+// CreateErasureRequest marks the user pending erasure: the user is soft-deleted, sessions are revoked and
+// every registered service gets an acknowledgement row to fill. With no services registered the local
+// data is anonymized right away. Returns the request and number of revoked sessions
+func (d *Database) CreateErasureRequest(ctx context.Context, userId, actorId int32, reason string, services []string) (*schema.ErasureRequestSchema, int64, error) {
+	log.Traceln("Database::CreateErasureRequest:", userId, services)
+	if d.db == nil {
+		return nil, 0, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	var deletedAt *time.Time
+	query := `SELECT deleted_at FROM users WHERE id = $1 FOR UPDATE`
+	if err := tx.GetContext(ctx, &deletedAt, query, userId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, 0, ErrUserNotFound
+		}
+		return nil, 0, err
+	}
+
+	if deletedAt == nil {
+		query = `UPDATE users SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+		if _, err := tx.ExecContext(ctx, query, userId); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	query = `
+		UPDATE user_sessions SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND deleted_at IS NULL`
+	revoked, err := execAffected(ctx, tx, query, userId)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	request := &schema.ErasureRequestSchema{}
+	query = `
+		INSERT INTO erasure_requests (user_id, reason, requested_by)
+		VALUES ($1, $2, NULLIF($3, 0))
+		RETURNING ` + erasureColumns
+	if err := tx.GetContext(ctx, request, query, userId, reason, actorId); err != nil {
+		if isUniqueViolation(err, "erasure_requests_pending_key") {
+			return nil, 0, ErrErasureRequested
+		}
+		return nil, 0, err
+	}
+
+	query = `
+		INSERT INTO erasure_acks (request_id, service)
+		SELECT $1, UNNEST($2::VARCHAR[])
+		ON CONFLICT DO NOTHING`
+	if _, err := tx.ExecContext(ctx, query, request.Id, pq.Array(services)); err != nil {
+		return nil, 0, err
+	}
+
+	details := map[string]any{"request_id": request.Id, "services": services, "reason": reason}
+	if err := insertAuditLog(ctx, tx, actorId, AuditActionErasureRequested, userId, details); err != nil {
+		return nil, 0, err
+	}
+
+	if len(services) == 0 {
+		if err := completeErasure(ctx, tx, request); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if request.Acks, err = loadErasureAcks(ctx, tx, []int32{request.Id}); err != nil {
+		return nil, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, 0, err
+	}
+
+	return request, revoked, nil
+}
+
+// AcknowledgeErasure records that the service has erased its data of the user. When the last service
+// acknowledges, local personal data is anonymized and the request is completed. Repeated
+// acknowledgements are accepted. Returns the request and whether it was completed by this call
+func (d *Database) AcknowledgeErasure(ctx context.Context, requestId int32, service string) (*schema.ErasureRequestSchema, bool, error) {
+	log.Traceln("Database::AcknowledgeErasure:", requestId, service)
+	if d.db == nil {
+		return nil, false, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	// Lock the request so concurrent acknowledgements can't both see themselves as the last one
+	request := &schema.ErasureRequestSchema{}
+	query := `SELECT ` + erasureColumns + ` FROM erasure_requests WHERE id = $1 FOR UPDATE`
+	if err := tx.GetContext(ctx, request, query, requestId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, false, ErrErasureNotFound
+		}
+		return nil, false, err
+	}
+
+	var exists bool
+	query = `SELECT EXISTS (SELECT 1 FROM erasure_acks WHERE request_id = $1 AND service = $2)`
+	if err := tx.GetContext(ctx, &exists, query, requestId, service); err != nil {
+		return nil, false, err
+	}
+	if !exists {
+		return nil, false, ErrUnknownErasureService
+	}
+
+	query = `
+		UPDATE erasure_acks SET acknowledged_at = CURRENT_TIMESTAMP
+		WHERE request_id = $1 AND service = $2 AND acknowledged_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, requestId, service); err != nil {
+		return nil, false, err
+	}
+
+	completed := false
+	if request.Status == schema.ErasurePending {
+		var remaining int
+		query = `SELECT COUNT(*) FROM erasure_acks WHERE request_id = $1 AND acknowledged_at IS NULL`
+		if err := tx.GetContext(ctx, &remaining, query, requestId); err != nil {
+			return nil, false, err
+		}
+		if remaining == 0 {
+			if err := completeErasure(ctx, tx, request); err != nil {
+				return nil, false, err
+			}
+			completed = true
+		}
+	}
+
+	if request.Acks, err = loadErasureAcks(ctx, tx, []int32{request.Id}); err != nil {
+		return nil, false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, false, err
+	}
+
+	return request, completed, nil
+}
+
+// LoadErasureRequests returns newest erasure requests with their acknowledgements.
+// userId 0 returns requests of all users
+func (d *Database) LoadErasureRequests(ctx context.Context, userId int32, pendingOnly bool, limit int) ([]schema.ErasureRequestSchema, error) {
+	log.Traceln("Database::LoadErasureRequests:", userId, pendingOnly)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	var result []schema.ErasureRequestSchema
+	query := `
+		SELECT ` + erasureColumns + `
+		FROM erasure_requests
+		WHERE ($1 = 0 OR user_id = $1) AND (NOT $2 OR status = 'pending')
+		ORDER BY id DESC
+		LIMIT $3`
+	if err := d.db.SelectContext(ctx, &result, query, userId, pendingOnly, limit); err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return result, nil
+	}
+
+	ids := make([]int32, 0, len(result))
+	index := make(map[int32]int, len(result))
+	for i := range result {
+		ids = append(ids, result[i].Id)
+		index[result[i].Id] = i
+	}
+
+	acks, err := loadErasureAcks(ctx, d.db, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, ack := range acks {
+		i := index[ack.RequestId]
+		result[i].Acks = append(result[i].Acks, ack)
+	}
+
+	return result, nil
+}
+
+// completeErasure anonymizes local data of the user and closes the request inside the caller's transaction
+func completeErasure(ctx context.Context, tx *sqlx.Tx, request *schema.ErasureRequestSchema) error {
+	if err := purgeUsers(ctx, tx, []int32{request.UserId}, PurgeModeAnonymize); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE erasure_requests SET status = 'completed', completed_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING ` + erasureColumns
+	if err := tx.GetContext(ctx, request, query, request.Id); err != nil {
+		return err
+	}
+
+	return insertAuditLog(ctx, tx, 0, AuditActionUserErased, request.UserId, map[string]any{"request_id": request.Id})
+}
+
+func loadErasureAcks(ctx context.Context, q sqlx.QueryerContext, requestIds []int32) ([]schema.ErasureAckSchema, error) {
+	var acks []schema.ErasureAckSchema
+	query := `
+		SELECT id, request_id, service, acknowledged_at
+		FROM erasure_acks
+		WHERE request_id = ANY($1)
+		ORDER BY request_id, service`
+	if err := sqlx.SelectContext(ctx, q, &acks, query, pq.Array(requestIds)); err != nil {
+		return nil, err
+	}
+	return acks, nil
+}
@@ -0,0 +1,117 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// exportQuery builds the whole export as one JSON document, so every section comes from the same snapshot.
+// Secrets (password hash, guest secret, session tokens) and ids of staff members are left out
+const exportQuery = `
+	SELECT jsonb_build_object(
+		'account', (
+			SELECT jsonb_build_object(
+				'id', u.id, 'username', u.username, 'email', u.email, 'is_guest', u.is_guest,
+				'has_password', u.password IS NOT NULL, 'created_at', u.created_at, 'updated_at', u.updated_at,
+				'deleted_at', u.deleted_at)
+			FROM users u WHERE u.id = $1),
+		'profile', (
+			SELECT jsonb_build_object(
+				'display_name', p.display_name, 'avatar_url', p.avatar_url, 'locale', p.locale,
+				'timezone', p.timezone, 'country', p.country, 'attributes', p.attributes,
+				'created_at', p.created_at, 'updated_at', p.updated_at)
+			FROM user_profiles p WHERE p.user_id = $1),
+		'platforms', COALESCE((
+			SELECT jsonb_agg(jsonb_build_object(
+				'platform', p.platform_name, 'platform_user_id', p.platform_user_id,
+				'linked_at', p.created_at, 'unlinked_at', p.deleted_at) ORDER BY p.id)
+			FROM platforms p WHERE p.user_id = $1), '[]'),
+		'login_history', COALESCE((
+			SELECT jsonb_agg(jsonb_build_object(
+				'platform', s.platform_name, 'logged_in_at', s.created_at, 'ended_at', s.deleted_at) ORDER BY s.id)
+			FROM user_sessions s WHERE s.user_id = $1), '[]'),
+		'groups', COALESCE((
+			SELECT jsonb_agg(jsonb_build_object(
+				'group_id', gm.group_id, 'name', g.name, 'joined_at', gm.created_at, 'expires_at', gm.expires_at,
+				'left_at', gm.deleted_at) ORDER BY gm.id)
+			FROM group_members gm JOIN groups g ON g.id = gm.group_id WHERE gm.user_id = $1), '[]'),
+		'username_history', COALESCE((
+			SELECT jsonb_agg(jsonb_build_object(
+				'old_username', h.old_username, 'new_username', h.new_username, 'changed_at', h.created_at) ORDER BY h.id)
+			FROM username_history h WHERE h.user_id = $1), '[]'),
+		'sanctions', COALESCE((
+			SELECT jsonb_agg(jsonb_build_object(
+				'type', s.type, 'scope', s.scope, 'reason', s.reason, 'issued_at', s.created_at,
+				'expires_at', s.expires_at, 'ended_at', s.ended_at, 'revoked_at', s.revoked_at,
+				'revoke_reason', s.revoke_reason,
+				'notes', COALESCE((
+					SELECT jsonb_agg(jsonb_build_object('note', n.note, 'created_at', n.created_at) ORDER BY n.id)
+					FROM sanction_notes n WHERE n.sanction_id = s.id), '[]')) ORDER BY s.id)
+			FROM sanctions s WHERE s.user_id = $1), '[]'),
+		'account_events', COALESCE((
+			SELECT jsonb_agg(jsonb_build_object(
+				'action', a.action, 'details', a.details, 'created_at', a.created_at) ORDER BY a.id)
+			FROM audit_log a WHERE a.target_user_id = $1), '[]')
+	)`
+
+// ExportUser returns everything stored about the user as a JSON document. Deleted users
+// are exported as well until they are purged
+func (d *Database) ExportUser(ctx context.Context, userId int32) (types.JSONText, error) {
+	log.Traceln("Database::ExportUser:", userId)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND purged_at IS NULL)`
+	if err := tx.GetContext(ctx, &exists, query, userId); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	var result types.JSONText
+	if err := tx.GetContext(ctx, &result, exportQuery, userId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// RecordAuditLog stores an action that isn't part of another transaction
+func (d *Database) RecordAuditLog(ctx context.Context, actorId int32, action string, targetUserId int32, details any) error {
+	log.Traceln("Database::RecordAuditLog:", action, targetUserId)
+	if d.db == nil {
+		return fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertAuditLog(ctx, tx, actorId, action, targetUserId, details); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
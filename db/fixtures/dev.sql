@@ -0,0 +1,49 @@
+-- Sample data for local development. Applied by "ogbuser migrate up --fixtures" or with postgres.dev_fixtures
+-- enabled, and only to a database without users. Never enable it in production: root has a well-known password
+
+INSERT INTO permission_catalog (service, name, domain, description)
+VALUES ('ogbuser', 'manage_users', 'global', 'Administer users, groups and sanctions'),
+       ('ogbcontent', 'manage_content', 'global', 'Administer game content'),
+       ('ogbcontent', 'moderate_content', 'global', 'Moderate content created by players'),
+       ('ogbcontent', 'view_content', 'global', 'View published content')
+ON CONFLICT (name, domain) DO NOTHING;
+
+INSERT INTO users (username, password, email, created_at, updated_at)
+VALUES ('root', '$argon2id$v=19$m=65536,t=3,p=2$dmVyeXN0cm9uZ3NhbHQ$2xQImWCDVqmTG0F9ALqoV1RSG2Y98i5Jl3hcXxathms', 'admin@localhost', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+       ('jane_smith', '$argon2id$v=19$m=65536,t=3,p=2$dmVyeXN0cm9uZ3NhbHQ$tTF5B137G/sEiXKnTpCHN16j9ZOJ3ri2UPPbnIS875w', 'john.smith@example.com', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+       ('alice_wonder', '$argon2id$v=19$m=65536,t=3,p=2$dmVyeXN0cm9uZ3NhbHQ$tTF5B137G/sEiXKnTpCHN16j9ZOJ3ri2UPPbnIS875w', 'alice.wonder@example.com', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+
+-- Insert sample platforms for users
+INSERT INTO platforms (user_id, platform_name, platform_user_id, created_at, updated_at)
+VALUES (1, 'steam', 'john_steam_123', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+       (1, 'xbox', 'john_xbox_456', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+       (2, 'ps', 'jane_ps_789', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+       (3, 'steam', 'alice_steam_012', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+
+-- Insert sample groups
+INSERT INTO groups (name, parent_id, is_special, created_at, updated_at)
+VALUES
+	   ('Super Administrators', NULL, TRUE, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+	   ('Players', NULL, FALSE, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+	   ('Moderators', 2, FALSE, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+	   ('Administrators', 3, FALSE, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+
+-- Insert sample group memberships
+INSERT INTO group_members (group_id, user_id, created_at, updated_at)
+VALUES (1, 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+       (2, 2, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+       (3, 3, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+       (3, 1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+
+-- Insert sample group permissions
+INSERT INTO group_permissions (group_id, permission, read, write, delete, domain, created_at, updated_at)
+VALUES (1, 'manage_users', true, true, true, 'global', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+       (1, 'manage_content', true, true, true, 'global', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+       (2, 'moderate_content', true, true, false, 'global', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+       (3, 'view_content', true, false, false, 'global', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+
+-- Insert sample user sessions
+INSERT INTO user_sessions (user_id, token, platform_name, created_at, updated_at)
+VALUES (1, 'token_john_123456', 'steam', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+       (2, 'token_jane_789012', 'ps', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP),
+       (3, 'token_alice_345678', 'steam', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
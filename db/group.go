@@ -0,0 +1,483 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+var (
+	ErrGroupNameTaken          = errors.New("group name is already taken")
+	ErrGroupParentCycle        = errors.New("group can't inherit from itself or its descendants")
+	ErrGroupHasChildren        = errors.New("group has child groups")
+	ErrAlreadyMember           = errors.New("user is already a member of the group")
+	ErrNotMember               = errors.New("user is not a member of the group")
+	ErrGroupPermissionNotFound = errors.New("group has no such permission")
+	ErrGroupProtected          = errors.New("special group can't be renamed or deleted")
+	ErrApprovalRequired        = errors.New("membership change of a special group requires approval")
+)
+
+const groupMemberColumns = `id, group_id, user_id, expires_at, created_at, updated_at, deleted_at`
+
+// CreateGroup stores a new group. ParentId 0 creates a root group
+func (d *Database) CreateGroup(ctx context.Context, actorId int32, group *schema.GroupSchema) (*schema.GroupSchema, error) {
+	log.Traceln("Database::CreateGroup:", group.Name)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockGroup(ctx, tx, group.ParentId); err != nil {
+		return nil, fmt.Errorf("parent %w", err)
+	}
+
+	result := &schema.GroupSchema{}
+	query := `
+		INSERT INTO groups (name, description, parent_id)
+		VALUES ($1, $2, NULLIF($3, 0))
+		RETURNING ` + groupColumns
+	if err := tx.GetContext(ctx, result, query, group.Name, group.Description, group.ParentId); err != nil {
+		if isUniqueViolation(err, "groups_name_key") {
+			return nil, ErrGroupNameTaken
+		}
+		return nil, err
+	}
+
+	details := map[string]any{"group_id": result.Id, "name": result.Name, "parent_id": result.ParentId}
+	if err := insertAuditLog(ctx, tx, actorId, AuditActionGroupCreated, 0, details); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// UpdateGroup changes name, description and parent of the group. The new parent can't be the group
+// itself or one of its descendants. Special groups can't be renamed
+func (d *Database) UpdateGroup(ctx context.Context, actorId int32, group *schema.GroupSchema) (*schema.GroupSchema, error) {
+	log.Traceln("Database::UpdateGroup:", group.Id)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	current, err := lockGroupRow(ctx, tx, group.Id)
+	if err != nil {
+		return nil, err
+	}
+	if current.IsSpecial && current.Name != group.Name {
+		return nil, ErrGroupProtected
+	}
+	if err := lockGroup(ctx, tx, group.ParentId); err != nil {
+		return nil, fmt.Errorf("parent %w", err)
+	}
+
+	if err := checkParentCycle(ctx, tx, group.Id, group.ParentId); err != nil {
+		return nil, err
+	}
+
+	result := &schema.GroupSchema{}
+	query := `
+		UPDATE groups
+		SET name = $2, description = $3, parent_id = NULLIF($4, 0), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING ` + groupColumns
+	if err := tx.GetContext(ctx, result, query, group.Id, group.Name, group.Description, group.ParentId); err != nil {
+		if isUniqueViolation(err, "groups_name_key") {
+			return nil, ErrGroupNameTaken
+		}
+		return nil, err
+	}
+
+	details := map[string]any{"group_id": result.Id, "name": result.Name, "parent_id": result.ParentId}
+	if err := insertAuditLog(ctx, tx, actorId, AuditActionGroupUpdated, 0, details); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DeleteGroup soft-deletes the group together with its permissions and memberships. Groups that still
+// have child groups and special groups can't be deleted. Returns ids of users that were members
+func (d *Database) DeleteGroup(ctx context.Context, actorId, groupId int32) ([]int32, error) {
+	log.Traceln("Database::DeleteGroup:", groupId)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	current, err := lockGroupRow(ctx, tx, groupId)
+	if err != nil {
+		return nil, err
+	}
+	if current.IsSpecial {
+		return nil, ErrGroupProtected
+	}
+
+	members, err := deleteGroup(ctx, tx, actorId, groupId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// deleteGroup soft-deletes the locked group inside the caller's transaction
+func deleteGroup(ctx context.Context, tx *sqlx.Tx, actorId, groupId int32) ([]int32, error) {
+	var hasChildren bool
+	query := `SELECT EXISTS (SELECT 1 FROM groups WHERE parent_id = $1 AND deleted_at IS NULL)`
+	if err := tx.GetContext(ctx, &hasChildren, query, groupId); err != nil {
+		return nil, err
+	}
+	if hasChildren {
+		return nil, ErrGroupHasChildren
+	}
+
+	var members []int32
+	query = `
+		UPDATE group_members SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE group_id = $1 AND deleted_at IS NULL
+		RETURNING user_id`
+	if err := tx.SelectContext(ctx, &members, query, groupId); err != nil {
+		return nil, err
+	}
+
+	queries := []string{
+		`UPDATE group_permissions SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		 WHERE group_id = $1 AND deleted_at IS NULL`,
+		`UPDATE groups SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP WHERE id = $1`,
+	}
+	for _, query := range queries {
+		if _, err := tx.ExecContext(ctx, query, groupId); err != nil {
+			return nil, err
+		}
+	}
+
+	details := map[string]any{"group_id": groupId, "members": len(members)}
+	if err := insertAuditLog(ctx, tx, actorId, AuditActionGroupDeleted, 0, details); err != nil {
+		return nil, err
+	}
+
+	return members, nil
+}
+
+// RemoveGroupPermission soft-deletes the permission of the group
+func (d *Database) RemoveGroupPermission(ctx context.Context, actorId, groupId int32, permission, domain string) error {
+	log.Traceln("Database::RemoveGroupPermission:", groupId, permission, domain)
+	if d.db == nil {
+		return fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockGroup(ctx, tx, groupId); err != nil {
+		return err
+	}
+
+	if err := removeGroupPermission(ctx, tx, actorId, groupId, permission, domain); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// removeGroupPermission soft-deletes the permission inside the caller's transaction
+func removeGroupPermission(ctx context.Context, tx *sqlx.Tx, actorId, groupId int32, permission, domain string) error {
+	query := `
+		UPDATE group_permissions SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE group_id = $1 AND permission = $2 AND domain = $3 AND deleted_at IS NULL`
+	removed, err := execAffected(ctx, tx, query, groupId, permission, domain)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrGroupPermissionNotFound
+	}
+
+	details := map[string]any{"group_id": groupId, "permission": permission, "domain": domain}
+	return insertAuditLog(ctx, tx, actorId, AuditActionPermissionRemoved, 0, details)
+}
+
+// AddGroupMember adds the user to the group. Membership with expiresAt ends at that time, nil adds the user
+// permanently. Special groups return ErrApprovalRequired, their members are added by approved requests
+func (d *Database) AddGroupMember(ctx context.Context, actorId, groupId, userId int32, expiresAt *time.Time) error {
+	log.Traceln("Database::AddGroupMember:", groupId, userId)
+	if d.db == nil {
+		return fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := lockGroupRow(ctx, tx, groupId)
+	if err != nil {
+		return err
+	}
+	if current.IsSpecial {
+		return ErrApprovalRequired
+	}
+
+	details := map[string]any{"group_id": groupId, "expires_at": expiresAt}
+	if err := addMember(ctx, tx, actorId, groupId, userId, expiresAt, details); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AddGroupMemberByName adds the user to the group permanently, without approval even when the group is special.
+// Only for commands run by an operator with access to the server, e.g. to make the first superuser, so the
+// change is audited without an actor. Returns id of the group
+func (d *Database) AddGroupMemberByName(ctx context.Context, groupName string, userId int32) (int32, error) {
+	log.Traceln("Database::AddGroupMemberByName:", groupName, userId)
+	if d.db == nil {
+		return 0, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var groupId int32
+	if err := tx.GetContext(ctx, &groupId, `SELECT id FROM groups WHERE name = $1 AND deleted_at IS NULL`, groupName); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrGroupNotFound
+		}
+		return 0, err
+	}
+	current, err := lockGroupRow(ctx, tx, groupId)
+	if err != nil {
+		return 0, err
+	}
+
+	details := map[string]any{"group_id": groupId, "is_special": current.IsSpecial}
+	if err := addMember(ctx, tx, 0, groupId, userId, nil, details); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return groupId, nil
+}
+
+// addMember adds the user to the locked group inside the caller's transaction. An expired membership that
+// wasn't swept yet is replaced
+func addMember(ctx context.Context, tx *sqlx.Tx, actorId, groupId, userId int32, expiresAt *time.Time, details map[string]any) error {
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)`
+	if err := tx.GetContext(ctx, &exists, query, userId); err != nil {
+		return err
+	}
+	if !exists {
+		return ErrUserNotFound
+	}
+
+	query = `
+		UPDATE group_members SET deleted_at = expires_at, updated_at = CURRENT_TIMESTAMP
+		WHERE group_id = $1 AND user_id = $2 AND deleted_at IS NULL AND expires_at <= CURRENT_TIMESTAMP`
+	if _, err := tx.ExecContext(ctx, query, groupId, userId); err != nil {
+		return err
+	}
+
+	query = `INSERT INTO group_members (group_id, user_id, expires_at) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, query, groupId, userId, expiresAt); err != nil {
+		if isUniqueViolation(err, "group_members_active_key") {
+			return ErrAlreadyMember
+		}
+		return err
+	}
+
+	return insertAuditLog(ctx, tx, actorId, AuditActionMemberAdded, userId, details)
+}
+
+// RemoveGroupMember removes the user from the group. Special groups return ErrApprovalRequired
+func (d *Database) RemoveGroupMember(ctx context.Context, actorId, groupId, userId int32) error {
+	log.Traceln("Database::RemoveGroupMember:", groupId, userId)
+	if d.db == nil {
+		return fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	current, err := lockGroupRow(ctx, tx, groupId)
+	if err != nil {
+		return err
+	}
+	if current.IsSpecial {
+		return ErrApprovalRequired
+	}
+
+	if err := removeMember(ctx, tx, actorId, groupId, userId, map[string]any{"group_id": groupId}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// removeMember removes the user from the locked group inside the caller's transaction
+func removeMember(ctx context.Context, tx *sqlx.Tx, actorId, groupId, userId int32, details map[string]any) error {
+	query := `
+		UPDATE group_members SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE group_id = $1 AND user_id = $2 AND deleted_at IS NULL`
+	removed, err := execAffected(ctx, tx, query, groupId, userId)
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrNotMember
+	}
+
+	return insertAuditLog(ctx, tx, actorId, AuditActionMemberRemoved, userId, details)
+}
+
+// LoadGroupMembers returns memberships of the group that are not deleted or expired, ordered by user id
+func (d *Database) LoadGroupMembers(ctx context.Context, groupId int32) ([]schema.GroupMemberSchema, error) {
+	log.Traceln("Database::LoadGroupMembers:", groupId)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM groups WHERE id = $1 AND deleted_at IS NULL)`
+	if err := d.db.GetContext(ctx, &exists, query, groupId); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrGroupNotFound
+	}
+
+	var members []schema.GroupMemberSchema
+	query = `
+		SELECT ` + groupMemberColumns + ` FROM group_members
+		WHERE group_id = $1 AND deleted_at IS NULL AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+		ORDER BY user_id`
+	if err := d.db.SelectContext(ctx, &members, query, groupId); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// ExpireGroupMemberships soft-deletes memberships whose expiry has passed and returns them
+func (d *Database) ExpireGroupMemberships(ctx context.Context) ([]schema.GroupMemberSchema, error) {
+	log.Traceln("Database::ExpireGroupMemberships")
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var result []schema.GroupMemberSchema
+	query := `
+		UPDATE group_members SET deleted_at = expires_at, updated_at = CURRENT_TIMESTAMP
+		WHERE deleted_at IS NULL AND expires_at <= CURRENT_TIMESTAMP
+		RETURNING ` + groupMemberColumns
+	if err := tx.SelectContext(ctx, &result, query); err != nil {
+		return nil, err
+	}
+
+	for _, member := range result {
+		details := map[string]any{"group_id": member.GroupId, "expires_at": member.ExpiresAt}
+		if err := insertAuditLog(ctx, tx, 0, AuditActionMemberExpired, member.UserId, details); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// checkParentCycle returns ErrGroupParentCycle when the parent is the group itself or one of its descendants
+func checkParentCycle(ctx context.Context, tx *sqlx.Tx, groupId, parentId int32) error {
+	if parentId == 0 {
+		return nil
+	}
+	var cycle bool
+	query := `
+		WITH RECURSIVE ancestors (id, parent_id) AS (
+			SELECT id, parent_id FROM groups WHERE id = $1
+			UNION
+			SELECT g.id, g.parent_id FROM groups g JOIN ancestors a ON g.id = a.parent_id
+		)
+		SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $2)`
+	if err := tx.GetContext(ctx, &cycle, query, parentId, groupId); err != nil {
+		return err
+	}
+	if cycle {
+		return ErrGroupParentCycle
+	}
+	return nil
+}
+
+// lockGroup locks an existing group for the rest of the transaction. Group 0 is ignored
+func lockGroup(ctx context.Context, tx *sqlx.Tx, groupId int32) error {
+	if groupId == 0 {
+		return nil
+	}
+	_, err := lockGroupRow(ctx, tx, groupId)
+	return err
+}
+
+// lockGroupRow locks an existing group for the rest of the transaction and returns it
+func lockGroupRow(ctx context.Context, tx *sqlx.Tx, groupId int32) (*schema.GroupSchema, error) {
+	result := &schema.GroupSchema{}
+	query := `SELECT ` + groupColumns + ` FROM groups WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+	if err := tx.GetContext(ctx, result, query, groupId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrGroupNotFound
+		}
+		return nil, err
+	}
+	return result, nil
+}
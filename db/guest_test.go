@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDatabase_CreateGuestUser(t *testing.T) {
+	d := migratedTestDatabase(t)
+	ctx := context.Background()
+	if _, err := d.db.Exec(`INSERT INTO groups (name) VALUES ('Guests')`); err != nil {
+		t.Fatal(err)
+	}
+
+	guest, err := d.CreateGuestUser(ctx, "guest_1", "secret_hash", 1)
+	if err != nil {
+		t.Fatalf("CreateGuestUser() error = %v", err)
+	}
+	if !guest.IsGuest || guest.Username != "guest_1" {
+		t.Errorf("CreateGuestUser() = %+v, want guest guest_1", guest)
+	}
+
+	var member bool
+	if err := d.db.Get(&member, `SELECT EXISTS (SELECT 1 FROM group_members WHERE group_id = 1 AND user_id = $1)`, guest.Id); err != nil {
+		t.Fatal(err)
+	}
+	if !member {
+		t.Errorf("guest isn't a member of the guest group")
+	}
+
+	loaded, err := d.LoadUserByGuestSecret(ctx, "secret_hash")
+	if err != nil {
+		t.Fatalf("LoadUserByGuestSecret() error = %v", err)
+	}
+	if loaded.Id != guest.Id {
+		t.Errorf("LoadUserByGuestSecret() = user %d, want %d", loaded.Id, guest.Id)
+	}
+	if _, err := d.LoadUserByGuestSecret(ctx, "unknown_hash"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("LoadUserByGuestSecret() of an unknown secret error = %v, want %v", err, ErrUserNotFound)
+	}
+
+	if _, err := d.CreateGuestUser(ctx, "guest_1", "other_hash", 0); !errors.Is(err, ErrUsernameTaken) {
+		t.Errorf("CreateGuestUser() with a taken username error = %v, want %v", err, ErrUsernameTaken)
+	}
+}
+
+// guestSetup creates guest 1 and full account 2
+const guestSetup = `
+	INSERT INTO users (username, is_guest, guest_secret) VALUES ('guest_1', TRUE, 'secret_hash');
+	INSERT INTO users (username, password, email) VALUES ('player', 'hash', 'player@localhost');
+	INSERT INTO platforms (user_id, platform_name, platform_user_id) VALUES (2, 'steam', '76561198000000001');`
+
+func TestDatabase_UpgradeGuestWithCredentials(t *testing.T) {
+	tests := []struct {
+		name         string
+		userId       int32
+		username     string
+		email        string
+		wantUsername string
+		wantErr      error
+	}{
+		{"Keeps generated username", 1, "", "guest@localhost", "guest_1", nil},
+		{"Sets username", 1, "newplayer", "guest@localhost", "newplayer", nil},
+		{"Full account", 2, "", "other@localhost", "", ErrNotGuest},
+		{"Username taken", 1, "PLAYER", "guest@localhost", "", ErrUsernameTaken},
+		{"Email taken", 1, "", "player@localhost", "", ErrEmailTaken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := migratedTestDatabase(t)
+			ctx := context.Background()
+			if _, err := d.db.Exec(guestSetup); err != nil {
+				t.Fatal(err)
+			}
+
+			upgraded, err := d.UpgradeGuestWithCredentials(ctx, tt.userId, tt.username, tt.email, "password_hash")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpgradeGuestWithCredentials() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if upgraded.Id != tt.userId || upgraded.Username != tt.wantUsername || upgraded.IsGuest {
+				t.Errorf("UpgradeGuestWithCredentials() = %+v, want full account %d [%s]", upgraded, tt.userId, tt.wantUsername)
+			}
+			if _, err := d.LoadUserByGuestSecret(ctx, "secret_hash"); !errors.Is(err, ErrUserNotFound) {
+				t.Errorf("upgraded user is still found by the guest secret, error = %v", err)
+			}
+		})
+	}
+}
+
+func TestDatabase_UpgradeGuestWithPlatform(t *testing.T) {
+	tests := []struct {
+		name           string
+		userId         int32
+		platformUserId string
+		wantErr        error
+	}{
+		{"Links identity", 1, "76561198000000002", nil},
+		{"Identity of another account", 1, "76561198000000001", ErrPlatformLinked},
+		{"Full account", 2, "76561198000000002", ErrNotGuest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := migratedTestDatabase(t)
+			ctx := context.Background()
+			if _, err := d.db.Exec(guestSetup); err != nil {
+				t.Fatal(err)
+			}
+
+			upgraded, err := d.UpgradeGuestWithPlatform(ctx, tt.userId, "steam", tt.platformUserId)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpgradeGuestWithPlatform() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var owner int32
+			query := `SELECT user_id FROM platforms WHERE platform_name = 'steam' AND platform_user_id = $1`
+			err = d.db.Get(&owner, query, tt.platformUserId)
+			if tt.wantErr != nil {
+				var guest bool
+				if err := d.db.Get(&guest, `SELECT is_guest FROM users WHERE id = 1`); err != nil {
+					t.Fatal(err)
+				}
+				if !guest {
+					t.Errorf("refused upgrade turned the guest into a full account")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if upgraded.IsGuest || owner != tt.userId {
+				t.Errorf("UpgradeGuestWithPlatform() = %+v, identity owned by %d, want full account %d", upgraded, owner, tt.userId)
+			}
+		})
+	}
+}
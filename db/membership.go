@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrMembershipRequestNotFound = errors.New("membership request not found")
+	ErrMembershipRequestDecided  = errors.New("membership request is already decided")
+	ErrMembershipRequestPending  = errors.New("membership change of this user is already waiting for approval")
+	ErrSelfApproval              = errors.New("membership request must be approved by another admin")
+)
+
+const membershipRequestColumns = `id, group_id, user_id, change, expires_at, status, requested_by, decided_by, created_at, decided_at`
+
+// RequestMembershipChange stores a membership change of a special group until another admin approves it.
+// The change has to be applicable right now: users are added only if they are not members yet and removed
+// only if they are
+func (d *Database) RequestMembershipChange(ctx context.Context, request *schema.MembershipRequestSchema) (*schema.MembershipRequestSchema, error) {
+	log.Traceln("Database::RequestMembershipChange:", request.GroupId, request.UserId, request.Change)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockGroup(ctx, tx, request.GroupId); err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)`
+	if err := tx.GetContext(ctx, &exists, query, request.UserId); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	var member bool
+	query = `
+		SELECT EXISTS (SELECT 1 FROM group_members
+		WHERE group_id = $1 AND user_id = $2 AND deleted_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP))`
+	if err := tx.GetContext(ctx, &member, query, request.GroupId, request.UserId); err != nil {
+		return nil, err
+	}
+	if request.Change == schema.MembershipChangeAdd && member {
+		return nil, ErrAlreadyMember
+	}
+	if request.Change == schema.MembershipChangeRemove && !member {
+		return nil, ErrNotMember
+	}
+
+	result := &schema.MembershipRequestSchema{}
+	query = `
+		INSERT INTO membership_requests (group_id, user_id, change, expires_at, requested_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + membershipRequestColumns
+	err = tx.GetContext(ctx, result, query, request.GroupId, request.UserId, request.Change, request.ExpiresAt, request.RequestedBy)
+	if err != nil {
+		if isUniqueViolation(err, "membership_requests_pending_key") {
+			return nil, ErrMembershipRequestPending
+		}
+		return nil, err
+	}
+
+	details := map[string]any{"request_id": result.Id, "group_id": result.GroupId, "change": result.Change, "expires_at": result.ExpiresAt}
+	if err := insertAuditLog(ctx, tx, result.RequestedBy, AuditActionMembershipRequest, result.UserId, details); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DecideMembershipRequest approves or rejects a pending request. Approved changes are applied in the same
+// transaction. Requests can't be approved by the admin who made them, but can be rejected to withdraw them
+func (d *Database) DecideMembershipRequest(ctx context.Context, actorId, requestId int32, approve bool) (*schema.MembershipRequestSchema, error) {
+	log.Traceln("Database::DecideMembershipRequest:", requestId, approve)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	request := &schema.MembershipRequestSchema{}
+	query := `SELECT ` + membershipRequestColumns + ` FROM membership_requests WHERE id = $1 FOR UPDATE`
+	if err := tx.GetContext(ctx, request, query, requestId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrMembershipRequestNotFound
+		}
+		return nil, err
+	}
+	if request.Status != schema.ApprovalPending {
+		return nil, ErrMembershipRequestDecided
+	}
+
+	status, action := schema.ApprovalRejected, AuditActionMembershipReject
+	if approve {
+		if request.RequestedBy == actorId {
+			return nil, ErrSelfApproval
+		}
+		status, action = schema.ApprovalApproved, AuditActionMembershipApprove
+
+		if err := lockGroup(ctx, tx, request.GroupId); err != nil {
+			return nil, err
+		}
+		details := map[string]any{"group_id": request.GroupId, "request_id": request.Id, "requested_by": request.RequestedBy}
+		switch request.Change {
+		case schema.MembershipChangeAdd:
+			details["expires_at"] = request.ExpiresAt
+			err = addMember(ctx, tx, actorId, request.GroupId, request.UserId, request.ExpiresAt, details)
+		case schema.MembershipChangeRemove:
+			err = removeMember(ctx, tx, actorId, request.GroupId, request.UserId, details)
+		default:
+			err = fmt.Errorf("unknown membership change %q", request.Change)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	query = `
+		UPDATE membership_requests SET status = $2, decided_by = $3, decided_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING ` + membershipRequestColumns
+	if err := tx.GetContext(ctx, request, query, requestId, status, actorId); err != nil {
+		return nil, err
+	}
+
+	details := map[string]any{"request_id": request.Id, "group_id": request.GroupId, "change": request.Change}
+	if err := insertAuditLog(ctx, tx, actorId, action, request.UserId, details); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
+
+// LoadPendingMembershipRequests returns requests waiting for approval, oldest first
+func (d *Database) LoadPendingMembershipRequests(ctx context.Context) ([]schema.MembershipRequestSchema, error) {
+	log.Traceln("Database::LoadPendingMembershipRequests")
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	var result []schema.MembershipRequestSchema
+	query := `SELECT ` + membershipRequestColumns + ` FROM membership_requests WHERE status = $1 ORDER BY id`
+	if err := d.db.SelectContext(ctx, &result, query, schema.ApprovalPending); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
@@ -0,0 +1,148 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrMergeSameUser = errors.New("can't merge user into itself")
+	ErrMergeConflict = errors.New("both users have an identity on the same platform")
+)
+
+// MergeUsers moves everything of sourceId into targetId in a single transaction:
+// platforms are moved, group memberships are united, source sessions are revoked
+// and source user is soft-deleted. Memberships of special groups are requested for the
+// target instead, unless the merge is started from CLI. The merge is recorded in audit_log.
+// actorId is the admin who requested the merge or 0 when started from CLI
+func (d *Database) MergeUsers(ctx context.Context, targetId, sourceId, actorId int32) (*schema.MergeResultSchema, error) {
+	log.Traceln("Database::MergeUsers:", sourceId, "->", targetId)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	if targetId == sourceId {
+		return nil, ErrMergeSameUser
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock both rows in a stable order to avoid deadlocks with concurrent merges
+	var locked []int32
+	query := `SELECT id FROM users WHERE id IN ($1, $2) AND deleted_at IS NULL ORDER BY id FOR UPDATE`
+	if err := tx.SelectContext(ctx, &locked, query, targetId, sourceId); err != nil {
+		return nil, err
+	}
+	if len(locked) != 2 {
+		return nil, ErrUserNotFound
+	}
+
+	var conflicts []string
+	query = `
+		SELECT s.platform_name FROM platforms s
+		JOIN platforms t ON t.platform_name = s.platform_name AND t.user_id = $1 AND t.deleted_at IS NULL
+		WHERE s.user_id = $2 AND s.deleted_at IS NULL`
+	if err := tx.SelectContext(ctx, &conflicts, query, targetId, sourceId); err != nil {
+		return nil, err
+	}
+	if len(conflicts) > 0 {
+		return nil, fmt.Errorf("%w: %v", ErrMergeConflict, conflicts)
+	}
+
+	result := &schema.MergeResultSchema{TargetUserId: targetId, SourceUserId: sourceId}
+
+	// Unlinked rows of the target would collide with UNIQUE (user_id, platform_name)
+	query = `
+		DELETE FROM platforms
+		WHERE user_id = $1 AND deleted_at IS NOT NULL
+		  AND platform_name IN (SELECT platform_name FROM platforms WHERE user_id = $2 AND deleted_at IS NULL)`
+	if _, err := tx.ExecContext(ctx, query, targetId, sourceId); err != nil {
+		return nil, err
+	}
+
+	query = `
+		UPDATE platforms SET user_id = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $2 AND deleted_at IS NULL`
+	if result.MovedPlatforms, err = execAffected(ctx, tx, query, targetId, sourceId); err != nil {
+		return nil, err
+	}
+
+	// Memberships of special groups make the target a superuser, so an admin can't copy them without approval
+	// of another admin. Operators merging from CLI can
+	const sourceMemberships = `
+		FROM group_members s
+		JOIN groups g ON g.id = s.group_id
+		WHERE s.user_id = $2 AND s.deleted_at IS NULL
+		  AND (s.expires_at IS NULL OR s.expires_at > CURRENT_TIMESTAMP)
+		  AND NOT EXISTS (
+		      SELECT 1 FROM group_members t
+		      WHERE t.user_id = $1 AND t.group_id = s.group_id AND t.deleted_at IS NULL)`
+
+	query = `
+		INSERT INTO group_members (group_id, user_id, expires_at)
+		SELECT DISTINCT s.group_id, $1::INTEGER, s.expires_at ` + sourceMemberships + `
+		  AND (NOT g.is_special OR $3::INTEGER = 0)`
+	if result.AddedGroups, err = execAffected(ctx, tx, query, targetId, sourceId, actorId); err != nil {
+		return nil, err
+	}
+
+	if actorId != 0 {
+		// Pending requests of the target for the same group are kept
+		var requests []schema.MembershipRequestSchema
+		query = `
+			INSERT INTO membership_requests (group_id, user_id, change, expires_at, requested_by)
+			SELECT DISTINCT s.group_id, $1::INTEGER, 'add'::membership_change, s.expires_at, $3::INTEGER ` + sourceMemberships + `
+			  AND g.is_special
+			ON CONFLICT DO NOTHING
+			RETURNING ` + membershipRequestColumns
+		if err := tx.SelectContext(ctx, &requests, query, targetId, sourceId, actorId); err != nil {
+			return nil, err
+		}
+		for _, request := range requests {
+			details := map[string]any{"request_id": request.Id, "group_id": request.GroupId, "change": request.Change,
+				"expires_at": request.ExpiresAt, "merged_from": sourceId}
+			if err := insertAuditLog(ctx, tx, actorId, AuditActionMembershipRequest, targetId, details); err != nil {
+				return nil, err
+			}
+			result.MembershipRequests = append(result.MembershipRequests, request.Id)
+		}
+	}
+
+	query = `
+		UPDATE group_members SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND deleted_at IS NULL`
+	if _, err := tx.ExecContext(ctx, query, sourceId); err != nil {
+		return nil, err
+	}
+
+	query = `
+		UPDATE user_sessions SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND deleted_at IS NULL`
+	if result.RevokedSessions, err = execAffected(ctx, tx, query, sourceId); err != nil {
+		return nil, err
+	}
+
+	query = `
+		UPDATE users SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, guest_secret = NULL
+		WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, query, sourceId); err != nil {
+		return nil, err
+	}
+
+	if err := insertAuditLog(ctx, tx, actorId, AuditActionUserMerged, targetId, result); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
@@ -0,0 +1,129 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestDatabase_MergeUsers(t *testing.T) {
+	d := migratedTestDatabase(t)
+	ctx := context.Background()
+	setup := `
+		INSERT INTO users (username, password, email) VALUES
+			('target', 'hash', 'target@localhost'), ('source', 'hash', 'source@localhost'), ('other', 'hash', 'other@localhost');
+		INSERT INTO platforms (user_id, platform_name, platform_user_id) VALUES
+			(1, 'steam', 'steam_target'), (2, 'ps', 'ps_source'), (3, 'steam', 'steam_other');
+		INSERT INTO groups (name) VALUES ('Players'), ('Moderators');
+		INSERT INTO group_members (group_id, user_id) VALUES (1, 1), (1, 2), (2, 2);
+		INSERT INTO user_sessions (user_id, token, platform_name) VALUES (2, 'source_token', 'ps');`
+	if _, err := d.db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+
+	refusals := []struct {
+		name     string
+		targetId int32
+		sourceId int32
+		wantErr  error
+	}{
+		{"Same user", 1, 1, ErrMergeSameUser},
+		{"Missing user", 1, 4, ErrUserNotFound},
+		{"Both on the same platform", 1, 3, ErrMergeConflict},
+	}
+	for _, tt := range refusals {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := d.MergeUsers(ctx, tt.targetId, tt.sourceId, 0); !errors.Is(err, tt.wantErr) {
+				t.Errorf("MergeUsers() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	result, err := d.MergeUsers(ctx, 1, 2, 0)
+	if err != nil {
+		t.Fatalf("MergeUsers() error = %v", err)
+	}
+	if result.MovedPlatforms != 1 || result.AddedGroups != 1 || result.RevokedSessions != 1 {
+		t.Errorf("MergeUsers() = %+v, want 1 platform, 1 group and 1 session", result)
+	}
+
+	var moved, member, deleted, audited bool
+	checks := []struct {
+		dest  *bool
+		query string
+	}{
+		{&moved, `SELECT EXISTS (SELECT 1 FROM platforms WHERE user_id = 1 AND platform_name = 'ps' AND deleted_at IS NULL)`},
+		{&member, `SELECT EXISTS (SELECT 1 FROM group_members WHERE group_id = 2 AND user_id = 1 AND deleted_at IS NULL)`},
+		{&deleted, `SELECT deleted_at IS NOT NULL FROM users WHERE id = 2`},
+		{&audited, `SELECT EXISTS (SELECT 1 FROM audit_log WHERE action = '` + AuditActionUserMerged + `' AND target_user_id = 1)`},
+	}
+	for _, check := range checks {
+		if err := d.db.Get(check.dest, check.query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !moved || !member || !deleted || !audited {
+		t.Errorf("after MergeUsers() platform moved: %v, group added: %v, source deleted: %v, audited: %v, want all true",
+			moved, member, deleted, audited)
+	}
+
+	if _, err := d.MergeUsers(ctx, 1, 2, 0); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("MergeUsers() of a merged user error = %v, want ErrUserNotFound", err)
+	}
+}
+
+func TestDatabase_MergeUsers_SpecialGroups(t *testing.T) {
+	tests := []struct {
+		name         string
+		actorId      int32
+		wantMember   bool
+		wantRequests int
+	}{
+		{"Admin", 3, false, 1},
+		{"CLI", 0, true, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := migratedTestDatabase(t)
+			ctx := context.Background()
+			setup := `
+				INSERT INTO users (username, password, email) VALUES
+					('target', 'hash', 'target@localhost'), ('source', 'hash', 'source@localhost'), ('admin', 'hash', 'admin@localhost');
+				INSERT INTO groups (name, is_special) VALUES ('Super Administrators', TRUE), ('Players', FALSE);
+				INSERT INTO group_members (group_id, user_id) VALUES (1, 2), (2, 2);`
+			if _, err := d.db.Exec(setup); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := d.MergeUsers(ctx, 1, 2, tt.actorId)
+			if err != nil {
+				t.Fatalf("MergeUsers() error = %v", err)
+			}
+			if len(result.MembershipRequests) != tt.wantRequests {
+				t.Errorf("MergeUsers() requested %v, want %d requests", result.MembershipRequests, tt.wantRequests)
+			}
+
+			var special, regular, pending, audited bool
+			checks := []struct {
+				dest  *bool
+				query string
+			}{
+				{&special, `SELECT EXISTS (SELECT 1 FROM group_members WHERE group_id = 1 AND user_id = 1 AND deleted_at IS NULL)`},
+				{&regular, `SELECT EXISTS (SELECT 1 FROM group_members WHERE group_id = 2 AND user_id = 1 AND deleted_at IS NULL)`},
+				{&pending, `SELECT EXISTS (SELECT 1 FROM membership_requests WHERE group_id = 1 AND user_id = 1 AND status = 'pending')`},
+				{&audited, `SELECT EXISTS (SELECT 1 FROM audit_log WHERE action = '` + AuditActionMembershipRequest + `' AND target_user_id = 1)`},
+			}
+			for _, check := range checks {
+				if err := d.db.Get(check.dest, check.query); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if special != tt.wantMember || !regular {
+				t.Errorf("target is member of special group: %v, regular group: %v, want %v and true", special, regular, tt.wantMember)
+			}
+			if wantPending := tt.wantRequests > 0; pending != wantPending || audited != wantPending {
+				t.Errorf("pending request: %v, audited: %v, want %v", pending, audited, wantPending)
+			}
+		})
+	}
+}
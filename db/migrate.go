@@ -0,0 +1,493 @@
+package db
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql/driver"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+//go:embed fixtures/dev.sql
+var devFixtures string
+
+// migrationLockId is the key of the advisory lock held while migrating, so replicas starting together
+// don't apply the same migration twice
+const migrationLockId int64 = 7245082146
+
+// baselineSchema is a scratch schema the initial migration is replayed in, to compare the result with
+// a database that has no migration history
+const baselineSchema = "schema_migrations_baseline"
+
+const createMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations
+(
+	version    INTEGER PRIMARY KEY,
+	name       VARCHAR(255) NOT NULL,
+	checksum   CHAR(64)     NOT NULL,
+	applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+var (
+	ErrInvalidMigration  = errors.New("invalid migration")
+	ErrMigrationModified = errors.New("applied migration was modified")
+	ErrUnknownMigration  = errors.New("applied migration is unknown to this build")
+	ErrPendingMigrations = errors.New("database schema is not up to date")
+	ErrSchemaMismatch    = errors.New("database has no migration history and its schema doesn't match")
+)
+
+// migrationFilePattern matches NNNN_name.up.sql and NNNN_name.down.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// Migration is a pair of scripts moving the schema to Version and back
+type Migration struct {
+	Version  int32
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // sha256 of the up script
+}
+
+// MigrationStatus describes a migration known to this build or recorded in the database
+type MigrationStatus struct {
+	Version   int32
+	Name      string
+	AppliedAt *time.Time // nil while pending
+	Modified  bool       // Up script changed after the migration was applied
+	Unknown   bool       // Applied by another build, this one doesn't have the scripts
+}
+
+// Migrations returns migrations embedded into the binary, ordered by version
+func Migrations() ([]Migration, error) {
+	files, err := fs.Sub(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	return parseMigrations(files)
+}
+
+// parseMigrations reads migration scripts from the root of fsys. Every version must have both scripts
+func parseMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int32]*Migration)
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			return nil, fmt.Errorf("%w: unexpected file %s", ErrInvalidMigration, entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 32)
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("%w: bad version in %s", ErrInvalidMigration, entry.Name())
+		}
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[int32(version)]
+		if !ok {
+			migration = &Migration{Version: int32(version), Name: match[2]}
+			byVersion[int32(version)] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("%w: version %d is used by %s and %s", ErrInvalidMigration, version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			sum := sha256.Sum256(body)
+			migration.Up = string(body)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("%w: %04d_%s must have both up and down scripts", ErrInvalidMigration, migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// verifyApplied makes sure every applied migration is known and unchanged. Returns known migrations by version
+func verifyApplied(known []Migration, applied []schema.SchemaMigrationSchema) (map[int32]Migration, error) {
+	byVersion := make(map[int32]Migration, len(known))
+	for _, migration := range known {
+		byVersion[migration.Version] = migration
+	}
+	for _, record := range applied {
+		migration, ok := byVersion[record.Version]
+		if !ok {
+			return nil, fmt.Errorf("%w: %04d_%s", ErrUnknownMigration, record.Version, record.Name)
+		}
+		if migration.Checksum != record.Checksum {
+			return nil, fmt.Errorf("%w: %04d_%s", ErrMigrationModified, record.Version, record.Name)
+		}
+	}
+	return byVersion, nil
+}
+
+// MigrateUp applies every pending migration, each in its own transaction. Returns applied migrations
+func (d *Database) MigrateUp(ctx context.Context) ([]Migration, error) {
+	log.Traceln("Database::MigrateUp")
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+	known, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var migrated []Migration
+	err = d.withMigrationLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 && len(known) > 0 && known[0].Version == 1 {
+			if applied, err = baselineMigrations(ctx, conn, known[0]); err != nil {
+				return err
+			}
+		}
+		if _, err := verifyApplied(known, applied); err != nil {
+			return err
+		}
+
+		done := make(map[int32]bool, len(applied))
+		for _, record := range applied {
+			done[record.Version] = true
+		}
+		for _, migration := range known {
+			if done[migration.Version] {
+				continue
+			}
+			if err := runMigration(ctx, conn, migration.Up, func(tx *sqlx.Tx) error {
+				_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+					migration.Version, migration.Name, migration.Checksum)
+				return err
+			}); err != nil {
+				return fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			log.Infof("Applied migration %04d_%s", migration.Version, migration.Name)
+			migrated = append(migrated, migration)
+		}
+		return nil
+	})
+	return migrated, err
+}
+
+// MigrateDown reverts the latest steps applied migrations, newest first. Returns reverted migrations
+func (d *Database) MigrateDown(ctx context.Context, steps int) ([]Migration, error) {
+	log.Traceln("Database::MigrateDown:", steps)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+	if steps < 1 {
+		return nil, fmt.Errorf("steps must be positive")
+	}
+	known, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var reverted []Migration
+	err = d.withMigrationLock(ctx, func(conn *sqlx.Conn) error {
+		applied, err := appliedMigrations(ctx, conn)
+		if err != nil {
+			return err
+		}
+		byVersion, err := verifyApplied(known, applied)
+		if err != nil {
+			return err
+		}
+
+		for i := len(applied) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := byVersion[applied[i].Version]
+			if err := runMigration(ctx, conn, migration.Down, func(tx *sqlx.Tx) error {
+				_, err := tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+				return err
+			}); err != nil {
+				return fmt.Errorf("reverting migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			log.Infof("Reverted migration %04d_%s", migration.Version, migration.Name)
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// MigrationStatus lists known migrations and migrations recorded in the database, ordered by version.
+// It doesn't modify the database
+func (d *Database) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	log.Traceln("Database::MigrationStatus")
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+	known, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var exists bool
+	if err := d.db.GetContext(ctx, &exists, `SELECT to_regclass('schema_migrations') IS NOT NULL`); err != nil {
+		return nil, err
+	}
+	var applied []schema.SchemaMigrationSchema
+	if exists {
+		if applied, err = appliedMigrations(ctx, d.db); err != nil {
+			return nil, err
+		}
+	}
+
+	records := make(map[int32]schema.SchemaMigrationSchema, len(applied))
+	for _, record := range applied {
+		records[record.Version] = record
+	}
+	var statuses []MigrationStatus
+	for _, migration := range known {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if record, ok := records[migration.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+			status.Modified = record.Checksum != migration.Checksum
+			delete(records, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range records {
+		statuses = append(statuses, MigrationStatus{Version: record.Version, Name: record.Name, AppliedAt: &record.AppliedAt, Unknown: true})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// CheckMigrations returns ErrPendingMigrations unless every known migration is applied unchanged
+// and the database has no migrations unknown to this build
+func (d *Database) CheckMigrations(ctx context.Context) error {
+	statuses, err := d.MigrationStatus(ctx)
+	if err != nil {
+		return err
+	}
+	for _, status := range statuses {
+		switch {
+		case status.Unknown:
+			return fmt.Errorf("%w: %04d_%s", ErrUnknownMigration, status.Version, status.Name)
+		case status.Modified:
+			return fmt.Errorf("%w: %04d_%s", ErrMigrationModified, status.Version, status.Name)
+		case status.AppliedAt == nil:
+			return fmt.Errorf("%w: %04d_%s is pending", ErrPendingMigrations, status.Version, status.Name)
+		}
+	}
+	return nil
+}
+
+// ApplyDevFixtures inserts sample data for local development into a database without users.
+// Returns false if the database already has users and nothing was inserted
+func (d *Database) ApplyDevFixtures(ctx context.Context) (bool, error) {
+	log.Traceln("Database::ApplyDevFixtures")
+	if d.db == nil {
+		return false, fmt.Errorf("db is nil")
+	}
+
+	applied := false
+	err := d.withMigrationLock(ctx, func(conn *sqlx.Conn) error {
+		var hasUsers bool
+		if err := conn.GetContext(ctx, &hasUsers, `SELECT EXISTS (SELECT 1 FROM users)`); err != nil {
+			return err
+		}
+		if hasUsers {
+			return nil
+		}
+		if err := runMigration(ctx, conn, devFixtures, nil); err != nil {
+			return fmt.Errorf("dev fixtures failed: %w", err)
+		}
+		applied = true
+		return nil
+	})
+	return applied, err
+}
+
+// withMigrationLock runs fn on a dedicated connection holding the migration advisory lock. Waits while
+// another replica is migrating
+func (d *Database) withMigrationLock(ctx context.Context, fn func(conn *sqlx.Conn) error) error {
+	conn, err := d.db.Connx(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockId); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, migrationLockId); err != nil {
+			log.Errorf("Failed to release migration lock: %v", err)
+			// Session locks live as long as the connection, so it must not go back to the pool
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createMigrationsTable); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// runMigration executes the script and record in one transaction
+func runMigration(ctx context.Context, conn *sqlx.Conn, script string, record func(tx *sqlx.Tx) error) error {
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if record != nil {
+		if err := record(tx); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func appliedMigrations(ctx context.Context, q sqlx.QueryerContext) ([]schema.SchemaMigrationSchema, error) {
+	var applied []schema.SchemaMigrationSchema
+	err := sqlx.SelectContext(ctx, q, &applied, `SELECT version, name, checksum, applied_at FROM schema_migrations ORDER BY version`)
+	return applied, err
+}
+
+// baselineMigrations records the initial migration as applied to databases created from db.sql before
+// migrations existed. Their schema must match the initial migration exactly, so a database created from any
+// other db.sql is never mistaken for it
+func baselineMigrations(ctx context.Context, conn *sqlx.Conn, initial Migration) ([]schema.SchemaMigrationSchema, error) {
+	var exists bool
+	if err := conn.GetContext(ctx, &exists, `SELECT to_regclass('users') IS NOT NULL`); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	if err := compareBaseline(ctx, conn, initial); err != nil {
+		return nil, err
+	}
+
+	log.Warnf("Database has the initial schema but no migration history, recording %04d_%s as applied", initial.Version, initial.Name)
+	if _, err := conn.ExecContext(ctx, `INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+		initial.Version, initial.Name, initial.Checksum); err != nil {
+		return nil, err
+	}
+	return appliedMigrations(ctx, conn)
+}
+
+// schemaColumn describes a table column as far as comparing schemas is concerned
+type schemaColumn struct {
+	Table    string `db:"table_name"`
+	Column   string `db:"column_name"`
+	Type     string `db:"udt_name"`
+	Nullable string `db:"is_nullable"`
+	Length   int    `db:"length"`
+}
+
+// compareBaseline replays the initial migration in a scratch schema inside a transaction that is never
+// committed, and compares its tables with the current schema
+func compareBaseline(ctx context.Context, conn *sqlx.Conn, initial Migration) error {
+	var current string
+	if err := conn.GetContext(ctx, &current, `SELECT current_schema()`); err != nil {
+		return err
+	}
+
+	tx, err := conn.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `CREATE SCHEMA `+baselineSchema); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `SET LOCAL search_path TO `+baselineSchema); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, initial.Up); err != nil {
+		return fmt.Errorf("failed to replay %04d_%s: %w", initial.Version, initial.Name, err)
+	}
+
+	expected, err := schemaColumns(ctx, tx, baselineSchema)
+	if err != nil {
+		return err
+	}
+	actual, err := schemaColumns(ctx, tx, current)
+	if err != nil {
+		return err
+	}
+	if diff := diffColumns(expected, actual); len(diff) > 0 {
+		return fmt.Errorf("%w %04d_%s: %s", ErrSchemaMismatch, initial.Version, initial.Name, strings.Join(diff, ", "))
+	}
+	return nil
+}
+
+func schemaColumns(ctx context.Context, q sqlx.QueryerContext, schemaName string) ([]schemaColumn, error) {
+	var columns []schemaColumn
+	err := sqlx.SelectContext(ctx, q, &columns, `
+		SELECT table_name, column_name, udt_name, is_nullable, COALESCE(character_maximum_length, 0) AS length
+		FROM information_schema.columns
+		WHERE table_schema = $1
+		ORDER BY table_name, column_name`, schemaName)
+	return columns, err
+}
+
+// diffColumns lists differences of actual columns from expected ones, in tables that expected has.
+// Other tables of the actual schema are ignored
+func diffColumns(expected, actual []schemaColumn) []string {
+	tables := make(map[string]bool)
+	want := make(map[string]schemaColumn, len(expected))
+	for _, column := range expected {
+		tables[column.Table] = true
+		want[column.Table+"."+column.Column] = column
+	}
+
+	var diff []string
+	for _, column := range actual {
+		if !tables[column.Table] {
+			continue
+		}
+		name := column.Table + "." + column.Column
+		expectedColumn, ok := want[name]
+		switch {
+		case !ok:
+			diff = append(diff, "unexpected column "+name)
+		case expectedColumn != column:
+			diff = append(diff, "column "+name+" differs")
+		}
+		delete(want, name)
+	}
+	for name := range want {
+		diff = append(diff, "missing column "+name)
+	}
+	sort.Strings(diff)
+	return diff
+}
@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/savageking-io/ogbuser/schema"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestParseMigrations(t *testing.T) {
+	file := func(body string) *fstest.MapFile { return &fstest.MapFile{Data: []byte(body)} }
+	tests := []struct {
+		name         string
+		files        fstest.MapFS
+		wantVersions []int32
+		wantErr      bool
+	}{
+		{"Ordered by version", fstest.MapFS{
+			"0010_sessions.up.sql":   file("CREATE TABLE b ();"),
+			"0010_sessions.down.sql": file("DROP TABLE b;"),
+			"0002_users.up.sql":      file("CREATE TABLE a ();"),
+			"0002_users.down.sql":    file("DROP TABLE a;"),
+		}, []int32{2, 10}, false},
+		{"Missing down", fstest.MapFS{"0001_initial.up.sql": file("CREATE TABLE a ();")}, nil, true},
+		{"Empty up", fstest.MapFS{
+			"0001_initial.up.sql":   file(""),
+			"0001_initial.down.sql": file("DROP TABLE a;"),
+		}, nil, true},
+		{"Same version, different names", fstest.MapFS{
+			"0001_initial.up.sql":   file("CREATE TABLE a ();"),
+			"0001_other.down.sql":   file("DROP TABLE a;"),
+			"0001_initial.down.sql": file("DROP TABLE a;"),
+		}, nil, true},
+		{"Version zero", fstest.MapFS{
+			"0000_initial.up.sql":   file("CREATE TABLE a ();"),
+			"0000_initial.down.sql": file("DROP TABLE a;"),
+		}, nil, true},
+		{"Unexpected file", fstest.MapFS{"README.md": file("")}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMigrations(tt.files)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseMigrations() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrInvalidMigration) {
+					t.Errorf("parseMigrations() error = %v, want ErrInvalidMigration", err)
+				}
+				return
+			}
+			if len(got) != len(tt.wantVersions) {
+				t.Fatalf("parseMigrations() returned %d migrations, want %d", len(got), len(tt.wantVersions))
+			}
+			for i, migration := range got {
+				if migration.Version != tt.wantVersions[i] {
+					t.Errorf("migration %d has version %d, want %d", i, migration.Version, tt.wantVersions[i])
+				}
+				if len(migration.Checksum) != 64 {
+					t.Errorf("migration %d has checksum %q", i, migration.Checksum)
+				}
+			}
+		})
+	}
+}
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("Migrations() error = %v", err)
+	}
+	if len(migrations) == 0 || migrations[0].Version != 1 {
+		t.Fatalf("Migrations() must start with version 1, got %+v", migrations)
+	}
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version != migrations[i-1].Version+1 {
+			t.Errorf("migration %04d_%s follows version %d", migrations[i].Version, migrations[i].Name, migrations[i-1].Version)
+		}
+	}
+}
+
+func TestVerifyApplied(t *testing.T) {
+	known := []Migration{{Version: 1, Name: "initial", Checksum: "a"}, {Version: 2, Name: "sessions", Checksum: "b"}}
+	tests := []struct {
+		name    string
+		applied []schema.SchemaMigrationSchema
+		wantErr error
+	}{
+		{"Nothing applied", nil, nil},
+		{"Partially applied", []schema.SchemaMigrationSchema{{Version: 1, Name: "initial", Checksum: "a"}}, nil},
+		{"Modified", []schema.SchemaMigrationSchema{{Version: 1, Name: "initial", Checksum: "c"}}, ErrMigrationModified},
+		{"Unknown", []schema.SchemaMigrationSchema{{Version: 3, Name: "future", Checksum: "d"}}, ErrUnknownMigration},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifyApplied(known, tt.applied)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("verifyApplied() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDiffColumns(t *testing.T) {
+	expected := []schemaColumn{
+		{Table: "users", Column: "id", Type: "int4", Nullable: "NO"},
+		{Table: "users", Column: "email", Type: "varchar", Nullable: "NO", Length: 100},
+	}
+	tests := []struct {
+		name   string
+		actual []schemaColumn
+		want   []string
+	}{
+		{"Same", expected, nil},
+		{"Other tables are ignored", append(expected, schemaColumn{Table: "sessions", Column: "id", Type: "int4", Nullable: "NO"}), nil},
+		{"Unexpected column", append(expected, schemaColumn{Table: "users", Column: "is_guest", Type: "bool", Nullable: "NO"}),
+			[]string{"unexpected column users.is_guest"}},
+		{"Changed column", []schemaColumn{expected[0], {Table: "users", Column: "email", Type: "varchar", Nullable: "YES", Length: 100}},
+			[]string{"column users.email differs"}},
+		{"Missing column", expected[:1], []string{"missing column users.email"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffColumns(expected, tt.actual); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffColumns() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// baselineSeed is data a database populated from the original db.sql had, including groups that are their own
+// parents and a duplicated membership
+const baselineSeed = `
+INSERT INTO users (username, password, email) VALUES ('root', 'hash', 'admin@localhost'), ('jane', 'hash', 'jane@localhost');
+INSERT INTO groups (name, parent_id, is_special) VALUES ('Super Administrators', 1, TRUE), ('Players', 2, FALSE);
+INSERT INTO group_members (group_id, user_id) VALUES (1, 1), (2, 2), (2, 2);
+INSERT INTO group_permissions (group_id, permission, read, write, delete, domain) VALUES (1, 'manage_users', true, true, true, 'global');
+`
+
+func TestDatabase_MigrateUp_Baseline(t *testing.T) {
+	known, err := Migrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		setup        []string
+		wantMigrated int
+		wantMembers  int
+		wantErr      error
+	}{
+		{"Empty database", nil, len(known), 0, nil},
+		{"Baseline schema", []string{known[0].Up, baselineSeed}, len(known) - 1, 2, nil},
+		{"Unknown schema", []string{known[0].Up, known[1].Up}, 0, 0, ErrSchemaMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := testDatabase(t)
+			for _, script := range tt.setup {
+				if _, err := d.db.Exec(script); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			migrated, err := d.MigrateUp(ctx)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MigrateUp() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(migrated) != tt.wantMigrated {
+				t.Errorf("MigrateUp() applied %d migrations, want %d", len(migrated), tt.wantMigrated)
+			}
+			if err := d.CheckMigrations(ctx); err != nil {
+				t.Errorf("CheckMigrations() error = %v", err)
+			}
+
+			var selfParents, members int
+			if err := d.db.Get(&selfParents, `SELECT COUNT(*) FROM groups WHERE parent_id = id`); err != nil {
+				t.Fatal(err)
+			}
+			if err := d.db.Get(&members, `SELECT COUNT(*) FROM group_members WHERE deleted_at IS NULL`); err != nil {
+				t.Fatal(err)
+			}
+			if selfParents != 0 || members != tt.wantMembers {
+				t.Errorf("after MigrateUp() %d groups are their own parents and %d memberships are active, want 0 and %d",
+					selfParents, members, tt.wantMembers)
+			}
+
+			reverted, err := d.MigrateDown(ctx, len(known))
+			if err != nil || len(reverted) != len(known) {
+				t.Errorf("MigrateDown() reverted %d migrations, error = %v", len(reverted), err)
+			}
+		})
+	}
+}
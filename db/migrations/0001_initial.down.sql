@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS user_sessions;
+DROP TABLE IF EXISTS group_permissions;
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
+DROP TABLE IF EXISTS platforms;
+DROP TABLE IF EXISTS users;
+
+DROP TYPE IF EXISTS permission_domain;
+DROP TYPE IF EXISTS platform_type;
@@ -0,0 +1,74 @@
+CREATE TYPE platform_type AS ENUM ('steam', 'eos', 'winstore', 'xbox', 'ps', 'web');
+CREATE TYPE permission_domain AS ENUM ('own', 'party', 'guild', 'global');
+
+CREATE TABLE users
+(
+	id         SERIAL PRIMARY KEY,
+	username   VARCHAR(50)  NOT NULL UNIQUE,
+	password   VARCHAR(255) NOT NULL,
+	email      VARCHAR(100) NOT NULL UNIQUE,
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE platforms
+(
+	id               SERIAL PRIMARY KEY,
+	user_id          INTEGER       NOT NULL REFERENCES users (id),
+	platform_name    platform_type NOT NULL,
+	platform_user_id VARCHAR(100)  NOT NULL,
+	created_at       TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	updated_at       TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	deleted_at       TIMESTAMP WITH TIME ZONE,
+	UNIQUE (user_id, platform_name)
+);
+
+CREATE TABLE groups
+(
+	id          SERIAL PRIMARY KEY,
+	parent_id   INTEGER REFERENCES groups (id),
+	name        VARCHAR(100) NOT NULL,
+	description VARCHAR(255),
+	is_special  BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	updated_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	deleted_at  TIMESTAMP WITH TIME ZONE,
+	UNIQUE (name)
+);
+
+CREATE TABLE group_members
+(
+	id         SERIAL PRIMARY KEY,
+	group_id   INTEGER NOT NULL REFERENCES groups (id),
+	user_id    INTEGER NOT NULL REFERENCES users (id),
+	created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE group_permissions
+(
+	id         SERIAL PRIMARY KEY,
+	group_id   INTEGER           NOT NULL REFERENCES groups (id),
+	permission VARCHAR(100)      NOT NULL,
+	read       BOOLEAN           NOT NULL DEFAULT FALSE,
+	write      BOOLEAN           NOT NULL DEFAULT FALSE,
+	delete     BOOLEAN           NOT NULL DEFAULT FALSE,
+	domain     permission_domain NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE   DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP WITH TIME ZONE   DEFAULT CURRENT_TIMESTAMP,
+	deleted_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE user_sessions
+(
+	id            SERIAL PRIMARY KEY,
+	user_id       INTEGER       NOT NULL REFERENCES users (id),
+	token         VARCHAR(255)  NOT NULL,
+	platform_name platform_type NOT NULL,
+	created_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	updated_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	deleted_at    TIMESTAMP WITH TIME ZONE,
+	UNIQUE (user_id, token)
+);
@@ -0,0 +1,11 @@
+-- Guests have neither password nor email, so they can't be kept
+DELETE FROM user_sessions WHERE user_id IN (SELECT id FROM users WHERE is_guest);
+DELETE FROM group_members WHERE user_id IN (SELECT id FROM users WHERE is_guest);
+DELETE FROM platforms WHERE user_id IN (SELECT id FROM users WHERE is_guest);
+DELETE FROM users WHERE is_guest;
+
+ALTER TABLE users
+	DROP COLUMN guest_secret,
+	DROP COLUMN is_guest,
+	ALTER COLUMN email SET NOT NULL,
+	ALTER COLUMN password SET NOT NULL;
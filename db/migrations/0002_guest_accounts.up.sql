@@ -0,0 +1,6 @@
+ALTER TABLE users
+	ALTER COLUMN password DROP NOT NULL,
+	ALTER COLUMN email DROP NOT NULL,
+	ADD COLUMN is_guest     BOOLEAN     NOT NULL DEFAULT FALSE,
+	ADD COLUMN guest_secret VARCHAR(64) UNIQUE,
+	ADD CHECK (is_guest OR guest_secret IS NULL);
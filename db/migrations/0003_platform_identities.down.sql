@@ -0,0 +1 @@
+DROP INDEX IF EXISTS platforms_identity_key;
@@ -0,0 +1,2 @@
+-- Platform identity can be linked to a single account at a time
+CREATE UNIQUE INDEX platforms_identity_key ON platforms (platform_name, platform_user_id) WHERE deleted_at IS NULL;
@@ -0,0 +1,11 @@
+CREATE TABLE audit_log
+(
+	id             SERIAL PRIMARY KEY,
+	actor_id       INTEGER,
+	action         VARCHAR(64) NOT NULL,
+	target_user_id INTEGER,
+	details        JSONB       NOT NULL DEFAULT '{}',
+	created_at     TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX audit_log_target_user_idx ON audit_log (target_user_id);
@@ -0,0 +1,12 @@
+CREATE TABLE user_profiles
+(
+	user_id      INTEGER PRIMARY KEY REFERENCES users (id),
+	display_name VARCHAR(64)  NOT NULL DEFAULT '',
+	avatar_url   VARCHAR(512) NOT NULL DEFAULT '',
+	locale       VARCHAR(35)  NOT NULL DEFAULT '',
+	timezone     VARCHAR(64)  NOT NULL DEFAULT '',
+	country      VARCHAR(2)   NOT NULL DEFAULT '',
+	attributes   JSONB        NOT NULL DEFAULT '{}',
+	created_at   TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	updated_at   TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,13 @@
+-- Every rename is recorded. Old username can't be taken by anyone else until held_until
+CREATE TABLE username_history
+(
+	id           SERIAL PRIMARY KEY,
+	user_id      INTEGER     NOT NULL REFERENCES users (id),
+	old_username VARCHAR(50) NOT NULL,
+	new_username VARCHAR(50) NOT NULL,
+	held_until   TIMESTAMP WITH TIME ZONE NOT NULL,
+	created_at   TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX username_history_user_idx ON username_history (user_id, created_at);
+CREATE INDEX username_history_old_username_idx ON username_history (LOWER(old_username), held_until);
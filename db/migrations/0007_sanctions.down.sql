@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS sanction_notes;
+DROP TABLE IF EXISTS sanctions;
+DROP TYPE IF EXISTS sanction_type;
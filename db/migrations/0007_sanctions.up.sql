@@ -0,0 +1,36 @@
+CREATE TYPE sanction_type AS ENUM ('ban', 'suspension', 'mute');
+
+-- Scope is a platform for bans and suspensions (empty for the whole account) and a feature for mutes.
+-- ended_at is set when the sanction is revoked or its expiry has been processed
+CREATE TABLE sanctions
+(
+	id            SERIAL PRIMARY KEY,
+	user_id       INTEGER       NOT NULL REFERENCES users (id),
+	type          sanction_type NOT NULL,
+	scope         VARCHAR(64)   NOT NULL DEFAULT '',
+	reason        TEXT          NOT NULL,
+	issued_by     INTEGER,
+	expires_at    TIMESTAMP WITH TIME ZONE,
+	ended_at      TIMESTAMP WITH TIME ZONE,
+	revoked_at    TIMESTAMP WITH TIME ZONE,
+	revoked_by    INTEGER,
+	revoke_reason TEXT,
+	created_at    TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	CHECK (type <> 'ban' OR expires_at IS NULL),
+	CHECK (type <> 'suspension' OR expires_at IS NOT NULL),
+	CHECK (type <> 'mute' OR scope <> '')
+);
+
+CREATE INDEX sanctions_user_idx ON sanctions (user_id, created_at);
+CREATE INDEX sanctions_open_idx ON sanctions (expires_at) WHERE ended_at IS NULL;
+
+CREATE TABLE sanction_notes
+(
+	id          SERIAL PRIMARY KEY,
+	sanction_id INTEGER NOT NULL REFERENCES sanctions (id),
+	author_id   INTEGER,
+	note        TEXT    NOT NULL,
+	created_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX sanction_notes_sanction_idx ON sanction_notes (sanction_id);
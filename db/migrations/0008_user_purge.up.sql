@@ -0,0 +1,2 @@
+-- Set when personal data of a deleted user was anonymized
+ALTER TABLE users ADD COLUMN purged_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS erasure_acks;
+DROP TABLE IF EXISTS erasure_requests;
+DROP TYPE IF EXISTS erasure_status;
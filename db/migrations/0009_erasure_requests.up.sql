@@ -0,0 +1,25 @@
+CREATE TYPE erasure_status AS ENUM ('pending', 'completed');
+
+-- user_id has no foreign key: requests are kept as evidence after the user is purged
+CREATE TABLE erasure_requests
+(
+	id           SERIAL PRIMARY KEY,
+	user_id      INTEGER        NOT NULL,
+	status       erasure_status NOT NULL DEFAULT 'pending',
+	reason       TEXT           NOT NULL DEFAULT '',
+	requested_by INTEGER,
+	created_at   TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX erasure_requests_pending_key ON erasure_requests (user_id) WHERE status = 'pending';
+
+-- One row per service registered at the time of the request
+CREATE TABLE erasure_acks
+(
+	id              SERIAL PRIMARY KEY,
+	request_id      INTEGER     NOT NULL REFERENCES erasure_requests (id),
+	service         VARCHAR(64) NOT NULL,
+	acknowledged_at TIMESTAMP WITH TIME ZONE,
+	UNIQUE (request_id, service)
+);
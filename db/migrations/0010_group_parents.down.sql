@@ -0,0 +1 @@
+-- Groups that were their own parents can't be told apart from root groups, so nothing is restored
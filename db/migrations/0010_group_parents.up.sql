@@ -0,0 +1,3 @@
+-- Groups used to be seeded as their own parents. Permissions are now resolved through the parent chain,
+-- where that is a cycle
+UPDATE groups SET parent_id = NULL WHERE parent_id = id;
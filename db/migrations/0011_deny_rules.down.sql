@@ -0,0 +1,7 @@
+-- Rules that only deny would grant nothing without the deny columns
+DELETE FROM group_permissions WHERE NOT (read OR write OR delete);
+
+ALTER TABLE group_permissions
+	DROP COLUMN deny_delete,
+	DROP COLUMN deny_write,
+	DROP COLUMN deny_read;
@@ -0,0 +1,4 @@
+ALTER TABLE group_permissions
+	ADD COLUMN deny_read   BOOLEAN NOT NULL DEFAULT FALSE,
+	ADD COLUMN deny_write  BOOLEAN NOT NULL DEFAULT FALSE,
+	ADD COLUMN deny_delete BOOLEAN NOT NULL DEFAULT FALSE;
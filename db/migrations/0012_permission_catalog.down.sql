@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS permission_catalog;
+DROP INDEX IF EXISTS group_permissions_active_key;
@@ -0,0 +1,27 @@
+-- Earlier rows of the same permission were replaced by inserting another one
+UPDATE group_permissions
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE deleted_at IS NULL
+  AND EXISTS (SELECT 1
+              FROM group_permissions newer
+              WHERE newer.group_id = group_permissions.group_id
+                AND newer.permission = group_permissions.permission
+                AND newer.domain = group_permissions.domain
+                AND newer.deleted_at IS NULL
+                AND newer.id > group_permissions.id);
+
+CREATE UNIQUE INDEX group_permissions_active_key ON group_permissions (group_id, permission, domain) WHERE deleted_at IS NULL;
+
+-- Permissions registered by services. A name is owned by one service in each domain
+CREATE TABLE permission_catalog
+(
+	id          SERIAL PRIMARY KEY,
+	service     VARCHAR(64)       NOT NULL,
+	name        VARCHAR(100)      NOT NULL,
+	domain      permission_domain NOT NULL,
+	description VARCHAR(255)      NOT NULL DEFAULT '',
+	version     INTEGER           NOT NULL DEFAULT 1,
+	created_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	updated_at  TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (name, domain)
+);
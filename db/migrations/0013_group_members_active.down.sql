@@ -0,0 +1 @@
+DROP INDEX IF EXISTS group_members_active_key;
@@ -0,0 +1,12 @@
+-- A user could be added to the same group more than once
+UPDATE group_members
+SET deleted_at = CURRENT_TIMESTAMP
+WHERE deleted_at IS NULL
+  AND EXISTS (SELECT 1
+              FROM group_members older
+              WHERE older.group_id = group_members.group_id
+                AND older.user_id = group_members.user_id
+                AND older.deleted_at IS NULL
+                AND older.id < group_members.id);
+
+CREATE UNIQUE INDEX group_members_active_key ON group_members (group_id, user_id) WHERE deleted_at IS NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS group_members_expires_at_idx;
+ALTER TABLE group_members DROP COLUMN IF EXISTS expires_at;
@@ -0,0 +1,4 @@
+-- NULL for permanent membership
+ALTER TABLE group_members ADD COLUMN expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX group_members_expires_at_idx ON group_members (expires_at) WHERE deleted_at IS NULL AND expires_at IS NOT NULL;
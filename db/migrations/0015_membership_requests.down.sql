@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS membership_requests;
+DROP TYPE IF EXISTS approval_status;
+DROP TYPE IF EXISTS membership_change;
@@ -0,0 +1,20 @@
+CREATE TYPE membership_change AS ENUM ('add', 'remove');
+CREATE TYPE approval_status AS ENUM ('pending', 'approved', 'rejected');
+
+-- Membership changes of special groups wait here until another admin approves them. Like other actor columns,
+-- requested_by and decided_by have no foreign key, so admins can be purged
+CREATE TABLE membership_requests
+(
+	id           SERIAL PRIMARY KEY,
+	group_id     INTEGER           NOT NULL REFERENCES groups (id),
+	user_id      INTEGER           NOT NULL REFERENCES users (id),
+	change       membership_change NOT NULL,
+	expires_at   TIMESTAMP WITH TIME ZONE, -- Expiry of the membership to add
+	status       approval_status   NOT NULL DEFAULT 'pending',
+	requested_by INTEGER           NOT NULL,
+	decided_by   INTEGER,
+	created_at   TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+	decided_at   TIMESTAMP WITH TIME ZONE
+);
+
+CREATE UNIQUE INDEX membership_requests_pending_key ON membership_requests (group_id, user_id) WHERE status = 'pending';
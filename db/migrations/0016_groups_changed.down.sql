@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS group_permissions_changed ON group_permissions;
+DROP TRIGGER IF EXISTS groups_changed ON groups;
+DROP FUNCTION IF EXISTS notify_groups_changed();
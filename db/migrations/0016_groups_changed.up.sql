@@ -0,0 +1,20 @@
+-- Services listening on groups_changed reload groups after every committed change of groups or their
+-- permissions made directly in the database. The payload names the application that made the change, so
+-- services can skip their own
+CREATE FUNCTION notify_groups_changed() RETURNS trigger AS $$
+BEGIN
+	PERFORM pg_notify('groups_changed', json_build_object(
+		'table', TG_TABLE_NAME,
+		'application', current_setting('application_name')
+	)::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER groups_changed
+	AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON groups
+	FOR EACH STATEMENT EXECUTE FUNCTION notify_groups_changed();
+
+CREATE TRIGGER group_permissions_changed
+	AFTER INSERT OR UPDATE OR DELETE OR TRUNCATE ON group_permissions
+	FOR EACH STATEMENT EXECUTE FUNCTION notify_groups_changed();
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/lib/pq"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// ApplicationName identifies connections of the service, including its CLI, to Postgres
+const ApplicationName = "ogbuser"
+
+// GroupsChangedChannel is notified by database triggers after groups or their permissions change
+const GroupsChangedChannel = "groups_changed"
+
+// PermissionsInvalidatedChannel carries permission invalidations between replicas of the service
+const PermissionsInvalidatedChannel = "permissions_invalidated"
+
+// MaxNotifyPayload is the longest payload Postgres accepts in a notification
+const MaxNotifyPayload = 7999
+
+// listenerPingInterval is how often an idle listener checks its connection
+const listenerPingInterval = 90 * time.Second
+
+// ListenGroupChanges calls onChange after every committed change of groups or their permissions made
+// outside of the service, e.g. directly in the database. Changes committed by the service on any replica
+// are skipped, since they reach replicas as permission invalidations. onChange is also called after the
+// listener reconnects, because notifications sent while it was disconnected are lost. Blocks until ctx is done
+func (d *Database) ListenGroupChanges(ctx context.Context, onChange func()) error {
+	log.Traceln("Database::ListenGroupChanges")
+	return d.listen(ctx, GroupsChangedChannel, func(payload string) {
+		if changedExternally(payload) {
+			onChange()
+		}
+	}, onChange)
+}
+
+// changedExternally tells whether a groups_changed notification was sent for a change made by another
+// application than the service. Unreadable payloads are treated as external
+func changedExternally(payload string) bool {
+	var notification struct {
+		Application string `json:"application"`
+	}
+	if err := json.Unmarshal([]byte(payload), &notification); err != nil {
+		return true
+	}
+	return notification.Application != ApplicationName
+}
+
+// ListenPermissionInvalidations calls onInvalidation with the payload of every invalidation published by
+// NotifyPermissionsInvalidated, including the listener's own. onReconnect is called after the listener
+// reconnects, because notifications sent while it was disconnected are lost. Blocks until ctx is done
+func (d *Database) ListenPermissionInvalidations(ctx context.Context, onInvalidation func(payload string), onReconnect func()) error {
+	log.Traceln("Database::ListenPermissionInvalidations")
+	return d.listen(ctx, PermissionsInvalidatedChannel, onInvalidation, onReconnect)
+}
+
+// NotifyPermissionsInvalidated sends payload to every listener of PermissionsInvalidatedChannel
+func (d *Database) NotifyPermissionsInvalidated(ctx context.Context, payload string) error {
+	log.Traceln("Database::NotifyPermissionsInvalidated")
+	if d.db == nil {
+		return fmt.Errorf("db is nil")
+	}
+	if len(payload) > MaxNotifyPayload {
+		return fmt.Errorf("payload of %d bytes exceeds %d", len(payload), MaxNotifyPayload)
+	}
+
+	_, err := d.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, PermissionsInvalidatedChannel, payload)
+	return err
+}
+
+func (d *Database) listen(ctx context.Context, channel string, onNotify func(payload string), onReconnect func()) error {
+	if d.db == nil {
+		return fmt.Errorf("db is nil")
+	}
+
+	listener := pq.NewListener(d.buildConnString(), time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Warnf("Listener of %s: %v", channel, err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(channel); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-listener.Notify:
+			// nil is sent after the connection was re-established
+			if notification == nil {
+				onReconnect()
+				continue
+			}
+			log.Debugf("Received %s notification: %s", channel, notification.Extra)
+			onNotify(notification.Extra)
+		case <-time.After(listenerPingInterval):
+			go func() {
+				if err := listener.Ping(); err != nil {
+					log.Warnf("Listener of %s ping failed: %v", channel, err)
+				}
+			}()
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package db
+
+import "testing"
+
+func TestChangedExternally(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    bool
+	}{
+		{"Service", `{"table": "groups", "application": "ogbuser"}`, false},
+		{"Other application", `{"table": "group_permissions", "application": "psql"}`, true},
+		{"No application", `{"table": "groups", "application": ""}`, true},
+		{"Unreadable", `groups`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := changedExternally(tt.payload); got != tt.want {
+				t.Errorf("changedExternally(%q) = %v, want %v", tt.payload, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// platformSetup creates player 1 with a password and a Steam identity, player 2 who logs in only through
+// Steam and player 3 with an EOS identity
+const platformSetup = `
+	INSERT INTO users (username, password, email) VALUES ('player', 'hash', 'player@localhost');
+	INSERT INTO users (username) VALUES ('steam_only');
+	INSERT INTO users (username, password, email) VALUES ('other', 'hash', 'other@localhost');
+	INSERT INTO platforms (user_id, platform_name, platform_user_id) VALUES
+		(1, 'steam', 'steam_1'), (2, 'steam', 'steam_2'), (3, 'eos', 'eos_3');`
+
+func TestDatabase_LinkPlatform(t *testing.T) {
+	tests := []struct {
+		name           string
+		userId         int32
+		platform       string
+		platformUserId string
+		wantErr        error
+	}{
+		{"Links identity", 1, "eos", "eos_1", nil},
+		{"Identity of another account", 1, "eos", "eos_3", ErrPlatformLinked},
+		{"Platform already linked", 1, "steam", "steam_other", ErrPlatformExists},
+		{"Same identity again", 1, "steam", "steam_1", ErrPlatformExists},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := migratedTestDatabase(t)
+			if _, err := d.db.Exec(platformSetup); err != nil {
+				t.Fatal(err)
+			}
+
+			linked, err := d.LinkPlatform(context.Background(), tt.userId, tt.platform, tt.platformUserId)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LinkPlatform() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if linked.UserId != tt.userId || linked.PlatformUserId != tt.platformUserId {
+				t.Errorf("LinkPlatform() = %+v, want %s of user %d", linked, tt.platformUserId, tt.userId)
+			}
+		})
+	}
+}
+
+func TestDatabase_UnlinkPlatform(t *testing.T) {
+	tests := []struct {
+		name     string
+		userId   int32
+		platform string
+		wantErr  error
+	}{
+		{"Password remains", 1, "steam", nil},
+		{"Last login method", 2, "steam", ErrLastLoginMethod},
+		{"Platform not linked", 1, "eos", ErrPlatformMissing},
+		{"Unknown user", 4, "steam", ErrUserNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := migratedTestDatabase(t)
+			if _, err := d.db.Exec(platformSetup); err != nil {
+				t.Fatal(err)
+			}
+
+			err := d.UnlinkPlatform(context.Background(), tt.userId, tt.platform)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UnlinkPlatform() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var linked int
+			query := `SELECT COUNT(*) FROM platforms WHERE user_id = $1 AND platform_name = $2 AND deleted_at IS NULL`
+			if err := d.db.Get(&linked, query, tt.userId, tt.platform); err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr == nil && linked != 0 {
+				t.Errorf("platform is still linked after UnlinkPlatform()")
+			}
+			if errors.Is(tt.wantErr, ErrLastLoginMethod) && linked != 1 {
+				t.Errorf("refused UnlinkPlatform() removed the platform")
+			}
+		})
+	}
+}
+
+func TestDatabase_LinkPlatform_RevivesUnlinked(t *testing.T) {
+	d := migratedTestDatabase(t)
+	ctx := context.Background()
+	if _, err := d.db.Exec(platformSetup); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.UnlinkPlatform(ctx, 1, "steam"); err != nil {
+		t.Fatalf("UnlinkPlatform() error = %v", err)
+	}
+	linked, err := d.LinkPlatform(ctx, 1, "steam", "steam_new")
+	if err != nil {
+		t.Fatalf("LinkPlatform() error = %v", err)
+	}
+	if linked.Id != 1 || linked.PlatformUserId != "steam_new" || linked.DeletedAt != nil {
+		t.Errorf("LinkPlatform() = %+v, want revived row 1 with steam_new", linked)
+	}
+}
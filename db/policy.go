@@ -0,0 +1,205 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
+)
+
+// PolicyPlanner computes changes needed to reach the declared state from groups currently in the database
+type PolicyPlanner func(current []schema.GroupSchema) ([]schema.PolicyChangeSchema, error)
+
+// LoadGroupsSnapshot returns active groups together with their own permissions, all read from the same
+// database snapshot
+func (d *Database) LoadGroupsSnapshot(ctx context.Context) ([]schema.GroupSchema, error) {
+	log.Traceln("Database::LoadGroupsSnapshot")
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	return loadGroupsSnapshot(ctx, tx)
+}
+
+// ApplyPolicy plans and applies changes in one transaction. Group table is locked before the state is read,
+// so the plan can't be invalidated by concurrent administration. Returns applied changes and ids of users
+// that were members of deleted groups
+func (d *Database) ApplyPolicy(ctx context.Context, actorId int32, planner PolicyPlanner) ([]schema.PolicyChangeSchema, []int32, error) {
+	log.Traceln("Database::ApplyPolicy")
+	if d.db == nil {
+		return nil, nil, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE groups IN EXCLUSIVE MODE`); err != nil {
+		return nil, nil, err
+	}
+
+	current, err := loadGroupsSnapshot(ctx, tx)
+	if err != nil {
+		return nil, nil, err
+	}
+	changes, err := planner(current)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(changes) == 0 {
+		return nil, nil, nil
+	}
+
+	ids := make(map[string]int32, len(current))
+	for _, group := range current {
+		ids[group.Name] = group.Id
+	}
+	resolve := func(name string) (int32, error) {
+		if name == "" {
+			return 0, nil
+		}
+		id, ok := ids[name]
+		if !ok {
+			return 0, fmt.Errorf("group %q: %w", name, ErrGroupNotFound)
+		}
+		return id, nil
+	}
+
+	// Groups are created first without parents, so that parents can be declared in any order
+	for _, change := range changes {
+		if change.Kind != schema.PolicyCreateGroup {
+			continue
+		}
+		var id int32
+		query := `INSERT INTO groups (name, description, is_special) VALUES ($1, $2, $3) RETURNING id`
+		err := tx.GetContext(ctx, &id, query, change.GroupName, change.Group.Description, change.Group.IsSpecial)
+		if err != nil {
+			if isUniqueViolation(err, "groups_name_key") {
+				return nil, nil, ErrGroupNameTaken
+			}
+			return nil, nil, err
+		}
+		ids[change.GroupName] = id
+	}
+
+	for _, change := range changes {
+		if change.Kind != schema.PolicyCreateGroup && change.Kind != schema.PolicyUpdateGroup {
+			continue
+		}
+		id, err := resolve(change.GroupName)
+		if err != nil {
+			return nil, nil, err
+		}
+		parentId, err := resolve(change.ParentName)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parent %w", err)
+		}
+		if err := checkParentCycle(ctx, tx, id, parentId); err != nil {
+			return nil, nil, err
+		}
+		query := `
+			UPDATE groups
+			SET description = $2, is_special = $3, parent_id = NULLIF($4, 0), updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1`
+		if _, err := tx.ExecContext(ctx, query, id, change.Group.Description, change.Group.IsSpecial, parentId); err != nil {
+			return nil, nil, err
+		}
+
+		action := AuditActionGroupUpdated
+		if change.Kind == schema.PolicyCreateGroup {
+			action = AuditActionGroupCreated
+		}
+		details := map[string]any{"group_id": id, "name": change.GroupName, "parent_id": parentId, "is_special": change.Group.IsSpecial}
+		if err := insertAuditLog(ctx, tx, actorId, action, 0, details); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, change := range changes {
+		if change.Kind != schema.PolicySetPermission && change.Kind != schema.PolicyRemovePermission {
+			continue
+		}
+		id, err := resolve(change.GroupName)
+		if err != nil {
+			return nil, nil, err
+		}
+		if change.Kind == schema.PolicyRemovePermission {
+			err = removeGroupPermission(ctx, tx, actorId, id, change.Permission.Permission, change.Permission.Domain)
+		} else {
+			permission := change.Permission
+			permission.GroupId = id
+			err = setGroupPermission(ctx, tx, actorId, &permission)
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("group %q permission %s/%s: %w", change.GroupName, change.Permission.Domain, change.Permission.Permission, err)
+		}
+	}
+
+	// Deletions are expected in children-first order
+	var members []int32
+	for _, change := range changes {
+		if change.Kind != schema.PolicyDeleteGroup {
+			continue
+		}
+		id, err := resolve(change.GroupName)
+		if err != nil {
+			return nil, nil, err
+		}
+		deleted, err := deleteGroup(ctx, tx, actorId, id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("group %q: %w", change.GroupName, err)
+		}
+		members = append(members, deleted...)
+	}
+
+	details := map[string]any{"changes": len(changes)}
+	if err := insertAuditLog(ctx, tx, actorId, AuditActionPolicyApplied, 0, details); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	return changes, members, nil
+}
+
+func loadGroupsSnapshot(ctx context.Context, q sqlx.QueryerContext) ([]schema.GroupSchema, error) {
+	var groups []schema.GroupSchema
+	query := `SELECT ` + groupColumns + ` FROM groups WHERE deleted_at IS NULL ORDER BY id`
+	if err := sqlx.SelectContext(ctx, q, &groups, query); err != nil {
+		return nil, err
+	}
+
+	var permissions []schema.GroupPermissionSchema
+	query = `
+		SELECT id, group_id, permission, read, write, delete, deny_read, deny_write, deny_delete, domain,
+		       created_at, updated_at, deleted_at
+		FROM group_permissions
+		WHERE deleted_at IS NULL
+		ORDER BY id`
+	if err := sqlx.SelectContext(ctx, q, &permissions, query); err != nil {
+		return nil, err
+	}
+
+	index := make(map[int32]int, len(groups))
+	for i, group := range groups {
+		index[group.Id] = i
+	}
+	for _, permission := range permissions {
+		if i, ok := index[permission.GroupId]; ok {
+			groups[i].Permissions = append(groups[i].Permissions, permission)
+		}
+	}
+	return groups, nil
+}
@@ -0,0 +1,103 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
+)
+
+// LoadProfile returns profile of the user. Users that never stored a profile get empty values
+func (d *Database) LoadProfile(ctx context.Context, userId int32) (*schema.ProfileSchema, error) {
+	log.Traceln("Database::LoadProfile:", userId)
+	profiles, err := d.LoadProfiles(ctx, []int32{userId})
+	if err != nil {
+		return nil, err
+	}
+	if len(profiles) == 0 {
+		return nil, ErrUserNotFound
+	}
+	return &profiles[0], nil
+}
+
+// LoadProfiles returns profiles of existing users among ids in a single query.
+// Ids of unknown or deleted users are skipped
+func (d *Database) LoadProfiles(ctx context.Context, ids []int32) ([]schema.ProfileSchema, error) {
+	log.Traceln("Database::LoadProfiles:", len(ids))
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	query := `
+		SELECT u.id AS user_id, u.username,
+		       COALESCE(p.display_name, '') AS display_name, COALESCE(p.avatar_url, '') AS avatar_url,
+		       COALESCE(p.locale, '') AS locale, COALESCE(p.timezone, '') AS timezone,
+		       COALESCE(p.country, '') AS country, COALESCE(p.attributes, '{}') AS attributes,
+		       p.updated_at
+		FROM users u
+		LEFT JOIN user_profiles p ON p.user_id = u.id
+		WHERE u.id = ANY($1) AND u.deleted_at IS NULL`
+
+	var profiles []schema.ProfileSchema
+	if err := d.db.SelectContext(ctx, &profiles, query, pq.Array(ids)); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return profiles, nil
+}
+
+// SaveProfile applies non-nil fields of update to the profile of the user, creating it when needed
+func (d *Database) SaveProfile(ctx context.Context, userId int32, update *schema.ProfileUpdateSchema) (*schema.ProfileSchema, error) {
+	log.Traceln("Database::SaveProfile:", userId)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+	if update == nil {
+		return nil, fmt.Errorf("update is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)`
+	if err := tx.GetContext(ctx, &exists, query, userId); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrUserNotFound
+	}
+
+	query = `
+		INSERT INTO user_profiles (user_id, display_name, avatar_url, locale, timezone, country, attributes)
+		VALUES ($1, COALESCE($2, ''), COALESCE($3, ''), COALESCE($4, ''), COALESCE($5, ''), COALESCE($6, ''),
+		        jsonb_strip_nulls(COALESCE($7::JSONB, '{}')))
+		ON CONFLICT (user_id) DO UPDATE
+		SET display_name = COALESCE($2, user_profiles.display_name),
+		    avatar_url   = COALESCE($3, user_profiles.avatar_url),
+		    locale       = COALESCE($4, user_profiles.locale),
+		    timezone     = COALESCE($5, user_profiles.timezone),
+		    country      = COALESCE($6, user_profiles.country),
+		    attributes   = jsonb_strip_nulls(user_profiles.attributes || COALESCE($7::JSONB, '{}')),
+		    updated_at   = CURRENT_TIMESTAMP`
+	_, err = tx.ExecContext(ctx, query, userId, update.DisplayName, update.AvatarUrl, update.Locale,
+		update.Timezone, update.Country, update.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return d.LoadProfile(ctx, userId)
+}
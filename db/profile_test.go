@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/savageking-io/ogbuser/schema"
+	"testing"
+)
+
+func TestDatabase_SaveProfile(t *testing.T) {
+	d := migratedTestDatabase(t)
+	ctx := context.Background()
+	setup := `
+		INSERT INTO users (username, password, email) VALUES ('player', 'hash', 'player@localhost');
+		INSERT INTO users (username, password, email, deleted_at) VALUES ('deleted', 'hash', 'deleted@localhost', CURRENT_TIMESTAMP);`
+	if _, err := d.db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+	value := func(s string) *string { return &s }
+
+	empty, err := d.LoadProfile(ctx, 1)
+	if err != nil {
+		t.Fatalf("LoadProfile() error = %v", err)
+	}
+	if empty.Username != "player" || empty.DisplayName != "" || empty.Attributes.String() != "{}" || empty.UpdatedAt != nil {
+		t.Errorf("LoadProfile() of a user without profile = %+v, want empty values", empty)
+	}
+
+	update := &schema.ProfileUpdateSchema{DisplayName: value("Player"), Country: value("DE"), Attributes: value(`{"level": 3, "title": "rookie"}`)}
+	if _, err := d.SaveProfile(ctx, 1, update); err != nil {
+		t.Fatalf("SaveProfile() error = %v", err)
+	}
+
+	// Nil fields are kept, attributes are merged and null values remove keys
+	update = &schema.ProfileUpdateSchema{Locale: value("en-US"), Attributes: value(`{"level": 4, "title": null}`)}
+	saved, err := d.SaveProfile(ctx, 1, update)
+	if err != nil {
+		t.Fatalf("SaveProfile() error = %v", err)
+	}
+	if saved.DisplayName != "Player" || saved.Country != "DE" || saved.Locale != "en-US" || saved.UpdatedAt == nil {
+		t.Errorf("SaveProfile() = %+v, want display name, country and locale set", saved)
+	}
+	var attributes map[string]int
+	if err := saved.Attributes.Unmarshal(&attributes); err != nil {
+		t.Fatalf("attributes %s: %v", saved.Attributes, err)
+	}
+	if len(attributes) != 1 || attributes["level"] != 4 {
+		t.Errorf("SaveProfile() attributes = %s, want {\"level\": 4}", saved.Attributes)
+	}
+
+	if _, err := d.SaveProfile(ctx, 2, update); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("SaveProfile() of a deleted user error = %v, want %v", err, ErrUserNotFound)
+	}
+	if _, err := d.LoadProfile(ctx, 2); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("LoadProfile() of a deleted user error = %v, want %v", err, ErrUserNotFound)
+	}
+
+	profiles, err := d.LoadProfiles(ctx, []int32{1, 2, 3})
+	if err != nil {
+		t.Fatalf("LoadProfiles() error = %v", err)
+	}
+	if len(profiles) != 1 || profiles[0].UserId != 1 || profiles[0].DisplayName != "Player" {
+		t.Errorf("LoadProfiles() = %+v, want only the profile of user 1", profiles)
+	}
+}
@@ -0,0 +1,264 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	ErrSanctionNotFound = errors.New("sanction not found")
+	ErrSanctionEnded    = errors.New("sanction has already ended")
+)
+
+const sanctionColumns = `id, user_id, type, scope, reason, issued_by, expires_at, ended_at,
+	revoked_at, revoked_by, revoke_reason, created_at`
+
+// IssueSanction stores the sanction and, for bans and suspensions, revokes sessions of the user
+// on the sanctioned platform or on all platforms. Returns stored sanction and number of revoked sessions
+func (d *Database) IssueSanction(ctx context.Context, sanction *schema.SanctionSchema) (*schema.SanctionSchema, int64, error) {
+	log.Traceln("Database::IssueSanction:", sanction.UserId, sanction.Type, sanction.Scope)
+	if d.db == nil {
+		return nil, 0, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)`
+	if err := tx.GetContext(ctx, &exists, query, sanction.UserId); err != nil {
+		return nil, 0, err
+	}
+	if !exists {
+		return nil, 0, ErrUserNotFound
+	}
+
+	result := &schema.SanctionSchema{}
+	query = `
+		INSERT INTO sanctions (user_id, type, scope, reason, issued_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + sanctionColumns
+	err = tx.GetContext(ctx, result, query, sanction.UserId, sanction.Type, sanction.Scope, sanction.Reason,
+		sanction.IssuedBy, sanction.ExpiresAt)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var revoked int64
+	if schema.SanctionBlocksLogin(result.Type) {
+		query = `
+			UPDATE user_sessions SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			WHERE user_id = $1 AND deleted_at IS NULL AND ($2 = '' OR platform_name::TEXT = $2)`
+		if revoked, err = execAffected(ctx, tx, query, result.UserId, result.Scope); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	details := map[string]any{
+		"sanction_id":      result.Id,
+		"type":             result.Type,
+		"scope":            result.Scope,
+		"reason":           result.Reason,
+		"expires_at":       result.ExpiresAt,
+		"revoked_sessions": revoked,
+	}
+	if err := insertAuditLog(ctx, tx, derefInt32(result.IssuedBy), AuditActionSanctionIssued, result.UserId, details); err != nil {
+		return nil, 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, 0, err
+	}
+
+	return result, revoked, nil
+}
+
+// RevokeSanction ends the sanction before it expires. actorId is 0 when revoked from CLI
+func (d *Database) RevokeSanction(ctx context.Context, sanctionId, actorId int32, reason string) (*schema.SanctionSchema, error) {
+	log.Traceln("Database::RevokeSanction:", sanctionId)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result := &schema.SanctionSchema{}
+	query := `
+		UPDATE sanctions
+		SET revoked_at = CURRENT_TIMESTAMP, revoked_by = NULLIF($2, 0), revoke_reason = $3, ended_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND ended_at IS NULL AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+		RETURNING ` + sanctionColumns
+	err = tx.GetContext(ctx, result, query, sanctionId, actorId, reason)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		var exists bool
+		if err := tx.GetContext(ctx, &exists, `SELECT EXISTS (SELECT 1 FROM sanctions WHERE id = $1)`, sanctionId); err != nil {
+			return nil, err
+		}
+		if exists {
+			return nil, ErrSanctionEnded
+		}
+		return nil, ErrSanctionNotFound
+	}
+
+	details := map[string]any{
+		"sanction_id": result.Id,
+		"type":        result.Type,
+		"scope":       result.Scope,
+		"reason":      reason,
+	}
+	if err := insertAuditLog(ctx, tx, actorId, AuditActionSanctionRevoked, result.UserId, details); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// EndExpiredSanctions marks sanctions that passed their expiry as ended and returns them,
+// so each expiry is reported exactly once
+func (d *Database) EndExpiredSanctions(ctx context.Context) ([]schema.SanctionSchema, error) {
+	log.Traceln("Database::EndExpiredSanctions")
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	var result []schema.SanctionSchema
+	query := `
+		UPDATE sanctions SET ended_at = expires_at
+		WHERE ended_at IS NULL AND expires_at <= CURRENT_TIMESTAMP
+		RETURNING ` + sanctionColumns
+	if err := d.db.SelectContext(ctx, &result, query); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// LoadActiveSanctions returns sanctions of the user that are in effect right now
+func (d *Database) LoadActiveSanctions(ctx context.Context, userId int32) ([]schema.SanctionSchema, error) {
+	log.Traceln("Database::LoadActiveSanctions:", userId)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	var result []schema.SanctionSchema
+	query := `
+		SELECT ` + sanctionColumns + `
+		FROM sanctions
+		WHERE user_id = $1 AND ended_at IS NULL AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)`
+	if err := d.db.SelectContext(ctx, &result, query, userId); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// LoadSanctions returns full sanction history of the user with notes, newest first
+func (d *Database) LoadSanctions(ctx context.Context, userId int32) ([]schema.SanctionSchema, error) {
+	log.Traceln("Database::LoadSanctions:", userId)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	var result []schema.SanctionSchema
+	query := `SELECT ` + sanctionColumns + ` FROM sanctions WHERE user_id = $1 ORDER BY created_at DESC, id DESC`
+	if err := d.db.SelectContext(ctx, &result, query, userId); err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return result, nil
+	}
+
+	ids := make([]int32, 0, len(result))
+	index := make(map[int32]int, len(result))
+	for i := range result {
+		ids = append(ids, result[i].Id)
+		index[result[i].Id] = i
+	}
+
+	var notes []schema.SanctionNoteSchema
+	query = `
+		SELECT id, sanction_id, author_id, note, created_at
+		FROM sanction_notes
+		WHERE sanction_id = ANY($1)
+		ORDER BY created_at, id`
+	if err := d.db.SelectContext(ctx, &notes, query, pq.Array(ids)); err != nil {
+		return nil, err
+	}
+	for _, note := range notes {
+		i := index[note.SanctionId]
+		result[i].Notes = append(result[i].Notes, note)
+	}
+
+	return result, nil
+}
+
+// AddSanctionNote attaches a note to the sanction. authorId is 0 when added from CLI
+func (d *Database) AddSanctionNote(ctx context.Context, sanctionId, authorId int32, note string) (*schema.SanctionNoteSchema, error) {
+	log.Traceln("Database::AddSanctionNote:", sanctionId)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	result := &schema.SanctionNoteSchema{}
+	query := `
+		INSERT INTO sanction_notes (sanction_id, author_id, note)
+		SELECT id, NULLIF($2, 0), $3 FROM sanctions WHERE id = $1
+		RETURNING id, sanction_id, author_id, note, created_at`
+	if err := d.db.GetContext(ctx, result, query, sanctionId, authorId, note); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSanctionNotFound
+		}
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// GetRevokedUserSession returns a session that has been revoked. Used to tell a banned user
+// why their token stopped working
+func (d *Database) GetRevokedUserSession(ctx context.Context, token string) (*schema.UserSessionSchema, error) {
+	log.Traceln("Database::GetRevokedUserSession")
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	result := &schema.UserSessionSchema{}
+	query := `
+		SELECT id, user_id, platform_name, created_at, updated_at, deleted_at
+		FROM user_sessions
+		WHERE token = $1 AND deleted_at IS NOT NULL`
+	if err := d.db.GetContext(ctx, result, query, token); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func derefInt32(value *int32) int32 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
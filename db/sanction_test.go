@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"github.com/savageking-io/ogbuser/schema"
+	"slices"
+	"testing"
+)
+
+func TestDatabase_IssueSanction_RevokesScopedSessions(t *testing.T) {
+	tests := []struct {
+		name        string
+		scope       string
+		wantRevoked int64
+		wantActive  []string
+	}{
+		{"Steam", schema.PlatformSteam, 2, []string{"web_token"}},
+		{"Web", schema.PlatformWeb, 1, []string{"steam_token", "steam_other_token"}},
+		{"Whole account", "", 3, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := migratedTestDatabase(t)
+			ctx := context.Background()
+			setup := `
+				INSERT INTO users (username, password, email) VALUES ('player', 'hash', 'player@localhost');
+				INSERT INTO user_sessions (user_id, token, platform_name) VALUES
+					(1, 'steam_token', 'steam'), (1, 'steam_other_token', 'steam'), (1, 'web_token', 'web');`
+			if _, err := d.db.Exec(setup); err != nil {
+				t.Fatal(err)
+			}
+
+			ban := &schema.SanctionSchema{UserId: 1, Type: schema.SanctionBan, Scope: tt.scope, Reason: "cheating"}
+			_, revoked, err := d.IssueSanction(ctx, ban)
+			if err != nil {
+				t.Fatalf("IssueSanction() error = %v", err)
+			}
+			if revoked != tt.wantRevoked {
+				t.Errorf("IssueSanction() revoked %d sessions, want %d", revoked, tt.wantRevoked)
+			}
+
+			var active []string
+			if err := d.db.Select(&active, `SELECT token FROM user_sessions WHERE deleted_at IS NULL ORDER BY id`); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(active, tt.wantActive) {
+				t.Errorf("active sessions %v, want %v", active, tt.wantActive)
+			}
+		})
+	}
+}
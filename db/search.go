@@ -0,0 +1,225 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort field")
+)
+
+const (
+	DefaultSearchLimit = 50
+	MaxSearchLimit     = 200
+)
+
+// searchCursor points right after the last row of a page. It is bound to the sort it was created with
+type searchCursor struct {
+	SortBy     string `json:"s"`
+	Descending bool   `json:"d"`
+	Value      string `json:"v"`
+	Id         int32  `json:"i"`
+}
+
+func (c *searchCursor) encode() string {
+	payload, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func decodeSearchCursor(value string) (*searchCursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	cursor := &searchCursor{}
+	if err := json.Unmarshal(payload, cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// escapeLike escapes LIKE wildcards so user input is matched literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// SearchUsers returns a page of users matching the filter and a cursor of the next page.
+// Cursor is empty on the last page
+func (d *Database) SearchUsers(ctx context.Context, filter *schema.UserSearchSchema) ([]schema.UserSearchResultSchema, string, error) {
+	log.Traceln("Database::SearchUsers")
+	if d.db == nil {
+		return nil, "", fmt.Errorf("db is nil")
+	}
+
+	sortBy := filter.SortBy
+	if sortBy == "" {
+		sortBy = schema.UserSortId
+	}
+	var sortColumn string
+	switch sortBy {
+	case schema.UserSortId:
+		sortColumn = "u.id"
+	case schema.UserSortUsername:
+		sortColumn = "u.username"
+	case schema.UserSortCreatedAt:
+		sortColumn = "u.created_at"
+	default:
+		return nil, "", ErrInvalidSort
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	if limit > MaxSearchLimit {
+		limit = MaxSearchLimit
+	}
+
+	var conditions []string
+	var args []any
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if !filter.IncludeDeleted {
+		conditions = append(conditions, "u.deleted_at IS NULL")
+	}
+	if filter.Username != "" {
+		conditions = append(conditions, "u.username ILIKE '%' || "+arg(escapeLike(filter.Username))+" || '%'")
+	}
+	if filter.Email != "" {
+		conditions = append(conditions, "u.email ILIKE '%' || "+arg(escapeLike(filter.Email))+" || '%'")
+	}
+	if filter.Platform != "" || filter.PlatformUserId != "" {
+		platformConditions := []string{"p.user_id = u.id", "p.deleted_at IS NULL"}
+		if filter.Platform != "" {
+			platformConditions = append(platformConditions, "p.platform_name::TEXT = "+arg(filter.Platform))
+		}
+		if filter.PlatformUserId != "" {
+			platformConditions = append(platformConditions, "p.platform_user_id = "+arg(filter.PlatformUserId))
+		}
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM platforms p WHERE "+strings.Join(platformConditions, " AND ")+")")
+	}
+	if filter.GroupId != 0 {
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM group_members gm WHERE gm.user_id = u.id AND gm.deleted_at IS NULL AND (gm.expires_at IS NULL OR gm.expires_at > CURRENT_TIMESTAMP) AND gm.group_id = "+arg(filter.GroupId)+")")
+	}
+	if filter.CreatedAfter != nil {
+		conditions = append(conditions, "u.created_at >= "+arg(*filter.CreatedAfter))
+	}
+	if filter.CreatedBefore != nil {
+		conditions = append(conditions, "u.created_at < "+arg(*filter.CreatedBefore))
+	}
+
+	direction, comparison := "ASC", ">"
+	if filter.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	if filter.Cursor != "" {
+		cursor, err := decodeSearchCursor(filter.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		if cursor.SortBy != sortBy || cursor.Descending != filter.Descending {
+			return nil, "", fmt.Errorf("%w: cursor was created with another sort", ErrInvalidCursor)
+		}
+		switch sortBy {
+		case schema.UserSortId:
+			conditions = append(conditions, "u.id "+comparison+" "+arg(cursor.Id))
+		case schema.UserSortUsername:
+			conditions = append(conditions, fmt.Sprintf("(u.username, u.id) %s (%s, %s)", comparison, arg(cursor.Value), arg(cursor.Id)))
+		case schema.UserSortCreatedAt:
+			createdAt, err := time.Parse(time.RFC3339Nano, cursor.Value)
+			if err != nil {
+				return nil, "", ErrInvalidCursor
+			}
+			conditions = append(conditions, fmt.Sprintf("(u.created_at, u.id) %s (%s, %s)", comparison, arg(createdAt), arg(cursor.Id)))
+		}
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+	order := "u.id " + direction
+	if sortColumn != "u.id" {
+		order = sortColumn + " " + direction + ", " + order
+	}
+
+	query := `
+		SELECT u.id, u.username, u.email, u.is_guest, u.created_at, u.updated_at, u.deleted_at,
+		       ARRAY(SELECT gm.group_id FROM group_members gm
+		             WHERE gm.user_id = u.id AND gm.deleted_at IS NULL
+               AND (gm.expires_at IS NULL OR gm.expires_at > CURRENT_TIMESTAMP)
+             ORDER BY gm.group_id) AS group_ids
+		FROM users u
+		` + where + `
+		ORDER BY ` + order + `
+		LIMIT ` + arg(limit+1)
+
+	var result []schema.UserSearchResultSchema
+	if err := d.db.SelectContext(ctx, &result, query, args...); err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(result) > limit {
+		result = result[:limit]
+		last := result[len(result)-1]
+		cursor := &searchCursor{SortBy: sortBy, Descending: filter.Descending, Id: last.Id}
+		switch sortBy {
+		case schema.UserSortUsername:
+			cursor.Value = last.Username
+		case schema.UserSortCreatedAt:
+			cursor.Value = last.CreatedAt.Format(time.RFC3339Nano)
+		}
+		nextCursor = cursor.encode()
+	}
+
+	if err := d.attachSearchPlatforms(ctx, result); err != nil {
+		return nil, "", err
+	}
+
+	return result, nextCursor, nil
+}
+
+// attachSearchPlatforms loads platform identities of all found users with a single query
+func (d *Database) attachSearchPlatforms(ctx context.Context, users []schema.UserSearchResultSchema) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	ids := make([]int32, 0, len(users))
+	index := make(map[int32]int, len(users))
+	for i := range users {
+		ids = append(ids, users[i].Id)
+		index[users[i].Id] = i
+	}
+
+	var platforms []schema.PlatformSchema
+	query := `
+		SELECT id, user_id, platform_name, platform_user_id, created_at, updated_at, deleted_at
+		FROM platforms
+		WHERE user_id = ANY($1) AND deleted_at IS NULL
+		ORDER BY id`
+	if err := d.db.SelectContext(ctx, &platforms, query, pq.Array(ids)); err != nil {
+		return err
+	}
+
+	for _, platform := range platforms {
+		i := index[platform.UserId]
+		users[i].Platforms = append(users[i].Platforms, platform)
+	}
+	return nil
+}
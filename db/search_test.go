@@ -0,0 +1,142 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/savageking-io/ogbuser/schema"
+	"slices"
+	"testing"
+)
+
+func TestDatabase_SearchUsers_Pagination(t *testing.T) {
+	d := migratedTestDatabase(t)
+	// Users 3 and 4 were created at the same time, so pages sorted by created_at are ordered by id between them
+	setup := `
+		INSERT INTO users (username, password, email, created_at, deleted_at) VALUES
+			('delta', 'hash', 'delta@localhost', '2025-01-01 02:00:00+00', NULL),
+			('alpha', 'hash', 'alpha@localhost', '2025-01-01 00:00:00+00', NULL),
+			('echo', 'hash', 'echo@localhost', '2025-01-01 01:00:00+00', NULL),
+			('charlie', 'hash', 'charlie@localhost', '2025-01-01 01:00:00+00', NULL),
+			('bravo', 'hash', 'bravo@localhost', '2025-01-01 03:00:00+00', NULL),
+			('foxtrot', 'hash', 'foxtrot@localhost', '2025-01-01 00:30:00+00', CURRENT_TIMESTAMP);`
+	if _, err := d.db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		sortBy     string
+		descending bool
+		want       []int32
+	}{
+		{"Id", schema.UserSortId, false, []int32{1, 2, 3, 4, 5}},
+		{"Id descending", schema.UserSortId, true, []int32{5, 4, 3, 2, 1}},
+		{"Username", schema.UserSortUsername, false, []int32{2, 5, 4, 1, 3}},
+		{"Username descending", schema.UserSortUsername, true, []int32{3, 1, 4, 5, 2}},
+		{"Created at", schema.UserSortCreatedAt, false, []int32{2, 3, 4, 1, 5}},
+		{"Created at descending", schema.UserSortCreatedAt, true, []int32{5, 1, 4, 3, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := &schema.UserSearchSchema{SortBy: tt.sortBy, Descending: tt.descending, Limit: 2}
+			var got []int32
+			for pages := 0; ; pages++ {
+				if pages > len(tt.want) {
+					t.Fatalf("SearchUsers() didn't finish after %d pages", pages)
+				}
+				users, cursor, err := d.SearchUsers(context.Background(), filter)
+				if err != nil {
+					t.Fatalf("SearchUsers() error = %v", err)
+				}
+				for _, u := range users {
+					got = append(got, u.Id)
+				}
+				if cursor == "" {
+					break
+				}
+				filter.Cursor = cursor
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SearchUsers() pages = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDatabase_SearchUsers_Filters(t *testing.T) {
+	d := migratedTestDatabase(t)
+	setup := `
+		INSERT INTO users (username, password, email, deleted_at) VALUES
+			('under_score', 'hash', 'under_score@localhost', NULL),
+			('underXscore', 'hash', 'underxscore@localhost', NULL),
+			('hundred%', 'hash', 'hundred@localhost', NULL),
+			('hundreds', 'hash', 'hundreds@localhost', NULL),
+			('back\slash', 'hash', 'backslash@localhost', NULL),
+			('gone_player', 'hash', 'gone@localhost', CURRENT_TIMESTAMP);`
+	if _, err := d.db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter schema.UserSearchSchema
+		want   []int32
+	}{
+		{"Underscore is literal", schema.UserSearchSchema{Username: "_"}, []int32{1}},
+		{"Percent is literal", schema.UserSearchSchema{Username: "%"}, []int32{3}},
+		{"Backslash is literal", schema.UserSearchSchema{Username: `\`}, []int32{5}},
+		{"Case-insensitive", schema.UserSearchSchema{Username: "UNDERX"}, []int32{2}},
+		{"Email", schema.UserSearchSchema{Email: "hundred"}, []int32{3, 4}},
+		{"Deleted are excluded", schema.UserSearchSchema{Username: "player"}, nil},
+		{"Deleted are included on request", schema.UserSearchSchema{Username: "player", IncludeDeleted: true}, []int32{6}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, _, err := d.SearchUsers(context.Background(), &tt.filter)
+			if err != nil {
+				t.Fatalf("SearchUsers() error = %v", err)
+			}
+			var got []int32
+			for _, u := range users {
+				got = append(got, u.Id)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SearchUsers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDatabase_SearchUsers_InvalidCursor(t *testing.T) {
+	d := migratedTestDatabase(t)
+	ctx := context.Background()
+	setup := `
+		INSERT INTO users (username, password, email) VALUES
+			('alpha', 'hash', 'alpha@localhost'), ('bravo', 'hash', 'bravo@localhost');`
+	if _, err := d.db.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+
+	_, cursor, err := d.SearchUsers(ctx, &schema.UserSearchSchema{SortBy: schema.UserSortUsername, Limit: 1})
+	if err != nil || cursor == "" {
+		t.Fatalf("SearchUsers() cursor = %q, error = %v", cursor, err)
+	}
+
+	tests := []struct {
+		name    string
+		filter  schema.UserSearchSchema
+		wantErr error
+	}{
+		{"Garbage", schema.UserSearchSchema{Cursor: "not a cursor"}, ErrInvalidCursor},
+		{"Another sort field", schema.UserSearchSchema{SortBy: schema.UserSortId, Cursor: cursor}, ErrInvalidCursor},
+		{"Another direction", schema.UserSearchSchema{SortBy: schema.UserSortUsername, Descending: true, Cursor: cursor}, ErrInvalidCursor},
+		{"Unknown sort field", schema.UserSearchSchema{SortBy: "email"}, ErrInvalidSort},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := d.SearchUsers(ctx, &tt.filter); !errors.Is(err, tt.wantErr) {
+				t.Errorf("SearchUsers() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+var (
+	ErrUsernameUnchanged = errors.New("new username is the same as the current one")
+	ErrUsernameHeld      = errors.New("username was recently released and is on hold")
+)
+
+// RenameCooldownError is returned when the user changed username less than cooldown ago
+type RenameCooldownError struct {
+	NextChangeAt time.Time
+}
+
+func (e *RenameCooldownError) Error() string {
+	return fmt.Sprintf("username can be changed again at %s", e.NextChangeAt.UTC().Format(time.RFC3339))
+}
+
+// RenameUser changes username of the user and records the change in username_history.
+// Old username is held for hold duration, so only its previous owner can take it back.
+// Usernames are compared case-insensitively
+func (d *Database) RenameUser(ctx context.Context, userId int32, username string, cooldown, hold time.Duration) (*schema.UsernameHistorySchema, error) {
+	log.Traceln("Database::RenameUser:", userId, username)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	tx, err := d.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var current string
+	query := `SELECT username FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+	if err := tx.GetContext(ctx, &current, query, userId); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	if current == username {
+		return nil, ErrUsernameUnchanged
+	}
+
+	var lastChange *time.Time
+	query = `SELECT MAX(created_at) FROM username_history WHERE user_id = $1`
+	if err := tx.GetContext(ctx, &lastChange, query, userId); err != nil {
+		return nil, err
+	}
+	if lastChange != nil && cooldown > 0 {
+		if next := lastChange.Add(cooldown); time.Now().Before(next) {
+			return nil, &RenameCooldownError{NextChangeAt: next}
+		}
+	}
+
+	if err := checkUsernameAvailable(ctx, tx, userId, username); err != nil {
+		return nil, err
+	}
+
+	query = `UPDATE users SET username = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, query, userId, username); err != nil {
+		if isUniqueViolation(err, "users_username_key") {
+			return nil, ErrUsernameTaken
+		}
+		return nil, err
+	}
+
+	result := &schema.UsernameHistorySchema{}
+	query = `
+		INSERT INTO username_history (user_id, old_username, new_username, held_until)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, user_id, old_username, new_username, held_until, created_at`
+	if err := tx.GetContext(ctx, result, query, userId, current, username, time.Now().Add(hold)); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// LoadUsernameHistory returns renames of the user, newest first
+func (d *Database) LoadUsernameHistory(ctx context.Context, userId int32) ([]schema.UsernameHistorySchema, error) {
+	log.Traceln("Database::LoadUsernameHistory:", userId)
+	if d.db == nil {
+		return nil, fmt.Errorf("db is nil")
+	}
+
+	var result []schema.UsernameHistorySchema
+	query := `
+		SELECT id, user_id, old_username, new_username, held_until, created_at
+		FROM username_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC`
+	if err := d.db.SelectContext(ctx, &result, query, userId); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// checkUsernameAvailable returns ErrUsernameTaken if another user has the username in any case
+// and ErrUsernameHeld if another user released it less than hold period ago
+func checkUsernameAvailable(ctx context.Context, tx *sqlx.Tx, userId int32, username string) error {
+	var taken bool
+	query := `SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(username) = LOWER($2) AND id <> $1)`
+	if err := tx.GetContext(ctx, &taken, query, userId, username); err != nil {
+		return err
+	}
+	if taken {
+		return ErrUsernameTaken
+	}
+
+	var held bool
+	query = `
+		SELECT EXISTS (
+			SELECT 1 FROM username_history
+			WHERE LOWER(old_username) = LOWER($2) AND held_until > CURRENT_TIMESTAMP AND user_id <> $1
+		)`
+	if err := tx.GetContext(ctx, &held, query, userId, username); err != nil {
+		return err
+	}
+	if held {
+		return ErrUsernameHeld
+	}
+
+	return nil
+}
@@ -9,13 +9,14 @@ require (
 	github.com/savageking-io/ogbcommon v0.2.0
 	github.com/savageking-io/ogbrest/proto v0.4.0
 	github.com/savageking-io/ogbrest/restlib v0.4.0
-	github.com/savageking-io/ogbuser/proto v0.4.0
+	github.com/savageking-io/ogbuser/proto v0.5.0
 	github.com/segmentio/kafka-go v0.4.49
 	github.com/sirupsen/logrus v1.9.3
 	github.com/urfave/cli v1.22.17
 	golang.org/x/crypto v0.42.0
 	google.golang.org/grpc v1.75.1
 	google.golang.org/protobuf v1.36.9
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -27,5 +28,4 @@ require (
 	golang.org/x/sys v0.36.0 // indirect
 	golang.org/x/text v0.29.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250908214217-97024824d090 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
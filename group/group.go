@@ -2,19 +2,36 @@ package group
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/savageking-io/ogbuser/db"
 	"github.com/savageking-io/ogbuser/perm"
 	"github.com/savageking-io/ogbuser/schema"
 	log "github.com/sirupsen/logrus"
+	"sync"
+	"sync/atomic"
 )
 
+var ErrGroupCycle = errors.New("group parent chain has a cycle")
+
+// groupLoader loads a group by id. Implemented by db.Database
+type groupLoader func(ctx context.Context, id int32) (*schema.GroupSchema, error)
+
+// permissionLoader loads permissions defined by the group itself. Implemented by db.Database
+type permissionLoader func(ctx context.Context, groupId int32) ([]schema.GroupPermissionSchema, error)
+
+// versions numbers every initialization of any group, so users can tell their cached permissions are stale
+var versions atomic.Uint64
+
 type Group struct {
 	raw        schema.GroupSchema
 	db         *db.Database
 	perms      *perm.Perm
+	chain      []int32
+	version    uint64
 	hasRawData bool
 	hasId      bool
+	mutex      sync.RWMutex
 }
 
 func NewGroup(db *db.Database) *Group {
@@ -28,6 +45,7 @@ func NewGroupFromId(db *db.Database, id int32) *Group {
 	return &Group{
 		db:    db,
 		raw:   schema.GroupSchema{Id: id},
+		perms: perm.NewPerm(),
 		hasId: true,
 	}
 }
@@ -36,10 +54,14 @@ func NewGroupFromSchema(db *db.Database, schema *schema.GroupSchema) *Group {
 	return &Group{
 		db:         db,
 		raw:        *schema,
+		perms:      perm.NewPerm(),
 		hasRawData: true,
 	}
 }
 
+// Init loads the group and builds its effective permissions. Permissions of the group itself come first,
+// then every ancestor from the parent up to the root adds permissions not defined closer to the group.
+// A cycle in the parent chain fails the initialization
 func (g *Group) Init(ctx context.Context) error {
 	if g.db == nil {
 		return fmt.Errorf("DB is not initialized")
@@ -53,28 +75,111 @@ func (g *Group) Init(ctx context.Context) error {
 		if err != nil {
 			return err
 		}
-		g.raw = *raw
+		g.SetSchema(*raw)
+	}
+
+	return g.inherit(ctx, g.db.LoadGroupById, g.db.LoadGroupPermissions)
+}
+
+// Build creates initialized groups from a snapshot of groups with their own permissions without querying
+// the database, so every group sees the same state. Groups that fail to initialize are logged and skipped
+func Build(ctx context.Context, database *db.Database, snapshot []schema.GroupSchema) []*Group {
+	byId := make(map[int32]*schema.GroupSchema, len(snapshot))
+	for i := range snapshot {
+		byId[snapshot[i].Id] = &snapshot[i]
+	}
+	loadGroup := func(ctx context.Context, id int32) (*schema.GroupSchema, error) {
+		if raw, ok := byId[id]; ok {
+			return raw, nil
+		}
+		return nil, db.ErrGroupNotFound
+	}
+	loadPermissions := func(ctx context.Context, groupId int32) ([]schema.GroupPermissionSchema, error) {
+		return byId[groupId].Permissions, nil
+	}
+
+	result := make([]*Group, 0, len(snapshot))
+	for i := range snapshot {
+		g := NewGroupFromSchema(database, &snapshot[i])
+		if err := g.inherit(ctx, loadGroup, loadPermissions); err != nil {
+			log.Errorf("Failed to initialize group %d: %v", snapshot[i].Id, err)
+			continue
+		}
+		result = append(result, g)
 	}
+	return result
+}
 
-	permissions, err := g.db.LoadGroupPermissions(ctx, g.raw.Id)
+// inherit builds effective permissions of the group from its parent chain
+func (g *Group) inherit(ctx context.Context, loadGroup groupLoader, loadPermissions permissionLoader) error {
+	chain, err := parentChain(ctx, g.GetSchema(), loadGroup)
 	if err != nil {
-		return err
+		return fmt.Errorf("group %s: %w", g.GetName(), err)
 	}
 
-	for _, permission := range permissions {
-		log.Debugf("Adding permission %s for group %s: %t %t %t [%s]", permission.Permission, g.GetName(), permission.Read, permission.Write, permission.Delete, permission.Domain)
-		if err := g.perms.Populate(&permission); err != nil {
-			log.Errorf("Failed to populate permissions for group %s: %s", g.GetName(), err.Error())
+	perms := perm.NewPerm()
+	ids := make([]int32, 0, len(chain))
+	for _, member := range chain {
+		permissions, err := loadPermissions(ctx, member.Id)
+		if err != nil {
+			return err
+		}
+
+		level := perm.NewPerm()
+		for _, permission := range permissions {
+			log.Debugf("Adding permission %s for group %s from %s: %t %t %t [%s]", permission.Permission, g.GetName(), member.Name, permission.Read, permission.Write, permission.Delete, permission.Domain)
+			if err := level.Populate(&permission); err != nil {
+				log.Errorf("Failed to populate permissions for group %s: %s", g.GetName(), err.Error())
+			}
 		}
+		perms.Inherit(level)
+		ids = append(ids, member.Id)
 	}
 
-	log.Infof("Group %s initialized. Total number of permissions: %d", g.GetName(), g.perms.Count())
+	g.mutex.Lock()
+	g.perms = perms
+	g.chain = ids
+	g.version = versions.Add(1)
+	g.mutex.Unlock()
+
+	log.Infof("Group %s initialized. Total number of permissions: %d, inherited from %d groups", g.GetName(), perms.Count(), len(ids)-1)
 
 	return nil
 }
 
+// parentChain returns the group followed by its ancestors, nearest first. A parent that no longer
+// exists ends the chain
+func parentChain(ctx context.Context, first schema.GroupSchema, load groupLoader) ([]schema.GroupSchema, error) {
+	chain := []schema.GroupSchema{first}
+	visited := map[int32]bool{first.Id: true}
+
+	current := first
+	for current.ParentId != 0 {
+		if visited[current.ParentId] {
+			return nil, fmt.Errorf("%w: %d is an ancestor of itself", ErrGroupCycle, current.ParentId)
+		}
+
+		parent, err := load(ctx, current.ParentId)
+		if err != nil {
+			if errors.Is(err, db.ErrGroupNotFound) {
+				log.Warnf("Parent %d of group %d not found, inheritance stops here", current.ParentId, current.Id)
+				break
+			}
+			return nil, err
+		}
+
+		visited[parent.Id] = true
+		chain = append(chain, *parent)
+		current = *parent
+	}
+
+	return chain, nil
+}
+
 // GetId returns group id from raw data. Id can't be 0
 func (g *Group) GetId() int32 {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
 	if !g.hasRawData {
 		return 0
 	}
@@ -82,8 +187,64 @@ func (g *Group) GetId() int32 {
 }
 
 func (g *Group) GetName() string {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
 	if !g.hasRawData {
 		return ""
 	}
 	return g.raw.Name
 }
+
+// GetParentId returns id of the parent group or 0 for root groups
+func (g *Group) GetParentId() int32 {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	if !g.hasRawData {
+		return 0
+	}
+	return g.raw.ParentId
+}
+
+// IsSpecial reports whether members of the group are superusers
+func (g *Group) IsSpecial() bool {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.hasRawData && g.raw.IsSpecial
+}
+
+// GetSchema returns a copy of raw group data
+func (g *Group) GetSchema() schema.GroupSchema {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.raw
+}
+
+// SetSchema replaces raw group data after the group was changed. Permissions are rebuilt on the next Init
+func (g *Group) SetSchema(raw schema.GroupSchema) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	g.raw = raw
+	g.hasRawData = true
+}
+
+// GetChain returns ids of the group and its ancestors, nearest first, as resolved at Init
+func (g *Group) GetChain() []int32 {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.chain
+}
+
+// GetPermissions returns effective permissions of the group including inherited ones.
+// The result must not be modified
+func (g *Group) GetPermissions() *perm.Perm {
+	perms, _ := g.Snapshot()
+	return perms
+}
+
+// Snapshot returns effective permissions together with the version they were built at.
+// The version changes every time the group is initialized
+func (g *Group) Snapshot() (*perm.Perm, uint64) {
+	g.mutex.RLock()
+	defer g.mutex.RUnlock()
+	return g.perms, g.version
+}
@@ -2,6 +2,7 @@ package group
 
 import (
 	"context"
+	"errors"
 	"github.com/savageking-io/ogbuser/db"
 	"github.com/savageking-io/ogbuser/perm"
 	"github.com/savageking-io/ogbuser/schema"
@@ -170,3 +171,88 @@ func TestNewGroupFromSchema(t *testing.T) {
 		})
 	}
 }
+
+func Test_parentChain(t *testing.T) {
+	groups := map[int32]schema.GroupSchema{
+		1: {Id: 1, Name: "Players"},
+		2: {Id: 2, ParentId: 1, Name: "Moderators"},
+		3: {Id: 3, ParentId: 2, Name: "Administrators"},
+		4: {Id: 4, ParentId: 5, Name: "Cycle A"},
+		5: {Id: 5, ParentId: 4, Name: "Cycle B"},
+		6: {Id: 6, ParentId: 6, Name: "Self"},
+		7: {Id: 7, ParentId: 99, Name: "Orphan"},
+	}
+	load := func(ctx context.Context, id int32) (*schema.GroupSchema, error) {
+		group, ok := groups[id]
+		if !ok {
+			return nil, db.ErrGroupNotFound
+		}
+		return &group, nil
+	}
+
+	tests := []struct {
+		name    string
+		first   int32
+		want    []int32
+		wantErr error
+	}{
+		{"Root", 1, []int32{1}, nil},
+		{"Two levels", 3, []int32{3, 2, 1}, nil},
+		{"Cycle", 4, nil, ErrGroupCycle},
+		{"Self parent", 6, nil, ErrGroupCycle},
+		{"Missing parent", 7, []int32{7}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parentChain(context.Background(), groups[tt.first], load)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("parentChain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var ids []int32
+			for _, group := range got {
+				ids = append(ids, group.Id)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("parentChain() = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuild(t *testing.T) {
+	snapshot := []schema.GroupSchema{
+		{Id: 1, Name: "Players", Permissions: []schema.GroupPermissionSchema{
+			{GroupId: 1, Permission: "chat", Domain: perm.DomainGlobal, Read: true},
+			{GroupId: 1, Permission: "trade", Domain: perm.DomainGlobal, Read: true},
+		}},
+		{Id: 2, ParentId: 1, Name: "Moderators", Permissions: []schema.GroupPermissionSchema{
+			{GroupId: 2, Permission: "chat", Domain: perm.DomainGlobal, Write: true},
+		}},
+		{Id: 3, ParentId: 4, Name: "Cycle A"},
+		{Id: 4, ParentId: 3, Name: "Cycle B"},
+		{Id: 5, ParentId: 99, Name: "Orphan"},
+	}
+
+	groups := Build(context.Background(), nil, snapshot)
+
+	chains := make(map[int32][]int32)
+	for _, g := range groups {
+		chains[g.GetId()] = g.GetChain()
+	}
+	want := map[int32][]int32{1: {1}, 2: {2, 1}, 5: {5}}
+	if !reflect.DeepEqual(chains, want) {
+		t.Errorf("Build() chains = %v, want %v", chains, want)
+	}
+
+	for _, g := range groups {
+		if g.GetId() != 2 {
+			continue
+		}
+		if chat := g.GetPermissions().GetPermission(perm.DomainGlobal, "chat"); chat == nil || !chat.Allows(perm.AccessWrite) || chat.Allows(perm.AccessRead) {
+			t.Errorf("Build() Moderators chat = %+v, want write defined by the group itself", chat)
+		}
+		if trade := g.GetPermissions().GetPermission(perm.DomainGlobal, "trade"); trade == nil || !trade.Allows(perm.AccessRead) {
+			t.Errorf("Build() Moderators trade = %+v, want read inherited from Players", trade)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package group
 
-import "sync"
+import (
+	"sort"
+	"sync"
+)
 
 type GroupsData struct {
 	groups map[int32]*Group
@@ -19,6 +22,18 @@ func (d *GroupsData) Add(group *Group) {
 	d.groups[int32(group.raw.Id)] = group
 }
 
+// Replace swaps all groups at once, so readers see either the old or the new set and never a mix
+func (d *GroupsData) Replace(groups []*Group) {
+	data := make(map[int32]*Group, len(groups))
+	for _, group := range groups {
+		data[group.raw.Id] = group
+	}
+
+	defer d.mutex.Unlock()
+	d.mutex.Lock()
+	d.groups = data
+}
+
 func (d *GroupsData) Remove(id int32) {
 	defer d.mutex.Unlock()
 	d.mutex.Lock()
@@ -32,10 +47,16 @@ func (d *GroupsData) Get(id int32) (*Group, bool) {
 	return group, ok
 }
 
+// GetAll returns groups ordered by id
 func (d *GroupsData) GetAll() []*Group {
+	defer d.mutex.Unlock()
+	d.mutex.Lock()
 	var result []*Group
 	for _, group := range d.groups {
 		result = append(result, group)
 	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].raw.Id < result[j].raw.Id
+	})
 	return result
 }
@@ -0,0 +1,151 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// Event names are used as message keys so consumers can filter what they need
+const (
+	EventUserMerged             = "user.merged"
+	EventUserBanned             = "user.banned"
+	EventUserUnbanned           = "user.unbanned"
+	EventUserDeleted            = "user.deleted"
+	EventUserRestored           = "user.restored"
+	EventUserPurged             = "user.purged"
+	EventUserExportRequested    = "user.export_requested"
+	EventUserErasureRequested   = "user.erasure_requested"
+	EventUserErasureCompleted   = "user.erasure_completed"
+	EventGroupMembershipExpired = "group.membership_expired"
+	EventPermissionsInvalidated = "permissions.invalidated"
+)
+
+// EventSchema is an envelope for all domain events published by the service
+type EventSchema struct {
+	Event       string          `json:"event"`
+	PublishedAt time.Time       `json:"published_at"`
+	Data        json.RawMessage `json:"data"`
+}
+
+// UserMergedSchema tells other services to re-key data of SourceUserId to TargetUserId
+type UserMergedSchema struct {
+	TargetUserId int32     `json:"target_user_id"`
+	SourceUserId int32     `json:"source_user_id"`
+	MergedBy     int32     `json:"merged_by"`
+	MergedAt     time.Time `json:"merged_at"`
+}
+
+// UserBannedSchema is published for every new sanction. Consumers filter by Type and Scope,
+// e.g. chat only cares about mutes of the "chat" feature
+type UserBannedSchema struct {
+	SanctionId int32      `json:"sanction_id"`
+	UserId     int32      `json:"user_id"`
+	Type       string     `json:"type"`
+	Scope      string     `json:"scope"`
+	Reason     string     `json:"reason"`
+	IssuedBy   int32      `json:"issued_by"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	IssuedAt   time.Time  `json:"issued_at"`
+}
+
+// Causes of UserUnbannedSchema
+const (
+	UnbanCauseRevoked = "revoked"
+	UnbanCauseExpired = "expired"
+)
+
+// UserUnbannedSchema is published when a sanction is revoked or expires
+type UserUnbannedSchema struct {
+	SanctionId int32     `json:"sanction_id"`
+	UserId     int32     `json:"user_id"`
+	Type       string    `json:"type"`
+	Scope      string    `json:"scope"`
+	Cause      string    `json:"cause"`
+	RevokedBy  int32     `json:"revoked_by"`
+	UnbannedAt time.Time `json:"unbanned_at"`
+}
+
+// UserDeletedSchema is published when a user is soft-deleted. The user can still be restored
+type UserDeletedSchema struct {
+	UserId    int32     `json:"user_id"`
+	DeletedBy int32     `json:"deleted_by"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+type UserRestoredSchema struct {
+	UserId     int32     `json:"user_id"`
+	RestoredBy int32     `json:"restored_by"`
+	RestoredAt time.Time `json:"restored_at"`
+}
+
+// UserPurgedSchema is published when personal data of a deleted user is gone for good
+type UserPurgedSchema struct {
+	UserId   int32     `json:"user_id"`
+	Mode     string    `json:"mode"`
+	PurgedAt time.Time `json:"purged_at"`
+}
+
+// UserExportRequestedSchema asks other services to prepare their part of a data export.
+// Parts are matched to ogbuser's archive by ExportId
+type UserExportRequestedSchema struct {
+	ExportId    string    `json:"export_id"`
+	UserId      int32     `json:"user_id"`
+	RequestedBy int32     `json:"requested_by"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// UserErasureRequestedSchema asks services to erase their data of the user and acknowledge
+// the request through AcknowledgeErasure RPC
+type UserErasureRequestedSchema struct {
+	RequestId   int32     `json:"request_id"`
+	UserId      int32     `json:"user_id"`
+	Services    []string  `json:"services"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
+// UserErasureCompletedSchema is published once every service acknowledged and local data is anonymized
+type UserErasureCompletedSchema struct {
+	RequestId   int32     `json:"request_id"`
+	UserId      int32     `json:"user_id"`
+	CompletedAt time.Time `json:"completed_at"`
+}
+
+// GroupMembershipExpiredSchema is published when a time-limited membership ends
+type GroupMembershipExpiredSchema struct {
+	GroupId   int32     `json:"group_id"`
+	UserId    int32     `json:"user_id"`
+	ExpiredAt time.Time `json:"expired_at"`
+}
+
+// PermissionsInvalidatedSchema tells services caching permission results which of them are stale. Results
+// of UserIds are stale; a change of GroupIds may affect any of their members, so consumers that don't track
+// membership drop everything, as they do when All is set
+type PermissionsInvalidatedSchema struct {
+	UserIds       []int32   `json:"user_ids"`
+	GroupIds      []int32   `json:"group_ids"`
+	All           bool      `json:"all"`
+	Reason        string    `json:"reason"`
+	InvalidatedAt time.Time `json:"invalidated_at"`
+}
+
+// PublishEvent wraps data into EventSchema and writes it with event name as a key
+func (p *Publisher) PublishEvent(ctx context.Context, event string, data any) error {
+	log.Tracef("Kafka::Publisher::PublishEvent: %s", event)
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	envelope, err := json.Marshal(&EventSchema{
+		Event:       event,
+		PublishedAt: time.Now(),
+		Data:        payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	return p.Publish(ctx, []byte(event), envelope)
+}
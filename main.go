@@ -1,9 +1,15 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/savageking-io/ogbuser/db"
+	"github.com/savageking-io/ogbuser/policy"
 	"github.com/savageking-io/ogbuser/token"
 	"os"
 	"time"
+	_ "time/tzdata" // Profile timezones are validated against the embedded database. Alpine has none
 
 	ogb "github.com/savageking-io/ogbcommon"
 	steam "github.com/savageking-io/ogbsteam/client"
@@ -18,6 +24,44 @@ func main() {
 	app.Description = "User management service for online games"
 	app.Usage = "User Microservice of OnlineGameBase ecosystem"
 
+	policyFlags := []cli.Flag{
+		cli.StringFlag{
+			Name:        "config",
+			Usage:       "Configuration filepath",
+			Value:       ConfigFilepath,
+			Destination: &ConfigFilepath,
+		},
+		cli.StringFlag{
+			Name:        "log",
+			Usage:       "Specify logging level",
+			Value:       "",
+			Destination: &LogLevel,
+		},
+		cli.StringFlag{
+			Name:  "file",
+			Usage: "Policy file. Taken from configuration when empty",
+		},
+		cli.BoolFlag{
+			Name:  "prune",
+			Usage: "Delete groups that are not declared in the file",
+		},
+	}
+
+	migrateFlags := []cli.Flag{
+		cli.StringFlag{
+			Name:        "config",
+			Usage:       "Configuration filepath",
+			Value:       ConfigFilepath,
+			Destination: &ConfigFilepath,
+		},
+		cli.StringFlag{
+			Name:        "log",
+			Usage:       "Specify logging level",
+			Value:       "",
+			Destination: &LogLevel,
+		},
+	}
+
 	app.Authors = []cli.Author{
 		{
 			Name:  "savageking.io",
@@ -51,12 +95,168 @@ func main() {
 			},
 			Action: Serve,
 		},
+		{
+			Name:  "merge",
+			Usage: "Merge duplicate account into another one",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:        "config",
+					Usage:       "Configuration filepath",
+					Value:       ConfigFilepath,
+					Destination: &ConfigFilepath,
+				},
+				cli.StringFlag{
+					Name:        "log",
+					Usage:       "Specify logging level",
+					Value:       "",
+					Destination: &LogLevel,
+				},
+				cli.IntFlag{
+					Name:  "into",
+					Usage: "Id of the user that will stay",
+				},
+				cli.IntFlag{
+					Name:  "from",
+					Usage: "Id of the user that will be merged and deleted",
+				},
+			},
+			Action: Merge,
+		},
+		{
+			Name:  "purge",
+			Usage: "Purge users deleted longer than retention period ago",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:        "config",
+					Usage:       "Configuration filepath",
+					Value:       ConfigFilepath,
+					Destination: &ConfigFilepath,
+				},
+				cli.StringFlag{
+					Name:        "log",
+					Usage:       "Specify logging level",
+					Value:       "",
+					Destination: &LogLevel,
+				},
+			},
+			Action: Purge,
+		},
+		{
+			Name:  "export",
+			Usage: "Export everything stored about a user as JSON",
+			Flags: []cli.Flag{
+				cli.StringFlag{
+					Name:        "config",
+					Usage:       "Configuration filepath",
+					Value:       ConfigFilepath,
+					Destination: &ConfigFilepath,
+				},
+				cli.StringFlag{
+					Name:        "log",
+					Usage:       "Specify logging level",
+					Value:       "",
+					Destination: &LogLevel,
+				},
+				cli.IntFlag{
+					Name:  "user",
+					Usage: "Id of the user to export",
+				},
+				cli.StringFlag{
+					Name:  "out",
+					Usage: "File to write the archive to. Standard output when empty",
+				},
+			},
+			Action: Export,
+		},
+		{
+			Name:  "policy",
+			Usage: "Manage groups and permissions with a declarative policy file",
+			Subcommands: []cli.Command{
+				{
+					Name:   "plan",
+					Usage:  "Show changes the policy file would make",
+					Flags:  policyFlags,
+					Action: PolicyPlan,
+				},
+				{
+					Name:   "apply",
+					Usage:  "Reconcile groups and permissions with the policy file",
+					Flags:  policyFlags,
+					Action: PolicyApply,
+				},
+			},
+		},
+		{
+			Name:  "groups",
+			Usage: "Manage group membership from the server",
+			Subcommands: []cli.Command{
+				{
+					Name:  "add-member",
+					Usage: "Add a user to a group without approval, e.g. to make the first superuser",
+					Flags: []cli.Flag{
+						cli.StringFlag{
+							Name:        "config",
+							Usage:       "Configuration filepath",
+							Value:       ConfigFilepath,
+							Destination: &ConfigFilepath,
+						},
+						cli.StringFlag{
+							Name:        "log",
+							Usage:       "Specify logging level",
+							Value:       "",
+							Destination: &LogLevel,
+						},
+						cli.StringFlag{
+							Name:  "group",
+							Usage: "Name of the group",
+						},
+						cli.IntFlag{
+							Name:  "user",
+							Usage: "Id of the user to add",
+						},
+					},
+					Action: GroupsAddMember,
+				},
+			},
+		},
+		{
+			Name:  "migrate",
+			Usage: "Manage database schema migrations",
+			Subcommands: []cli.Command{
+				{
+					Name:  "up",
+					Usage: "Apply pending migrations",
+					Flags: append(migrateFlags, cli.BoolFlag{
+						Name:  "fixtures",
+						Usage: "Insert sample data for development into a database without users",
+					}),
+					Action: MigrateUp,
+				},
+				{
+					Name:  "down",
+					Usage: "Revert the latest migrations",
+					Flags: append(migrateFlags, cli.IntFlag{
+						Name:  "steps",
+						Usage: "Number of migrations to revert",
+						Value: 1,
+					}),
+					Action: MigrateDown,
+				},
+				{
+					Name:   "status",
+					Usage:  "List migrations and whether they are applied",
+					Flags:  migrateFlags,
+					Action: MigrateStatus,
+				},
+			},
+		},
 	}
 
 	_ = app.Run(os.Args)
 }
 
-func Serve(c *cli.Context) error {
+// LoadConfig reads configuration file and sets up logging
+func LoadConfig() error {
 	err := ogb.ReadYAMLConfig(ConfigFilepath, &AppConfig)
 	if err != nil {
 		log.Errorf("Failed to read configuration file: %v", err)
@@ -78,6 +278,13 @@ func Serve(c *cli.Context) error {
 	log.Infof("Configuration loaded from %s", ConfigFilepath)
 
 	token.SetConfig(&AppConfig.Crypto.JWT)
+	return nil
+}
+
+func Serve(c *cli.Context) error {
+	if err := LoadConfig(); err != nil {
+		return err
+	}
 
 	steamClient := steam.NewClient(AppConfig.SteamClient.Hostname, AppConfig.SteamClient.Port)
 	go func() {
@@ -104,3 +311,289 @@ func Serve(c *cli.Context) error {
 
 	return service.Start()
 }
+
+// NewCommandService prepares service for one-off CLI commands: database and kafka only
+func NewCommandService() (*Service, error) {
+	if err := LoadConfig(); err != nil {
+		return nil, err
+	}
+
+	service := NewService(&AppConfig, nil)
+	if err := service.ConnectToDatabase(); err != nil {
+		return nil, err
+	}
+	service.users.SetDb(service.db)
+
+	if err := service.kafka.Init(AppConfig.Kafka); err != nil {
+		return nil, err
+	}
+
+	return service, nil
+}
+
+func Merge(c *cli.Context) error {
+	targetId := int32(c.Int("into"))
+	sourceId := int32(c.Int("from"))
+	if targetId == 0 || sourceId == 0 {
+		return fmt.Errorf("both --into and --from must be provided")
+	}
+
+	service, err := NewCommandService()
+	if err != nil {
+		return err
+	}
+	defer service.kafka.Close()
+
+	result, err := service.mergeUsers(context.Background(), targetId, sourceId, 0)
+	if err != nil {
+		log.Errorf("Failed to merge user %d into %d: %v", sourceId, targetId, err)
+		return err
+	}
+
+	fmt.Printf("Merged user %d into %d: moved platforms: %d, added groups: %d, revoked sessions: %d\n",
+		sourceId, targetId, result.MovedPlatforms, result.AddedGroups, result.RevokedSessions)
+	return nil
+}
+
+func Purge(c *cli.Context) error {
+	service, err := NewCommandService()
+	if err != nil {
+		return err
+	}
+	defer service.kafka.Close()
+
+	purged, err := service.purgeDeletedUsers(context.Background())
+	if err != nil {
+		log.Errorf("Failed to purge deleted users: %v", err)
+		return err
+	}
+
+	fmt.Printf("Purged %d deleted users\n", purged)
+	return nil
+}
+
+func Export(c *cli.Context) error {
+	userId := int32(c.Int("user"))
+	if userId == 0 {
+		return fmt.Errorf("--user must be provided")
+	}
+
+	service, err := NewCommandService()
+	if err != nil {
+		return err
+	}
+	defer service.kafka.Close()
+
+	archive, err := service.exportUser(context.Background(), userId, 0)
+	if err != nil {
+		log.Errorf("Failed to export user %d: %v", userId, err)
+		return err
+	}
+
+	body, err := json.MarshalIndent(archive, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if c.String("out") == "" {
+		fmt.Println(string(body))
+		return nil
+	}
+	if err := os.WriteFile(c.String("out"), body, 0600); err != nil {
+		return err
+	}
+	fmt.Printf("Export %s of user %d written to %s\n", archive.ExportId, userId, c.String("out"))
+	return nil
+}
+
+func GroupsAddMember(c *cli.Context) error {
+	groupName := c.String("group")
+	userId := int32(c.Int("user"))
+	if groupName == "" || userId == 0 {
+		return fmt.Errorf("both --group and --user must be provided")
+	}
+
+	service, err := NewCommandService()
+	if err != nil {
+		return err
+	}
+	defer service.kafka.Close()
+
+	groupId, err := service.addGroupMemberFromCli(context.Background(), groupName, userId)
+	if err != nil {
+		log.Errorf("Failed to add user %d to group %q: %v", userId, groupName, err)
+		return err
+	}
+	fmt.Printf("User %d added to group %q (%d)\n", userId, groupName, groupId)
+	return nil
+}
+
+// loadPolicyCommand prepares the service and reads the policy file given to policy subcommands
+func loadPolicyCommand(c *cli.Context) (*Service, *policy.Policy, bool, error) {
+	service, err := NewCommandService()
+	if err != nil {
+		return nil, nil, false, err
+	}
+
+	path := c.String("file")
+	if path == "" {
+		path = AppConfig.Policy.File
+	}
+	if path == "" {
+		service.kafka.Close()
+		return nil, nil, false, fmt.Errorf("--file must be provided")
+	}
+
+	p, err := policy.Load(path)
+	if err != nil {
+		service.kafka.Close()
+		log.Errorf("Failed to load policy file %s: %v", path, err)
+		return nil, nil, false, err
+	}
+	return service, p, c.Bool("prune") || AppConfig.Policy.Prune, nil
+}
+
+func PolicyPlan(c *cli.Context) error {
+	service, p, prune, err := loadPolicyCommand(c)
+	if err != nil {
+		return err
+	}
+	defer service.kafka.Close()
+
+	changes, err := service.planPolicy(context.Background(), p, prune)
+	if err != nil {
+		log.Errorf("Failed to plan policy: %v", err)
+		return err
+	}
+
+	if len(changes) == 0 {
+		fmt.Println("No changes. Groups match the policy")
+		return nil
+	}
+	for _, change := range changes {
+		fmt.Println(change.Summary)
+	}
+	fmt.Printf("Plan: %d changes\n", len(changes))
+	return nil
+}
+
+func PolicyApply(c *cli.Context) error {
+	service, p, prune, err := loadPolicyCommand(c)
+	if err != nil {
+		return err
+	}
+	defer service.kafka.Close()
+
+	changes, err := service.applyPolicy(context.Background(), 0, p, prune)
+	if err != nil {
+		log.Errorf("Failed to apply policy: %v", err)
+		return err
+	}
+
+	for _, change := range changes {
+		fmt.Println(change.Summary)
+	}
+	fmt.Printf("Applied %d changes\n", len(changes))
+	return nil
+}
+
+// connectMigrateCommand connects to the database for migrate subcommands. Unlike other commands it
+// doesn't require the schema to be up to date
+func connectMigrateCommand() (*db.Database, error) {
+	if err := LoadConfig(); err != nil {
+		return nil, err
+	}
+
+	database := new(db.Database)
+	if err := database.Init(&AppConfig.Postgres); err != nil {
+		return nil, err
+	}
+	if err := database.Connect(); err != nil {
+		return nil, err
+	}
+	return database, nil
+}
+
+func MigrateUp(c *cli.Context) error {
+	database, err := connectMigrateCommand()
+	if err != nil {
+		return err
+	}
+
+	migrations, err := database.MigrateUp(context.Background())
+	for _, migration := range migrations {
+		fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+	}
+	if err != nil {
+		log.Errorf("Failed to migrate database: %v", err)
+		return err
+	}
+	if len(migrations) == 0 {
+		fmt.Println("No pending migrations")
+	}
+
+	if c.Bool("fixtures") || AppConfig.Postgres.DevFixtures {
+		applied, err := database.ApplyDevFixtures(context.Background())
+		if err != nil {
+			log.Errorf("Failed to insert dev fixtures: %v", err)
+			return err
+		}
+		if applied {
+			fmt.Println("Dev fixtures inserted")
+		} else {
+			fmt.Println("Database has users, dev fixtures skipped")
+		}
+	}
+	return nil
+}
+
+func MigrateDown(c *cli.Context) error {
+	database, err := connectMigrateCommand()
+	if err != nil {
+		return err
+	}
+
+	migrations, err := database.MigrateDown(context.Background(), c.Int("steps"))
+	for _, migration := range migrations {
+		fmt.Printf("Reverted %04d_%s\n", migration.Version, migration.Name)
+	}
+	if err != nil {
+		log.Errorf("Failed to revert migrations: %v", err)
+		return err
+	}
+	if len(migrations) == 0 {
+		fmt.Println("No applied migrations")
+	}
+	return nil
+}
+
+func MigrateStatus(c *cli.Context) error {
+	database, err := connectMigrateCommand()
+	if err != nil {
+		return err
+	}
+
+	statuses, err := database.MigrationStatus(context.Background())
+	if err != nil {
+		log.Errorf("Failed to read migration status: %v", err)
+		return err
+	}
+
+	pending := 0
+	for _, status := range statuses {
+		state := "pending"
+		switch {
+		case status.Unknown:
+			state = "applied " + status.AppliedAt.Format(time.RFC3339) + ", unknown to this build"
+		case status.Modified:
+			state = "applied " + status.AppliedAt.Format(time.RFC3339) + ", modified since"
+		case status.AppliedAt != nil:
+			state = "applied " + status.AppliedAt.Format(time.RFC3339)
+		default:
+			pending++
+		}
+		fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, state)
+	}
+	fmt.Printf("%d migrations, %d pending\n", len(statuses), pending)
+	return nil
+}
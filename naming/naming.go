@@ -0,0 +1,89 @@
+// Package naming validates usernames against format rules, reserved names and a profanity list
+package naming
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrInvalidUsername  = errors.New("invalid username")
+	ErrReservedUsername = errors.New("username is reserved")
+	ErrProfaneUsername  = errors.New("username contains inappropriate words")
+)
+
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 32
+)
+
+var usernameRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+type Config struct {
+	CooldownDays     int      `yaml:"cooldown_days"`     // How often a user can change their username
+	HoldDays         int      `yaml:"hold_days"`         // How long an old username can't be taken by someone else
+	Reserved         []string `yaml:"reserved"`          // Names nobody can take. Case-insensitive
+	ReservedPrefixes []string `yaml:"reserved_prefixes"` // Prefixes nobody can take, e.g. generated guest names
+	Profanity        []string `yaml:"profanity"`         // Words that can't appear anywhere in a username. Case-insensitive
+}
+
+type Policy struct {
+	reserved         map[string]struct{}
+	reservedPrefixes []string
+	profanity        []string
+}
+
+func NewPolicy(config *Config) *Policy {
+	p := &Policy{
+		reserved: make(map[string]struct{}),
+	}
+	if config == nil {
+		return p
+	}
+	for _, name := range config.Reserved {
+		p.reserved[strings.ToLower(strings.TrimSpace(name))] = struct{}{}
+	}
+	for _, prefix := range config.ReservedPrefixes {
+		if prefix = strings.ToLower(strings.TrimSpace(prefix)); prefix != "" {
+			p.reservedPrefixes = append(p.reservedPrefixes, prefix)
+		}
+	}
+	for _, word := range config.Profanity {
+		if word = strings.ToLower(strings.TrimSpace(word)); word != "" {
+			p.profanity = append(p.profanity, word)
+		}
+	}
+	return p
+}
+
+// Validate returns nil if the username can be taken by a user
+func (p *Policy) Validate(username string) error {
+	if len(username) < MinUsernameLength || len(username) > MaxUsernameLength {
+		return fmt.Errorf("%w: length must be between %d and %d", ErrInvalidUsername, MinUsernameLength, MaxUsernameLength)
+	}
+	if !usernameRegexp.MatchString(username) {
+		return fmt.Errorf("%w: only letters, digits, '_', '.' and '-' are allowed", ErrInvalidUsername)
+	}
+
+	lower := strings.ToLower(username)
+	if _, ok := p.reserved[lower]; ok {
+		return ErrReservedUsername
+	}
+	for _, prefix := range p.reservedPrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return ErrReservedUsername
+		}
+	}
+
+	// Separators are dropped so "b.a.d" doesn't slip through
+	compact := strings.NewReplacer("_", "", ".", "", "-", "").Replace(lower)
+	for _, word := range p.profanity {
+		if strings.Contains(lower, word) || strings.Contains(compact, word) {
+			return ErrProfaneUsername
+		}
+	}
+
+	return nil
+}
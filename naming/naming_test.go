@@ -0,0 +1,49 @@
+package naming
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPolicy_Validate(t *testing.T) {
+	config := &Config{
+		Reserved:         []string{"Admin", "root"},
+		ReservedPrefixes: []string{"guest_"},
+		Profanity:        []string{"badword"},
+	}
+	tests := []struct {
+		name     string
+		username string
+		wantErr  error
+	}{
+		{"Valid", "jane_smith", nil},
+		{"Valid with dots and dashes", "jane.smith-2", nil},
+		{"Too short", "ab", ErrInvalidUsername},
+		{"Too long", "abcdefghijklmnopqrstuvwxyz0123456", ErrInvalidUsername},
+		{"Email", "jane@example.com", ErrInvalidUsername},
+		{"Spaces", "jane smith", ErrInvalidUsername},
+		{"Reserved", "root", ErrReservedUsername},
+		{"Reserved case-insensitive", "ADMIN", ErrReservedUsername},
+		{"Reserved prefix", "Guest_0a1b2c", ErrReservedUsername},
+		{"Profanity", "mybadword1", ErrProfaneUsername},
+		{"Profanity with separators", "bad.word", ErrProfaneUsername},
+	}
+	p := NewPolicy(config)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Validate(tt.username)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("Validate() error = %v, want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewPolicy_NilConfig(t *testing.T) {
+	if err := NewPolicy(nil).Validate("admin"); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,54 @@
+package perm
+
+// Match is a grant or deny of a single access bit matching a checked permission
+type Match struct {
+	Name    string // Exact permission name or a wildcard covering it
+	Domain  string
+	Access  Access
+	Rule    string // RuleGrant or RuleDeny
+	GroupId int32  // Group that defined the rule, an ancestor when the rule is inherited
+	Applied bool   // Rule decides its bit
+}
+
+// Matches lists every grant and deny in the domain matching the permission, most specific first. Unlike
+// GetPermission it also returns rules that don't decide their bit, marking the applied ones: the most specific
+// deny of a bit, or when nothing denies it, the grant of the most specific match granting any bit
+func (p *Perm) Matches(domain string, permission string) []Match {
+	denied := [3]bool{}
+	granting := ""
+	for _, key := range candidates(permission) {
+		entry := p.lookup(domain, key)
+		if entry == nil {
+			continue
+		}
+		if granting == "" && entry.grants() {
+			granting = key
+		}
+		for i, bit := range accessBits {
+			if _, deny := entry.bits(bit); *deny != 0 {
+				denied[i] = true
+			}
+		}
+	}
+
+	var result []Match
+	denyApplied := [3]bool{}
+	for _, key := range candidates(permission) {
+		entry := p.lookup(domain, key)
+		if entry == nil {
+			continue
+		}
+		for i, bit := range accessBits {
+			grant, deny := entry.bits(bit)
+			if *grant != 0 {
+				applied := !denied[i] && key == granting
+				result = append(result, Match{Name: key, Domain: domain, Access: bit, Rule: RuleGrant, GroupId: entry.grantedBy[i], Applied: applied})
+			}
+			if *deny != 0 {
+				result = append(result, Match{Name: key, Domain: domain, Access: bit, Rule: RuleDeny, GroupId: entry.deniedBy[i], Applied: !denyApplied[i]})
+				denyApplied[i] = true
+			}
+		}
+	}
+	return result
+}
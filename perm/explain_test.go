@@ -0,0 +1,72 @@
+package perm
+
+import (
+	"github.com/savageking-io/ogbuser/schema"
+	"reflect"
+	"testing"
+)
+
+func TestPerm_Matches(t *testing.T) {
+	p := newTestPerm(t,
+		schema.GroupPermissionSchema{GroupId: 2, Permission: "inventory.*", Read: true, Write: true, Domain: DomainGlobal},
+		schema.GroupPermissionSchema{GroupId: 5, Permission: "inventory.items.trade", DenyWrite: true, Domain: DomainGlobal},
+		schema.GroupPermissionSchema{GroupId: 3, Permission: "chat", Read: true, Domain: DomainOwn},
+		schema.GroupPermissionSchema{GroupId: 4, Permission: "chat.send", Read: true, Domain: DomainGlobal},
+		schema.GroupPermissionSchema{GroupId: 6, Permission: "chat.*", DenyRead: true, Domain: DomainGlobal},
+		schema.GroupPermissionSchema{GroupId: 7, Permission: "inventory.gold", Read: true, Domain: DomainGlobal},
+	)
+
+	tests := []struct {
+		name       string
+		domain     string
+		permission string
+		want       []Match
+	}{
+		{"Exact rule shadows wildcard", DomainGlobal, "inventory.items.trade", []Match{
+			{Name: "inventory.items.trade", Domain: DomainGlobal, Access: AccessWrite, Rule: RuleDeny, GroupId: 5, Applied: true},
+			{Name: "inventory.*", Domain: DomainGlobal, Access: AccessRead, Rule: RuleGrant, GroupId: 2, Applied: true},
+			{Name: "inventory.*", Domain: DomainGlobal, Access: AccessWrite, Rule: RuleGrant, GroupId: 2, Applied: false},
+		}},
+		{"Wildcard only", DomainGlobal, "inventory.items.sell", []Match{
+			{Name: "inventory.*", Domain: DomainGlobal, Access: AccessRead, Rule: RuleGrant, GroupId: 2, Applied: true},
+			{Name: "inventory.*", Domain: DomainGlobal, Access: AccessWrite, Rule: RuleGrant, GroupId: 2, Applied: true},
+		}},
+		{"Exact grant narrows wildcard", DomainGlobal, "inventory.gold", []Match{
+			{Name: "inventory.gold", Domain: DomainGlobal, Access: AccessRead, Rule: RuleGrant, GroupId: 7, Applied: true},
+			{Name: "inventory.*", Domain: DomainGlobal, Access: AccessRead, Rule: RuleGrant, GroupId: 2, Applied: false},
+			{Name: "inventory.*", Domain: DomainGlobal, Access: AccessWrite, Rule: RuleGrant, GroupId: 2, Applied: false},
+		}},
+		{"Wildcard deny overrides exact grant", DomainGlobal, "chat.send", []Match{
+			{Name: "chat.send", Domain: DomainGlobal, Access: AccessRead, Rule: RuleGrant, GroupId: 4, Applied: false},
+			{Name: "chat.*", Domain: DomainGlobal, Access: AccessRead, Rule: RuleDeny, GroupId: 6, Applied: true},
+		}},
+		{"Other domain", DomainGlobal, "chat", nil},
+		{"Unknown domain", "world", "chat", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.Matches(tt.domain, tt.permission); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Matches() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccess_String(t *testing.T) {
+	tests := []struct {
+		name   string
+		access Access
+		want   string
+	}{
+		{"Single bit", AccessWrite, "write"},
+		{"Several bits", AccessRead | AccessDelete, "read|delete"},
+		{"No bits", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.access.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
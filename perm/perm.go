@@ -1,9 +1,12 @@
 package perm
 
 import (
+	"errors"
 	"fmt"
 	"github.com/savageking-io/ogbuser/schema"
 	log "github.com/sirupsen/logrus"
+	"regexp"
+	"strings"
 )
 
 const (
@@ -13,20 +16,234 @@ const (
 	DomainGlobal string = "global"
 )
 
+// Domains lists every permission domain
+var Domains = []string{DomainOwn, DomainParty, DomainGuild, DomainGlobal}
+
+const MaxNameLength = 100
+
+var ErrInvalidPermission = errors.New("invalid permission")
+
+// Wildcard is the last segment of a grant covering every permission under its namespace
+const Wildcard = "*"
+
+var (
+	namePattern  = regexp.MustCompile(`^[a-z][a-z0-9_]*(\.[a-z][a-z0-9_]*)*$`)
+	grantPattern = regexp.MustCompile(`^([a-z][a-z0-9_]*\.)*([a-z][a-z0-9_]*|\*)$`)
+)
+
+// ValidateGrant is Validate that also accepts wildcard grants such as inventory.* or *
+func ValidateGrant(name, domain string) error {
+	if len(name) > MaxNameLength || !grantPattern.MatchString(name) {
+		return fmt.Errorf("%w: grant %q must be a permission name or a namespace ending with .*", ErrInvalidPermission, name)
+	}
+	if !IsDomain(domain) {
+		return fmt.Errorf("%w: unknown domain %q", ErrInvalidPermission, domain)
+	}
+	return nil
+}
+
+// IsWildcard reports whether the grant covers a namespace rather than a single permission
+func IsWildcard(name string) bool {
+	return name == Wildcard || strings.HasSuffix(name, "."+Wildcard)
+}
+
+// candidates returns the name followed by wildcards covering it, most specific first:
+// inventory.items.trade, inventory.items.*, inventory.*, *
+func candidates(name string) []string {
+	result := []string{name}
+	for i := len(name) - 1; i > 0; i-- {
+		if name[i] == '.' {
+			result = append(result, name[:i+1]+Wildcard)
+		}
+	}
+	if name != Wildcard {
+		result = append(result, Wildcard)
+	}
+	return result
+}
+
+// IsDomain reports whether domain is one of Domains
+func IsDomain(domain string) bool {
+	for _, d := range Domains {
+		if d == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks that the permission name is made of dot-separated lowercase identifiers,
+// e.g. inventory.items.trade, and the domain is known
+func Validate(name, domain string) error {
+	if len(name) > MaxNameLength || !namePattern.MatchString(name) {
+		return fmt.Errorf("%w: name %q must be dot-separated lowercase identifiers", ErrInvalidPermission, name)
+	}
+	if !IsDomain(domain) {
+		return fmt.Errorf("%w: unknown domain %q", ErrInvalidPermission, domain)
+	}
+	return nil
+}
+
+// Access is a set of access bits requested from a permission
+type Access int
+
+const (
+	AccessRead Access = 1 << iota
+	AccessWrite
+	AccessDelete
+)
+
+// accessBits lists single access bits in the order they are evaluated
+var accessBits = []Access{AccessRead, AccessWrite, AccessDelete}
+
+// accessNames are names of accessBits
+var accessNames = []string{"read", "write", "delete"}
+
+// String returns names of the access bits joined with "|", e.g. "read|write"
+func (a Access) String() string {
+	var names []string
+	for i, bit := range accessBits {
+		if a&bit != 0 {
+			names = append(names, accessNames[i])
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+// Rules that can decide an access check
+const (
+	RuleGrant     string = "grant"
+	RuleDeny      string = "deny"
+	RuleNone      string = "none"      // Nothing grants the access
+	RuleSuperuser string = "superuser" // Member of a special group, checks are bypassed
+)
+
+// Decision explains the outcome of an access check
+type Decision struct {
+	Allowed bool
+	Access  Access // Bit that decided the outcome, all requested bits when allowed
+	GroupId int32  // Group whose rule decided the outcome, 0 for RuleNone
+	Rule    string
+}
+
 type Permission struct {
-	Name   string
-	Read   int32
-	Write  int32
-	Delete int32
-	Domain string
-	raw    schema.GroupPermissionSchema
+	Name       string
+	Read       int32
+	Write      int32
+	Delete     int32
+	DenyRead   int32
+	DenyWrite  int32
+	DenyDelete int32
+	Domain     string
+	raw        schema.GroupPermissionSchema
+	grantedBy  [3]int32 // Group that granted each of accessBits
+	deniedBy   [3]int32 // Group that denied each of accessBits
+	bypassedBy int32    // Special group that bypasses the check, 0 for regular permissions
+}
+
+// NewSuperuserPermission returns a permission with full access granted through the special group.
+// Denies don't apply to it
+func NewSuperuserPermission(name, domain string, groupId int32) *Permission {
+	return &Permission{
+		Name:       name,
+		Read:       1,
+		Write:      1,
+		Delete:     1,
+		Domain:     domain,
+		grantedBy:  [3]int32{groupId, groupId, groupId},
+		bypassedBy: groupId,
+	}
+}
+
+// IsBypass reports whether the permission was granted by a superuser bypass
+func (p *Permission) IsBypass() bool {
+	return p.bypassedBy != 0
+}
+
+// GetGroupId returns id of the group that defined the permission. 0 if permission is not defined
+func (p *Permission) GetGroupId() int32 {
+	return p.raw.GroupId
+}
+
+// bits returns grant and deny fields of a single access bit
+func (p *Permission) bits(bit Access) (*int32, *int32) {
+	switch bit {
+	case AccessRead:
+		return &p.Read, &p.DenyRead
+	case AccessWrite:
+		return &p.Write, &p.DenyWrite
+	}
+	return &p.Delete, &p.DenyDelete
+}
+
+// grants reports whether the permission grants any access bit
+func (p *Permission) grants() bool {
+	return p.Read != 0 || p.Write != 0 || p.Delete != 0
+}
+
+// Decide checks every requested access bit. A deny on any bit overrides all grants, otherwise every
+// requested bit has to be granted
+func (p *Permission) Decide(access Access) Decision {
+	if p.bypassedBy != 0 {
+		return Decision{Allowed: true, Access: access, GroupId: p.bypassedBy, Rule: RuleSuperuser}
+	}
+
+	for i, bit := range accessBits {
+		if access&bit == 0 {
+			continue
+		}
+		if _, deny := p.bits(bit); *deny != 0 {
+			return Decision{Allowed: false, Access: bit, GroupId: p.deniedBy[i], Rule: RuleDeny}
+		}
+	}
+
+	decision := Decision{Allowed: true, Access: access, Rule: RuleNone}
+	for i, bit := range accessBits {
+		if access&bit == 0 {
+			continue
+		}
+		if grant, _ := p.bits(bit); *grant == 0 {
+			return Decision{Allowed: false, Access: bit, Rule: RuleNone}
+		}
+		if decision.Rule == RuleNone {
+			decision.GroupId = p.grantedBy[i]
+			decision.Rule = RuleGrant
+		}
+	}
+	return decision
+}
+
+// Allows reports whether every requested access bit is granted and none is denied
+func (p *Permission) Allows(access Access) bool {
+	return p.Decide(access).Allowed
+}
+
+// Effective returns 1 if the single access bit is allowed, 0 otherwise
+func (p *Permission) Effective(bit Access) int32 {
+	return schema.BoolToInt32(p.Allows(bit))
+}
+
+// unite adds deny bits of other to p, and its grant bits as well when withGrants is set
+func (p *Permission) unite(other *Permission, withGrants bool) {
+	for i, bit := range accessBits {
+		grant, deny := p.bits(bit)
+		otherGrant, otherDeny := other.bits(bit)
+		if withGrants && *grant == 0 && *otherGrant != 0 {
+			*grant = 1
+			p.grantedBy[i] = other.grantedBy[i]
+		}
+		if *deny == 0 && *otherDeny != 0 {
+			*deny = 1
+			p.deniedBy[i] = other.deniedBy[i]
+		}
+	}
 }
 
 type Perm struct {
-	own         map[string]Permission
-	party       map[string]Permission
-	guild       map[string]Permission
-	global      map[string]Permission
+	own         map[string]*Permission
+	party       map[string]*Permission
+	guild       map[string]*Permission
+	global      map[string]*Permission
 	ownArray    []*Permission
 	partyArray  []*Permission
 	guildArray  []*Permission
@@ -35,10 +252,10 @@ type Perm struct {
 
 func NewPerm() *Perm {
 	return &Perm{
-		own:    make(map[string]Permission),
-		party:  make(map[string]Permission),
-		guild:  make(map[string]Permission),
-		global: make(map[string]Permission),
+		own:    make(map[string]*Permission),
+		party:  make(map[string]*Permission),
+		guild:  make(map[string]*Permission),
+		global: make(map[string]*Permission),
 	}
 }
 
@@ -82,28 +299,139 @@ func (p *Perm) Populate(rawPerm *schema.GroupPermissionSchema) error {
 	perm.Read = schema.BoolToInt32(perm.raw.Read)
 	perm.Write = schema.BoolToInt32(perm.raw.Write)
 	perm.Delete = schema.BoolToInt32(perm.raw.Delete)
+	perm.DenyRead = schema.BoolToInt32(perm.raw.DenyRead)
+	perm.DenyWrite = schema.BoolToInt32(perm.raw.DenyWrite)
+	perm.DenyDelete = schema.BoolToInt32(perm.raw.DenyDelete)
+	for i, bit := range accessBits {
+		grant, deny := perm.bits(bit)
+		if *grant != 0 {
+			perm.grantedBy[i] = perm.raw.GroupId
+		}
+		if *deny != 0 {
+			perm.deniedBy[i] = perm.raw.GroupId
+		}
+	}
 	p.Add(perm.raw.Domain, perm)
 	return nil
 }
 
+// Inherit copies permissions of the parent that aren't defined in p yet. Inheriting from the
+// nearest ancestor first makes the nearest definition of grant bits win. Deny bits are kept
+// from every level, so a deny can't be lifted by a descendant
+func (p *Perm) Inherit(parent *Perm) {
+	if parent == nil {
+		return
+	}
+	for _, domain := range Domains {
+		for _, permission := range parent.Get(domain) {
+			if existing := p.lookup(domain, permission.Name); existing != nil {
+				existing.unite(permission, false)
+				continue
+			}
+			p.Add(domain, *permission)
+		}
+	}
+}
+
+// Has reports whether the permission is defined in the domain
+func (p *Perm) Has(domain string, permission string) bool {
+	return p.lookup(domain, permission) != nil
+}
+
+// Merge unites permissions of other with p: an access bit is granted when either of them grants it
+// and denied when either of them denies it. Deny overrides grant when the permission is checked
+func (p *Perm) Merge(other *Perm) {
+	if other == nil {
+		return
+	}
+	for _, domain := range Domains {
+		for _, permission := range other.Get(domain) {
+			if existing := p.lookup(domain, permission.Name); existing != nil {
+				existing.unite(permission, true)
+				continue
+			}
+			p.Add(domain, *permission)
+		}
+	}
+}
+
+func (p *Perm) lookup(domain string, permission string) *Permission {
+	switch domain {
+	case DomainOwn:
+		return p.own[permission]
+	case DomainParty:
+		return p.party[permission]
+	case DomainGuild:
+		return p.guild[permission]
+	case DomainGlobal:
+		return p.global[permission]
+	}
+	return nil
+}
+
+// add stores the permission in the domain map and array. A permission added again replaces the previous one
+func add(index map[string]*Permission, list []*Permission, perm Permission) []*Permission {
+	if existing, ok := index[perm.Name]; ok {
+		*existing = perm
+		return list
+	}
+	index[perm.Name] = &perm
+	return append(list, &perm)
+}
+
+// get resolves the permission from the exact name and every wildcard covering it. A deny of any of them
+// denies the bit, since they may come from different groups of the user or from an ancestor and a deny can't
+// be lifted by a more specific grant. Grants come from the most specific match granting any bit, so an exact
+// entry narrows a wildcard: bits it leaves off aren't granted by inventory.* either. Entries that only deny
+// don't take part in grants. Returns an empty permission that allows nothing when nothing matches. Costs one
+// map lookup per namespace level
+func get(index map[string]*Permission, permission string) *Permission {
+	var result *Permission
+	granted := false
+	for _, key := range candidates(permission) {
+		perm, ok := index[key]
+		if !ok {
+			continue
+		}
+		if result == nil {
+			result = &Permission{Name: permission, Domain: perm.Domain, raw: perm.raw}
+		}
+		takeGrants := !granted && perm.grants()
+		granted = granted || takeGrants
+		for i, bit := range accessBits {
+			grant, deny := perm.bits(bit)
+			resultGrant, resultDeny := result.bits(bit)
+			if takeGrants {
+				*resultGrant = *grant
+				result.grantedBy[i] = perm.grantedBy[i]
+			}
+			if *deny != 0 && *resultDeny == 0 {
+				*resultDeny = 1
+				result.deniedBy[i] = perm.deniedBy[i]
+			}
+		}
+	}
+
+	if result == nil {
+		return &Permission{}
+	}
+	return result
+}
+
 func (p *Perm) AddOwn(perm Permission) {
-	p.own[perm.Name] = perm
-	p.ownArray = append(p.ownArray, &perm)
+	p.ownArray = add(p.own, p.ownArray, perm)
 }
 
 func (p *Perm) AddParty(perm Permission) {
-	p.party[perm.Name] = perm
-	p.partyArray = append(p.partyArray, &perm)
+	p.partyArray = add(p.party, p.partyArray, perm)
 }
 
 func (p *Perm) AddGuild(perm Permission) {
-	p.guild[perm.Name] = perm
-	p.guildArray = append(p.guildArray, &perm)
+	p.guildArray = add(p.guild, p.guildArray, perm)
 }
 
 func (p *Perm) AddGlobal(perm Permission) {
-	p.global[perm.Name] = perm
-	p.globalArray = append(p.globalArray, &perm)
+	p.globalArray = add(p.global, p.globalArray, perm)
 }
 
 func (p *Perm) Get(domain string) []*Permission {
@@ -157,35 +485,19 @@ func (p *Perm) GetPermission(domain string, permission string) *Permission {
 }
 
 func (p *Perm) GetPermOwn(permission string) *Permission {
-	perm, ok := p.own[permission]
-	if !ok {
-		return &Permission{}
-	}
-	return &perm
+	return get(p.own, permission)
 }
 
 func (p *Perm) GetPermParty(permission string) *Permission {
-	perm, ok := p.party[permission]
-	if !ok {
-		return &Permission{}
-	}
-	return &perm
+	return get(p.party, permission)
 }
 
 func (p *Perm) GetPermGuild(permission string) *Permission {
-	perm, ok := p.guild[permission]
-	if !ok {
-		return &Permission{}
-	}
-	return &perm
+	return get(p.guild, permission)
 }
 
 func (p *Perm) GetPermGlobal(permission string) *Permission {
-	perm, ok := p.global[permission]
-	if !ok {
-		return &Permission{}
-	}
-	return &perm
+	return get(p.global, permission)
 }
 
 func (p *Perm) Count() int {
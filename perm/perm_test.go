@@ -0,0 +1,355 @@
+package perm
+
+import (
+	"github.com/savageking-io/ogbuser/schema"
+	"reflect"
+	"testing"
+)
+
+func newTestPerm(t *testing.T, permissions ...schema.GroupPermissionSchema) *Perm {
+	t.Helper()
+	p := NewPerm()
+	for _, permission := range permissions {
+		if err := p.Populate(&permission); err != nil {
+			t.Fatalf("Populate() error = %v", err)
+		}
+	}
+	return p
+}
+
+func TestPerm_Inherit(t *testing.T) {
+	admins := []schema.GroupPermissionSchema{
+		{GroupId: 3, Permission: "manage_users", Read: true, Domain: DomainGlobal},
+	}
+	moderators := []schema.GroupPermissionSchema{
+		{GroupId: 2, Permission: "manage_users", Read: true, Write: true, Delete: true, Domain: DomainGlobal},
+		{GroupId: 2, Permission: "moderate_content", Read: true, Write: true, Domain: DomainGlobal},
+		{GroupId: 2, Permission: "manage_users", Read: true, Domain: DomainOwn},
+	}
+
+	tests := []struct {
+		name       string
+		domain     string
+		permission string
+		wantGroup  int32
+		wantAccess Access
+		wantDenied Access
+	}{
+		{"Nearest definition wins", DomainGlobal, "manage_users", 3, AccessRead, AccessWrite | AccessDelete},
+		{"Inherited from parent", DomainGlobal, "moderate_content", 2, AccessRead | AccessWrite, AccessDelete},
+		{"Domains are separate", DomainOwn, "manage_users", 2, AccessRead, AccessWrite},
+		{"Not defined anywhere", DomainGlobal, "manage_content", 0, 0, AccessRead},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := newTestPerm(t, admins...)
+			p.Inherit(newTestPerm(t, moderators...))
+			p.Inherit(nil)
+
+			got := p.GetPermission(tt.domain, tt.permission)
+			if got.GetGroupId() != tt.wantGroup {
+				t.Errorf("GetGroupId() = %d, want %d", got.GetGroupId(), tt.wantGroup)
+			}
+			if !got.Allows(tt.wantAccess) {
+				t.Errorf("Allows(%d) = false, want true", tt.wantAccess)
+			}
+			if got.Allows(tt.wantDenied) {
+				t.Errorf("Allows(%d) = true, want false", tt.wantDenied)
+			}
+		})
+	}
+
+	p := newTestPerm(t, admins...)
+	p.Inherit(newTestPerm(t, moderators...))
+	if p.Count() != 3 {
+		t.Errorf("Count() = %d, want 3", p.Count())
+	}
+}
+
+func TestPerm_Merge(t *testing.T) {
+	players := []schema.GroupPermissionSchema{
+		{GroupId: 2, Permission: "view_content", Read: true, Domain: DomainGlobal},
+		{GroupId: 2, Permission: "moderate_content", Read: true, Domain: DomainGlobal},
+	}
+	moderators := []schema.GroupPermissionSchema{
+		{GroupId: 3, Permission: "moderate_content", Write: true, Domain: DomainGlobal},
+		{GroupId: 3, Permission: "manage_users", Read: true, Domain: DomainOwn},
+	}
+
+	tests := []struct {
+		name       string
+		domain     string
+		permission string
+		wantAccess Access
+		wantDenied Access
+	}{
+		{"Only in first", DomainGlobal, "view_content", AccessRead, AccessWrite},
+		{"Bits are united", DomainGlobal, "moderate_content", AccessRead | AccessWrite, AccessDelete},
+		{"Only in second", DomainOwn, "manage_users", AccessRead, AccessDelete},
+		{"Other domain is untouched", DomainGlobal, "manage_users", 0, AccessRead},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPerm()
+			p.Merge(newTestPerm(t, players...))
+			p.Merge(newTestPerm(t, moderators...))
+			p.Merge(nil)
+
+			got := p.GetPermission(tt.domain, tt.permission)
+			if !got.Allows(tt.wantAccess) {
+				t.Errorf("Allows(%d) = false, want true", tt.wantAccess)
+			}
+			if got.Allows(tt.wantDenied) {
+				t.Errorf("Allows(%d) = true, want false", tt.wantDenied)
+			}
+		})
+	}
+
+	source := newTestPerm(t, players...)
+	p := NewPerm()
+	p.Merge(source)
+	p.Merge(newTestPerm(t, moderators...))
+	if source.GetPermGlobal("moderate_content").Allows(AccessWrite) {
+		t.Errorf("Merge() modified the merged Perm")
+	}
+}
+
+func TestPermission_Decide(t *testing.T) {
+	players := []schema.GroupPermissionSchema{
+		{GroupId: 2, Permission: "chat", Read: true, Write: true, Domain: DomainGlobal},
+		{GroupId: 2, Permission: "forum", Read: true, Write: true, Domain: DomainGlobal},
+	}
+	muted := []schema.GroupPermissionSchema{
+		{GroupId: 7, Permission: "chat", DenyWrite: true, Domain: DomainGlobal},
+	}
+	quiet := []schema.GroupPermissionSchema{
+		{GroupId: 8, Permission: "forum", Write: true, DenyDelete: true, Domain: DomainGlobal},
+	}
+
+	tests := []struct {
+		name       string
+		permission string
+		access     Access
+		want       Decision
+	}{
+		{"Granted", "chat", AccessRead, Decision{Allowed: true, Access: AccessRead, GroupId: 2, Rule: RuleGrant}},
+		{"Deny overrides grant of another group", "chat", AccessWrite, Decision{Allowed: false, Access: AccessWrite, GroupId: 7, Rule: RuleDeny}},
+		{"Deny on one of requested bits", "chat", AccessRead | AccessWrite, Decision{Allowed: false, Access: AccessWrite, GroupId: 7, Rule: RuleDeny}},
+		{"Nothing grants", "chat", AccessDelete, Decision{Allowed: false, Access: AccessDelete, Rule: RuleNone}},
+		{"Deny without grant", "forum", AccessDelete, Decision{Allowed: false, Access: AccessDelete, GroupId: 8, Rule: RuleDeny}},
+		{"Grant by first group", "forum", AccessRead | AccessWrite, Decision{Allowed: true, Access: AccessRead | AccessWrite, GroupId: 2, Rule: RuleGrant}},
+		{"Unknown permission", "trade", AccessRead, Decision{Allowed: false, Access: AccessRead, Rule: RuleNone}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPerm()
+			p.Merge(newTestPerm(t, players...))
+			p.Merge(newTestPerm(t, muted...))
+			p.Merge(newTestPerm(t, quiet...))
+
+			if got := p.GetPermission(DomainGlobal, tt.permission).Decide(tt.access); got != tt.want {
+				t.Errorf("Decide() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSuperuserPermission(t *testing.T) {
+	tests := []struct {
+		name   string
+		access Access
+		want   Decision
+	}{
+		{"Read", AccessRead, Decision{Allowed: true, Access: AccessRead, GroupId: 1, Rule: RuleSuperuser}},
+		{"All bits", AccessRead | AccessWrite | AccessDelete, Decision{Allowed: true, Access: AccessRead | AccessWrite | AccessDelete, GroupId: 1, Rule: RuleSuperuser}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewSuperuserPermission("chat", DomainGlobal, 1)
+			if !p.IsBypass() {
+				t.Errorf("IsBypass() = false, want true")
+			}
+			if got := p.Decide(tt.access); got != tt.want {
+				t.Errorf("Decide() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPerm_InheritDeny(t *testing.T) {
+	child := newTestPerm(t, schema.GroupPermissionSchema{GroupId: 3, Permission: "chat", Read: true, Write: true, Domain: DomainGlobal})
+	child.Inherit(newTestPerm(t, schema.GroupPermissionSchema{GroupId: 2, Permission: "chat", DenyWrite: true, Domain: DomainGlobal}))
+
+	got := child.GetPermGlobal("chat")
+	if !got.Allows(AccessRead) {
+		t.Errorf("Allows(AccessRead) = false, want true")
+	}
+	if decision := got.Decide(AccessWrite); decision.Allowed || decision.GroupId != 2 {
+		t.Errorf("Decide(AccessWrite) = %+v, want deny by group 2", decision)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		permission string
+		domain     string
+		wantErr    bool
+	}{
+		{"Valid", "manage_users", DomainGlobal, false},
+		{"Empty name", "", DomainGlobal, true},
+		{"Uppercase", "Manage_users", DomainGlobal, true},
+		{"Starts with digit", "1users", DomainOwn, true},
+		{"Spaces", "manage users", DomainOwn, true},
+		{"Namespaced", "inventory.items.trade", DomainGlobal, false},
+		{"Wildcard is not a permission", "inventory.*", DomainGlobal, true},
+		{"Unknown domain", "manage_users", "world", true},
+		{"Empty domain", "manage_users", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.permission, tt.domain); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_candidates(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"manage_users", []string{"manage_users", "*"}},
+		{"inventory.items.trade", []string{"inventory.items.trade", "inventory.items.*", "inventory.*", "*"}},
+		{"*", []string{"*"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := candidates(tt.name); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("candidates() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPerm_GetPermissionWildcard(t *testing.T) {
+	p := newTestPerm(t,
+		schema.GroupPermissionSchema{GroupId: 1, Permission: "*", Read: true, Domain: DomainGlobal},
+		schema.GroupPermissionSchema{GroupId: 2, Permission: "inventory.*", Read: true, Write: true, Delete: true, Domain: DomainGlobal},
+		schema.GroupPermissionSchema{GroupId: 3, Permission: "inventory.items.*", DenyDelete: true, Domain: DomainGlobal},
+		schema.GroupPermissionSchema{GroupId: 4, Permission: "inventory.items.trade", Read: true, Domain: DomainGlobal},
+	)
+
+	tests := []struct {
+		name       string
+		permission string
+		access     Access
+		want       Decision
+	}{
+		{"Exact match", "inventory.items.trade", AccessRead, Decision{Allowed: true, Access: AccessRead, GroupId: 4, Rule: RuleGrant}},
+		{"Bit missing in exact isn't granted by namespace", "inventory.items.trade", AccessWrite, Decision{Allowed: false, Access: AccessWrite, Rule: RuleNone}},
+		{"More specific deny wins", "inventory.items.trade", AccessDelete, Decision{Allowed: false, Access: AccessDelete, GroupId: 3, Rule: RuleDeny}},
+		{"Namespace wildcard", "inventory.gold", AccessDelete, Decision{Allowed: true, Access: AccessDelete, GroupId: 2, Rule: RuleGrant}},
+		{"Global wildcard", "chat.send", AccessRead, Decision{Allowed: true, Access: AccessRead, GroupId: 1, Rule: RuleGrant}},
+		{"Global wildcard doesn't grant write", "chat.send", AccessWrite, Decision{Allowed: false, Access: AccessWrite, Rule: RuleNone}},
+		{"Prefix is not a namespace", "inventoryx.items", AccessWrite, Decision{Allowed: false, Access: AccessWrite, Rule: RuleNone}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.GetPermission(DomainGlobal, tt.permission).Decide(tt.access); got != tt.want {
+				t.Errorf("Decide() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if got := p.GetPermission(DomainOwn, "inventory.gold"); got.Allows(AccessRead) {
+		t.Errorf("wildcard of global domain applied to own")
+	}
+}
+
+func TestPerm_GetPermissionMostSpecificGrant(t *testing.T) {
+	admins := newTestPerm(t, schema.GroupPermissionSchema{GroupId: 1, Permission: "inventory.*", Read: true, Write: true, Delete: true, Domain: DomainGlobal})
+	traders := newTestPerm(t,
+		schema.GroupPermissionSchema{GroupId: 2, Permission: "inventory.items.trade", Read: true, Domain: DomainGlobal},
+		schema.GroupPermissionSchema{GroupId: 2, Permission: "inventory.items.*", DenyDelete: true, Domain: DomainGlobal},
+	)
+	merged := NewPerm()
+	merged.Merge(admins)
+	merged.Merge(traders)
+
+	tests := []struct {
+		name       string
+		permission string
+		access     Access
+		want       Decision
+	}{
+		{"Exact entry grants its bits", "inventory.items.trade", AccessRead, Decision{Allowed: true, Access: AccessRead, GroupId: 2, Rule: RuleGrant}},
+		{"Exact entry narrows wildcard", "inventory.items.trade", AccessWrite, Decision{Allowed: false, Access: AccessWrite, Rule: RuleNone}},
+		{"Deny-only entry doesn't narrow", "inventory.items.sell", AccessWrite, Decision{Allowed: true, Access: AccessWrite, GroupId: 1, Rule: RuleGrant}},
+		{"Deny-only entry still denies", "inventory.items.sell", AccessDelete, Decision{Allowed: false, Access: AccessDelete, GroupId: 2, Rule: RuleDeny}},
+		{"Wildcard without a more specific entry", "inventory.gold", AccessDelete, Decision{Allowed: true, Access: AccessDelete, GroupId: 1, Rule: RuleGrant}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := merged.GetPermission(DomainGlobal, tt.permission).Decide(tt.access); got != tt.want {
+				t.Errorf("Decide() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPerm_GetPermissionWildcardDenyAcrossGroups(t *testing.T) {
+	muted := newTestPerm(t, schema.GroupPermissionSchema{GroupId: 1, Permission: "chat.*", DenyRead: true, Domain: DomainGlobal})
+	players := newTestPerm(t, schema.GroupPermissionSchema{GroupId: 2, Permission: "chat.send", Read: true, Write: true, Domain: DomainGlobal})
+
+	merged := NewPerm()
+	merged.Merge(players)
+	merged.Merge(muted)
+
+	// Muted inherits from Players, so the exact grant is its own and the wildcard deny comes from the parent
+	child := newTestPerm(t, schema.GroupPermissionSchema{GroupId: 3, Permission: "chat.send", Read: true, Domain: DomainGlobal})
+	child.Inherit(muted)
+
+	tests := []struct {
+		name   string
+		perm   *Perm
+		access Access
+		want   Decision
+	}{
+		{"Wildcard deny of another group", merged, AccessRead, Decision{Allowed: false, Access: AccessRead, GroupId: 1, Rule: RuleDeny}},
+		{"Bits the deny doesn't cover", merged, AccessWrite, Decision{Allowed: true, Access: AccessWrite, GroupId: 2, Rule: RuleGrant}},
+		{"Wildcard deny of an ancestor", child, AccessRead, Decision{Allowed: false, Access: AccessRead, GroupId: 1, Rule: RuleDeny}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.perm.GetPermission(DomainGlobal, "chat.send").Decide(tt.access); got != tt.want {
+				t.Errorf("Decide() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateGrant(t *testing.T) {
+	tests := []struct {
+		name    string
+		grant   string
+		wantErr bool
+	}{
+		{"Flat", "manage_users", false},
+		{"Namespaced", "inventory.items.trade", false},
+		{"Namespace wildcard", "inventory.*", false},
+		{"Everything", "*", false},
+		{"Wildcard in the middle", "inventory.*.trade", true},
+		{"Partial wildcard", "inventory.it*", true},
+		{"Empty segment", "inventory..trade", true},
+		{"Trailing dot", "inventory.", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateGrant(tt.grant, DomainGlobal); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateGrant() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
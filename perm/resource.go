@@ -0,0 +1,157 @@
+package perm
+
+import (
+	"context"
+	"fmt"
+)
+
+// Resource identifies what a permission is checked against. Zero ids are not set
+type Resource struct {
+	OwnerId int32
+	PartyId int32
+	GuildId int32
+}
+
+// IsSet reports whether any of the resource ids is set
+func (r Resource) IsSet() bool {
+	return r.OwnerId != 0 || r.PartyId != 0 || r.GuildId != 0
+}
+
+// MembershipResolver tells whether a user belongs to a party or a guild. ogbuser doesn't know
+// about parties and guilds, the resolver is backed by services that own them
+type MembershipResolver interface {
+	IsPartyMember(ctx context.Context, userId, partyId int32) (bool, error)
+	IsGuildMember(ctx context.Context, userId, guildId int32) (bool, error)
+}
+
+// ApplicableDomains returns domains in which the user is related to the resource. Global always applies,
+// own applies to the owner of the resource, party and guild apply to members. Without a resolver party
+// and guild never apply
+func ApplicableDomains(ctx context.Context, userId int32, resource Resource, resolver MembershipResolver) ([]string, error) {
+	domains := []string{DomainGlobal}
+
+	if resource.OwnerId != 0 && resource.OwnerId == userId {
+		domains = append(domains, DomainOwn)
+	}
+
+	if resolver == nil {
+		return domains, nil
+	}
+
+	if resource.PartyId != 0 {
+		member, err := resolver.IsPartyMember(ctx, userId, resource.PartyId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve party %d: %w", resource.PartyId, err)
+		}
+		if member {
+			domains = append(domains, DomainParty)
+		}
+	}
+
+	if resource.GuildId != 0 {
+		member, err := resolver.IsGuildMember(ctx, userId, resource.GuildId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve guild %d: %w", resource.GuildId, err)
+		}
+		if member {
+			domains = append(domains, DomainGuild)
+		}
+	}
+
+	return domains, nil
+}
+
+// Combine unites the permission over the domains: a bit is granted when any domain grants it
+// and denied when any domain denies it
+func (p *Perm) Combine(permission string, domains []string) *Permission {
+	result := &Permission{Name: permission}
+	for _, domain := range domains {
+		result.unite(p.GetPermission(domain, permission), true)
+	}
+	return result
+}
+
+type membershipKey struct {
+	userId int32
+	id     int32
+}
+
+// CachingResolver remembers answers of another resolver. It is meant to live for a single request,
+// so one batch of checks asks about every party or guild only once. Not safe for concurrent use
+type CachingResolver struct {
+	resolver MembershipResolver
+	parties  map[membershipKey]bool
+	guilds   map[membershipKey]bool
+}
+
+// NewCachingResolver wraps the resolver. Returns nil when resolver is nil, so party and guild
+// domains keep not applying
+func NewCachingResolver(resolver MembershipResolver) MembershipResolver {
+	if resolver == nil {
+		return nil
+	}
+	return &CachingResolver{
+		resolver: resolver,
+		parties:  make(map[membershipKey]bool),
+		guilds:   make(map[membershipKey]bool),
+	}
+}
+
+func (r *CachingResolver) IsPartyMember(ctx context.Context, userId, partyId int32) (bool, error) {
+	key := membershipKey{userId, partyId}
+	if member, ok := r.parties[key]; ok {
+		return member, nil
+	}
+	member, err := r.resolver.IsPartyMember(ctx, userId, partyId)
+	if err != nil {
+		return false, err
+	}
+	r.parties[key] = member
+	return member, nil
+}
+
+func (r *CachingResolver) IsGuildMember(ctx context.Context, userId, guildId int32) (bool, error) {
+	key := membershipKey{userId, guildId}
+	if member, ok := r.guilds[key]; ok {
+		return member, nil
+	}
+	member, err := r.resolver.IsGuildMember(ctx, userId, guildId)
+	if err != nil {
+		return false, err
+	}
+	r.guilds[key] = member
+	return member, nil
+}
+
+// ValidateTarget checks what a permission is checked against. Resource ids can be checked in any domain the
+// user is related to the resource in, without them only global can be looked up: own, party and guild need
+// the resource to compare the user with
+func ValidateTarget(domain string, resource Resource) error {
+	if resource.IsSet() {
+		return nil
+	}
+	if !IsDomain(domain) {
+		return fmt.Errorf("%w: unknown domain %q", ErrInvalidPermission, domain)
+	}
+	if domain != DomainGlobal {
+		return fmt.Errorf("%w: domain %q requires resource ids", ErrInvalidPermission, domain)
+	}
+	return nil
+}
+
+// Check evaluates the permission of the user. When the resource is set, every domain in which the user is
+// related to it is combined and domain is ignored, otherwise only global domain can be looked up
+func (p *Perm) Check(ctx context.Context, userId int32, permission, domain string, resource Resource, resolver MembershipResolver) (*Permission, error) {
+	if err := ValidateTarget(domain, resource); err != nil {
+		return nil, err
+	}
+	if !resource.IsSet() {
+		return p.GetPermission(domain, permission), nil
+	}
+
+	domains, err := ApplicableDomains(ctx, userId, resource, resolver)
+	if err != nil {
+		return nil, err
+	}
+	return p.Combine(permission, domains), nil
+}
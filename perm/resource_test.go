@@ -0,0 +1,185 @@
+package perm
+
+import (
+	"context"
+	"errors"
+	"github.com/savageking-io/ogbuser/schema"
+	"reflect"
+	"testing"
+)
+
+type testResolver struct {
+	parties map[int32][]int32
+	guilds  map[int32][]int32
+	err     error
+}
+
+func (r *testResolver) IsPartyMember(ctx context.Context, userId, partyId int32) (bool, error) {
+	return contains(r.parties[partyId], userId), r.err
+}
+
+func (r *testResolver) IsGuildMember(ctx context.Context, userId, guildId int32) (bool, error) {
+	return contains(r.guilds[guildId], userId), r.err
+}
+
+func contains(ids []int32, id int32) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestApplicableDomains(t *testing.T) {
+	resolver := &testResolver{
+		parties: map[int32][]int32{10: {1, 2}},
+		guilds:  map[int32][]int32{20: {1}},
+	}
+
+	tests := []struct {
+		name     string
+		userId   int32
+		resource Resource
+		resolver MembershipResolver
+		want     []string
+		wantErr  bool
+	}{
+		{"No resource", 1, Resource{}, resolver, []string{DomainGlobal}, false},
+		{"Owner", 1, Resource{OwnerId: 1}, resolver, []string{DomainGlobal, DomainOwn}, false},
+		{"Not owner", 2, Resource{OwnerId: 1}, resolver, []string{DomainGlobal}, false},
+		{"Party and guild member", 1, Resource{OwnerId: 3, PartyId: 10, GuildId: 20}, resolver, []string{DomainGlobal, DomainParty, DomainGuild}, false},
+		{"Party member only", 2, Resource{PartyId: 10, GuildId: 20}, resolver, []string{DomainGlobal, DomainParty}, false},
+		{"No resolver", 1, Resource{OwnerId: 1, PartyId: 10}, nil, []string{DomainGlobal, DomainOwn}, false},
+		{"Resolver failed", 1, Resource{PartyId: 10}, &testResolver{err: errors.New("unavailable")}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ApplicableDomains(context.Background(), tt.userId, tt.resource, tt.resolver)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplicableDomains() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ApplicableDomains() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPerm_Combine(t *testing.T) {
+	p := newTestPerm(t,
+		schema.GroupPermissionSchema{GroupId: 2, Permission: "inventory", Read: true, Domain: DomainGlobal},
+		schema.GroupPermissionSchema{GroupId: 2, Permission: "inventory", Write: true, Delete: true, Domain: DomainOwn},
+		schema.GroupPermissionSchema{GroupId: 2, Permission: "inventory", DenyDelete: true, Domain: DomainParty},
+	)
+
+	tests := []struct {
+		name       string
+		domains    []string
+		wantAccess Access
+		wantDenied Access
+	}{
+		{"Global only", []string{DomainGlobal}, AccessRead, AccessWrite},
+		{"Owner", []string{DomainGlobal, DomainOwn}, AccessRead | AccessWrite | AccessDelete, 0},
+		{"Owner in party", []string{DomainGlobal, DomainOwn, DomainParty}, AccessRead | AccessWrite, AccessDelete},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := p.Combine("inventory", tt.domains)
+			if !got.Allows(tt.wantAccess) {
+				t.Errorf("Allows(%d) = false, want true", tt.wantAccess)
+			}
+			if tt.wantDenied != 0 && got.Allows(tt.wantDenied) {
+				t.Errorf("Allows(%d) = true, want false", tt.wantDenied)
+			}
+		})
+	}
+}
+
+func TestPerm_Check(t *testing.T) {
+	p := newTestPerm(t,
+		schema.GroupPermissionSchema{GroupId: 2, Permission: "inventory", Read: true, Domain: DomainGlobal},
+		schema.GroupPermissionSchema{GroupId: 2, Permission: "inventory", Write: true, Domain: DomainOwn},
+	)
+
+	tests := []struct {
+		name      string
+		domain    string
+		resource  Resource
+		wantWrite bool
+		wantErr   bool
+	}{
+		{"Global without resource", DomainGlobal, Resource{}, false, false},
+		{"Own without resource", DomainOwn, Resource{}, false, true},
+		{"Party without resource", DomainParty, Resource{}, false, true},
+		{"Guild without resource", DomainGuild, Resource{}, false, true},
+		{"Unknown domain", "world", Resource{}, false, true},
+		{"Owned resource", DomainOwn, Resource{OwnerId: 1}, true, false},
+		{"Resource of another user", DomainOwn, Resource{OwnerId: 2}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := p.Check(context.Background(), 1, "inventory", tt.domain, tt.resource, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Check() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if !errors.Is(err, ErrInvalidPermission) {
+					t.Errorf("Check() error = %v, want ErrInvalidPermission", err)
+				}
+				return
+			}
+			if !got.Allows(AccessRead) || got.Allows(AccessWrite) != tt.wantWrite {
+				t.Errorf("Check() allows read %v, write %v, want true and %v", got.Allows(AccessRead), got.Allows(AccessWrite), tt.wantWrite)
+			}
+		})
+	}
+}
+
+type countingResolver struct {
+	testResolver
+	calls int
+}
+
+func (r *countingResolver) IsPartyMember(ctx context.Context, userId, partyId int32) (bool, error) {
+	r.calls++
+	return r.testResolver.IsPartyMember(ctx, userId, partyId)
+}
+
+func (r *countingResolver) IsGuildMember(ctx context.Context, userId, guildId int32) (bool, error) {
+	r.calls++
+	return r.testResolver.IsGuildMember(ctx, userId, guildId)
+}
+
+func TestCachingResolver(t *testing.T) {
+	if NewCachingResolver(nil) != nil {
+		t.Fatalf("NewCachingResolver(nil) != nil")
+	}
+
+	counting := &countingResolver{testResolver: testResolver{
+		parties: map[int32][]int32{10: {1}},
+		guilds:  map[int32][]int32{20: {2}},
+	}}
+	resolver := NewCachingResolver(counting)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if member, _ := resolver.IsPartyMember(ctx, 1, 10); !member {
+			t.Errorf("IsPartyMember(1, 10) = false, want true")
+		}
+		if member, _ := resolver.IsPartyMember(ctx, 2, 10); member {
+			t.Errorf("IsPartyMember(2, 10) = true, want false")
+		}
+		if member, _ := resolver.IsGuildMember(ctx, 2, 20); !member {
+			t.Errorf("IsGuildMember(2, 20) = false, want true")
+		}
+	}
+	if counting.calls != 3 {
+		t.Errorf("resolver called %d times, want 3", counting.calls)
+	}
+
+	failing := NewCachingResolver(&countingResolver{testResolver: testResolver{err: errors.New("unavailable")}})
+	if _, err := failing.IsGuildMember(ctx, 1, 20); err == nil {
+		t.Errorf("IsGuildMember() error = nil, want error")
+	}
+}
@@ -0,0 +1,345 @@
+// Package policy reads a declarative file of groups and permissions and compares it to the database
+package policy
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/savageking-io/ogbuser/perm"
+	"github.com/savageking-io/ogbuser/schema"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidPolicy = errors.New("invalid policy")
+
+const MaxGroupNameLength = 100
+
+// Policy declares the desired state of groups. Groups that are not declared are left alone unless pruned
+type Policy struct {
+	Groups []Group `yaml:"groups" json:"groups"`
+}
+
+type Group struct {
+	Name        string       `yaml:"name" json:"name"`
+	Parent      string       `yaml:"parent" json:"parent"`
+	Description string       `yaml:"description" json:"description"`
+	IsSpecial   bool         `yaml:"is_special" json:"is_special"`
+	Permissions []Permission `yaml:"permissions" json:"permissions"`
+}
+
+// Permission is a grant of the group. Grant and Deny list access names: read, write and delete
+type Permission struct {
+	Name   string   `yaml:"name" json:"name"`
+	Domain string   `yaml:"domain" json:"domain"` // Defaults to global
+	Grant  []string `yaml:"grant" json:"grant"`
+	Deny   []string `yaml:"deny" json:"deny"`
+}
+
+// Load reads and validates a policy file. Files with .json extension are read as JSON, anything else as YAML
+func Load(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data, strings.EqualFold(filepath.Ext(path), ".json"))
+}
+
+// Parse decodes and validates a policy. Unknown fields are rejected so typos don't silently drop rules
+func Parse(data []byte, isJSON bool) (*Policy, error) {
+	p := &Policy{}
+	if isJSON {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(p); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPolicy, err.Error())
+		}
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(p); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidPolicy, err.Error())
+		}
+	}
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate normalizes names and domains and checks everything that doesn't depend on the database
+func (p *Policy) Validate() error {
+	names := make(map[string]struct{}, len(p.Groups))
+	for i := range p.Groups {
+		g := &p.Groups[i]
+		g.Name = strings.TrimSpace(g.Name)
+		g.Parent = strings.TrimSpace(g.Parent)
+		if g.Name == "" || len(g.Name) > MaxGroupNameLength {
+			return fmt.Errorf("%w: group #%d: name must be 1 to %d characters", ErrInvalidPolicy, i+1, MaxGroupNameLength)
+		}
+		if _, ok := names[g.Name]; ok {
+			return fmt.Errorf("%w: group %q is declared twice", ErrInvalidPolicy, g.Name)
+		}
+		names[g.Name] = struct{}{}
+		if g.Parent == g.Name {
+			return fmt.Errorf("%w: group %q can't be its own parent", ErrInvalidPolicy, g.Name)
+		}
+
+		grants := make(map[string]struct{}, len(g.Permissions))
+		for j := range g.Permissions {
+			permission := &g.Permissions[j]
+			if permission.Domain == "" {
+				permission.Domain = perm.DomainGlobal
+			}
+			if err := perm.ValidateGrant(permission.Name, permission.Domain); err != nil {
+				return fmt.Errorf("%w: group %q: %s", ErrInvalidPolicy, g.Name, err.Error())
+			}
+			key := permission.Domain + "/" + permission.Name
+			if _, ok := grants[key]; ok {
+				return fmt.Errorf("%w: group %q declares %s twice", ErrInvalidPolicy, g.Name, key)
+			}
+			grants[key] = struct{}{}
+			if _, err := parseAccess(permission.Grant); err != nil {
+				return fmt.Errorf("%w: group %q permission %s: %s", ErrInvalidPolicy, g.Name, key, err.Error())
+			}
+			if _, err := parseAccess(permission.Deny); err != nil {
+				return fmt.Errorf("%w: group %q permission %s: %s", ErrInvalidPolicy, g.Name, key, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// Diff returns changes that bring current groups to the declared state. Permissions of declared groups
+// that are not in the policy are removed. With prune, groups that are not declared are deleted, children
+// first. Special groups are never pruned: they have to be declared or made regular first
+func Diff(p *Policy, current []schema.GroupSchema, prune bool) ([]schema.PolicyChangeSchema, error) {
+	existing := make(map[string]*schema.GroupSchema, len(current))
+	names := make(map[int32]string, len(current))
+	for i := range current {
+		existing[current[i].Name] = &current[i]
+		names[current[i].Id] = current[i].Name
+	}
+	declared := make(map[string]*Group, len(p.Groups))
+	for i := range p.Groups {
+		declared[p.Groups[i].Name] = &p.Groups[i]
+	}
+
+	// parents holds the resulting tree, including groups the policy doesn't manage
+	parents := make(map[string]string, len(current)+len(p.Groups))
+	for _, g := range current {
+		if _, ok := declared[g.Name]; !ok && !prune {
+			parents[g.Name] = names[g.ParentId]
+		}
+	}
+	for _, g := range p.Groups {
+		parents[g.Name] = g.Parent
+	}
+	for _, g := range p.Groups {
+		if _, ok := parents[g.Parent]; g.Parent != "" && !ok {
+			return nil, fmt.Errorf("%w: parent %q of group %q is neither declared nor kept", ErrInvalidPolicy, g.Parent, g.Name)
+		}
+		seen := map[string]struct{}{g.Name: {}}
+		for parent := g.Parent; parent != ""; parent = parents[parent] {
+			if _, ok := seen[parent]; ok {
+				return nil, fmt.Errorf("%w: group %q is part of a parent cycle", ErrInvalidPolicy, g.Name)
+			}
+			seen[parent] = struct{}{}
+		}
+	}
+
+	var groups, permissions, deletions []schema.PolicyChangeSchema
+	for _, g := range p.Groups {
+		var description *string
+		if g.Description != "" {
+			description = &g.Description
+		}
+		desired := schema.GroupSchema{Name: g.Name, Description: description, IsSpecial: g.IsSpecial}
+		change := schema.PolicyChangeSchema{GroupName: g.Name, ParentName: g.Parent, Group: desired}
+
+		var owned []schema.GroupPermissionSchema
+		if current, ok := existing[g.Name]; !ok {
+			change.Kind = schema.PolicyCreateGroup
+			change.Summary = fmt.Sprintf("+ group %q%s", g.Name, describeGroup(g.Parent, description, g.IsSpecial))
+			groups = append(groups, change)
+		} else {
+			owned = current.Permissions
+			if names[current.ParentId] != g.Parent || current.IsSpecial != g.IsSpecial ||
+				stringValue(current.Description) != g.Description {
+				change.Kind = schema.PolicyUpdateGroup
+				change.Summary = fmt.Sprintf("~ group %q%s (was%s)", g.Name, describeGroup(g.Parent, description, g.IsSpecial),
+					describeGroup(names[current.ParentId], current.Description, current.IsSpecial))
+				groups = append(groups, change)
+			}
+		}
+
+		wanted := make(map[string]struct{}, len(g.Permissions))
+		for _, permission := range g.Permissions {
+			desired := toSchema(permission)
+			key := desired.Domain + "/" + desired.Permission
+			wanted[key] = struct{}{}
+
+			var previous *schema.GroupPermissionSchema
+			for i := range owned {
+				if owned[i].Domain == desired.Domain && owned[i].Permission == desired.Permission {
+					previous = &owned[i]
+					break
+				}
+			}
+			if previous != nil && sameAccess(previous, &desired) {
+				continue
+			}
+			summary := fmt.Sprintf("+ permission %s of %q:%s", key, g.Name, describeAccess(&desired))
+			if previous != nil {
+				summary = fmt.Sprintf("~ permission %s of %q:%s (was%s)", key, g.Name, describeAccess(&desired), describeAccess(previous))
+			}
+			permissions = append(permissions, schema.PolicyChangeSchema{
+				Kind:       schema.PolicySetPermission,
+				GroupName:  g.Name,
+				Permission: desired,
+				Summary:    summary,
+			})
+		}
+		for _, permission := range owned {
+			key := permission.Domain + "/" + permission.Permission
+			if _, ok := wanted[key]; ok {
+				continue
+			}
+			permissions = append(permissions, schema.PolicyChangeSchema{
+				Kind:       schema.PolicyRemovePermission,
+				GroupName:  g.Name,
+				Permission: permission,
+				Summary:    fmt.Sprintf("- permission %s of %q", key, g.Name),
+			})
+		}
+	}
+
+	if prune {
+		depth := func(g *schema.GroupSchema) int {
+			result := 0
+			for parent := names[g.ParentId]; parent != "" && result <= len(current); parent = names[existing[parent].ParentId] {
+				result++
+			}
+			return result
+		}
+		var pruned []*schema.GroupSchema
+		for i := range current {
+			if _, ok := declared[current[i].Name]; ok {
+				continue
+			}
+			if current[i].IsSpecial {
+				return nil, fmt.Errorf("%w: special group %q must be declared or made regular before pruning", ErrInvalidPolicy, current[i].Name)
+			}
+			pruned = append(pruned, &current[i])
+		}
+		sort.SliceStable(pruned, func(i, j int) bool { return depth(pruned[i]) > depth(pruned[j]) })
+		for _, g := range pruned {
+			deletions = append(deletions, schema.PolicyChangeSchema{
+				Kind:      schema.PolicyDeleteGroup,
+				GroupName: g.Name,
+				Group:     *g,
+				Summary:   fmt.Sprintf("- group %q", g.Name),
+			})
+		}
+	}
+
+	result := append(groups, permissions...)
+	return append(result, deletions...), nil
+}
+
+// parseAccess converts access names to bits
+func parseAccess(names []string) (perm.Access, error) {
+	var result perm.Access
+	for _, name := range names {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "read":
+			result |= perm.AccessRead
+		case "write":
+			result |= perm.AccessWrite
+		case "delete":
+			result |= perm.AccessDelete
+		default:
+			return 0, fmt.Errorf("unknown access %q", name)
+		}
+	}
+	return result, nil
+}
+
+func toSchema(permission Permission) schema.GroupPermissionSchema {
+	grant, _ := parseAccess(permission.Grant)
+	deny, _ := parseAccess(permission.Deny)
+	return schema.GroupPermissionSchema{
+		Permission: permission.Name,
+		Domain:     permission.Domain,
+		Read:       grant&perm.AccessRead != 0,
+		Write:      grant&perm.AccessWrite != 0,
+		Delete:     grant&perm.AccessDelete != 0,
+		DenyRead:   deny&perm.AccessRead != 0,
+		DenyWrite:  deny&perm.AccessWrite != 0,
+		DenyDelete: deny&perm.AccessDelete != 0,
+	}
+}
+
+func sameAccess(a, b *schema.GroupPermissionSchema) bool {
+	return a.Read == b.Read && a.Write == b.Write && a.Delete == b.Delete &&
+		a.DenyRead == b.DenyRead && a.DenyWrite == b.DenyWrite && a.DenyDelete == b.DenyDelete
+}
+
+func describeAccess(p *schema.GroupPermissionSchema) string {
+	grant := accessOf(p.Read, p.Write, p.Delete)
+	deny := accessOf(p.DenyRead, p.DenyWrite, p.DenyDelete)
+	result := ""
+	if grant != 0 {
+		result += " grant=" + grant.String()
+	}
+	if deny != 0 {
+		result += " deny=" + deny.String()
+	}
+	if result == "" {
+		result = " no access"
+	}
+	return result
+}
+
+func accessOf(read, write, delete bool) perm.Access {
+	var result perm.Access
+	if read {
+		result |= perm.AccessRead
+	}
+	if write {
+		result |= perm.AccessWrite
+	}
+	if delete {
+		result |= perm.AccessDelete
+	}
+	return result
+}
+
+func describeGroup(parent string, description *string, isSpecial bool) string {
+	result := ""
+	if parent != "" {
+		result += fmt.Sprintf(" parent=%q", parent)
+	}
+	if description != nil && *description != "" {
+		result += fmt.Sprintf(" description=%q", *description)
+	}
+	if isSpecial {
+		result += " special"
+	}
+	if result == "" {
+		result = " root"
+	}
+	return result
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
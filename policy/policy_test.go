@@ -0,0 +1,125 @@
+package policy
+
+import (
+	"errors"
+	"github.com/savageking-io/ogbuser/schema"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		isJSON  bool
+		want    *Policy
+		wantErr bool
+	}{
+		{"YAML", `
+groups:
+  - name: " Moderators "
+    parent: Players
+    permissions:
+      - name: chat.*
+        grant: [read, write]
+        deny: [delete]
+`, false, &Policy{Groups: []Group{{Name: "Moderators", Parent: "Players", Permissions: []Permission{
+			{Name: "chat.*", Domain: "global", Grant: []string{"read", "write"}, Deny: []string{"delete"}},
+		}}}}, false},
+		{"JSON", `{"groups": [{"name": "Players", "is_special": true}]}`, true,
+			&Policy{Groups: []Group{{Name: "Players", IsSpecial: true}}}, false},
+		{"Empty", ``, false, &Policy{}, false},
+		{"Unknown field", "groups:\n  - name: Players\n    perms: []\n", false, nil, true},
+		{"Duplicate group", "groups:\n  - name: Players\n  - name: Players\n", false, nil, true},
+		{"Own parent", "groups:\n  - name: Players\n    parent: Players\n", false, nil, true},
+		{"Unknown access", "groups:\n  - name: Players\n    permissions:\n      - name: chat\n        grant: [execute]\n", false, nil, true},
+		{"Unknown domain", "groups:\n  - name: Players\n    permissions:\n      - name: chat\n        domain: world\n", false, nil, true},
+		{"Duplicate permission", "groups:\n  - name: Players\n    permissions:\n      - name: chat\n      - name: chat\n        domain: global\n", false, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse([]byte(tt.data), tt.isJSON)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidPolicy) {
+				t.Errorf("Parse() error = %v, want ErrInvalidPolicy", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	current := func() []schema.GroupSchema {
+		return []schema.GroupSchema{
+			{Id: 1, Name: "Players", Permissions: []schema.GroupPermissionSchema{
+				{GroupId: 1, Permission: "chat", Domain: "global", Read: true, Write: true},
+				{GroupId: 1, Permission: "trade", Domain: "global", Read: true},
+			}},
+			{Id: 2, ParentId: 1, Name: "Moderators"},
+			{Id: 3, ParentId: 2, Name: "Trial"},
+			{Id: 4, Name: "Admins", IsSpecial: true},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		policy  *Policy
+		prune   bool
+		want    []string
+		wantErr bool
+	}{
+		{"Nothing changed", &Policy{Groups: []Group{
+			{Name: "Players", Permissions: []Permission{
+				{Name: "chat", Domain: "global", Grant: []string{"read", "write"}},
+				{Name: "trade", Domain: "global", Grant: []string{"read"}},
+			}},
+		}}, false, nil, false},
+		{"Create, update and remove", &Policy{Groups: []Group{
+			{Name: "Players", Permissions: []Permission{
+				{Name: "chat", Domain: "global", Grant: []string{"read"}},
+			}},
+			{Name: "Veterans", Parent: "Players"},
+			{Name: "Moderators", Parent: "Veterans", Description: "Chat moderation"},
+		}}, false, []string{
+			`+ group "Veterans" parent="Players"`,
+			`~ group "Moderators" parent="Veterans" description="Chat moderation" (was parent="Players")`,
+			`~ permission global/chat of "Players": grant=read (was grant=read|write)`,
+			`- permission global/trade of "Players"`,
+		}, false},
+		{"Prune children first", &Policy{Groups: []Group{
+			{Name: "Players", Permissions: []Permission{
+				{Name: "chat", Domain: "global", Grant: []string{"read", "write"}},
+				{Name: "trade", Domain: "global", Grant: []string{"read"}},
+			}},
+			{Name: "Admins", IsSpecial: true},
+		}}, true, []string{
+			`- group "Trial"`,
+			`- group "Moderators"`,
+		}, false},
+		{"Prune special group", &Policy{Groups: []Group{{Name: "Players"}}}, true, nil, true},
+		{"Parent is pruned", &Policy{Groups: []Group{
+			{Name: "Players"}, {Name: "Admins", IsSpecial: true}, {Name: "Helpers", Parent: "Moderators"},
+		}}, true, nil, true},
+		{"Unknown parent", &Policy{Groups: []Group{{Name: "Helpers", Parent: "Staff"}}}, false, nil, true},
+		{"Cycle through unmanaged group", &Policy{Groups: []Group{{Name: "Moderators", Parent: "Trial"}}}, false, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := Diff(tt.policy, current(), tt.prune)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Diff() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []string
+			for _, change := range changes {
+				got = append(got, change.Summary)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
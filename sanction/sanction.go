@@ -0,0 +1,119 @@
+// Package sanction validates sanctions and decides which of them apply to a login or a feature
+package sanction
+
+import (
+	"errors"
+	"fmt"
+	"github.com/savageking-io/ogbuser/schema"
+	"strings"
+	"time"
+)
+
+var ErrInvalidSanction = errors.New("invalid sanction")
+
+const (
+	MaxReasonLength = 1024
+	MaxScopeLength  = 64
+)
+
+// Validate checks a new sanction before it is stored. Reason is trimmed in place
+func Validate(s *schema.SanctionSchema, now time.Time) error {
+	if s == nil {
+		return fmt.Errorf("%w: empty sanction", ErrInvalidSanction)
+	}
+	if s.UserId == 0 {
+		return fmt.Errorf("%w: invalid user id", ErrInvalidSanction)
+	}
+
+	s.Reason = strings.TrimSpace(s.Reason)
+	if s.Reason == "" {
+		return fmt.Errorf("%w: reason is required", ErrInvalidSanction)
+	}
+	if len(s.Reason) > MaxReasonLength {
+		return fmt.Errorf("%w: reason is longer than %d", ErrInvalidSanction, MaxReasonLength)
+	}
+	if len(s.Scope) > MaxScopeLength {
+		return fmt.Errorf("%w: scope is longer than %d", ErrInvalidSanction, MaxScopeLength)
+	}
+	if s.ExpiresAt != nil && !s.ExpiresAt.After(now) {
+		return fmt.Errorf("%w: expiry must be in the future", ErrInvalidSanction)
+	}
+
+	switch s.Type {
+	case schema.SanctionBan:
+		if s.ExpiresAt != nil {
+			return fmt.Errorf("%w: bans are permanent, use a suspension", ErrInvalidSanction)
+		}
+	case schema.SanctionSuspension:
+		if s.ExpiresAt == nil {
+			return fmt.Errorf("%w: suspension requires expiry", ErrInvalidSanction)
+		}
+	case schema.SanctionMute:
+		if s.Scope == "" {
+			return fmt.Errorf("%w: mute requires a feature scope", ErrInvalidSanction)
+		}
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidSanction, s.Type)
+	}
+
+	if s.Scope != "" && !schema.IsKnownPlatform(s.Scope) {
+		return fmt.Errorf("%w: unknown platform %q", ErrInvalidSanction, s.Scope)
+	}
+	return nil
+}
+
+// IsActive returns true if the sanction is neither ended, revoked nor expired
+func IsActive(s *schema.SanctionSchema, now time.Time) bool {
+	if s.EndedAt != nil || s.RevokedAt != nil {
+		return false
+	}
+	return s.ExpiresAt == nil || s.ExpiresAt.After(now)
+}
+
+// FindLoginBlock returns the active sanction that keeps the user from logging in on the platform.
+// When several apply, the one that lasts longest is returned
+func FindLoginBlock(sanctions []schema.SanctionSchema, platform string, now time.Time) *schema.SanctionSchema {
+	var result *schema.SanctionSchema
+	for i := range sanctions {
+		s := &sanctions[i]
+		if !schema.SanctionBlocksLogin(s.Type) || !IsActive(s, now) {
+			continue
+		}
+		if s.Scope != "" && s.Scope != platform {
+			continue
+		}
+		if result == nil || lastsLonger(s, result) {
+			result = s
+		}
+	}
+	return result
+}
+
+// FindMute returns the active mute of the feature. Bans and suspensions for the whole account
+// block every feature as well
+func FindMute(sanctions []schema.SanctionSchema, feature string, now time.Time) *schema.SanctionSchema {
+	var result *schema.SanctionSchema
+	for i := range sanctions {
+		s := &sanctions[i]
+		if !IsActive(s, now) {
+			continue
+		}
+		applies := (s.Type == schema.SanctionMute && s.Scope == feature) ||
+			(schema.SanctionBlocksLogin(s.Type) && s.Scope == "")
+		if !applies {
+			continue
+		}
+		if result == nil || lastsLonger(s, result) {
+			result = s
+		}
+	}
+	return result
+}
+
+func lastsLonger(a, b *schema.SanctionSchema) bool {
+	if b.ExpiresAt == nil {
+		return false
+	}
+	return a.ExpiresAt == nil || a.ExpiresAt.After(*b.ExpiresAt)
+}
@@ -0,0 +1,103 @@
+package sanction
+
+import (
+	"errors"
+	"github.com/savageking-io/ogbuser/schema"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	now := time.Now()
+	future := now.Add(time.Hour)
+	past := now.Add(-time.Hour)
+	tests := []struct {
+		name     string
+		sanction *schema.SanctionSchema
+		wantErr  bool
+	}{
+		{"Permanent ban", &schema.SanctionSchema{UserId: 1, Type: schema.SanctionBan, Reason: "cheating"}, false},
+		{"Platform ban", &schema.SanctionSchema{UserId: 1, Type: schema.SanctionBan, Scope: schema.PlatformSteam, Reason: "cheating"}, false},
+		{"Ban with expiry", &schema.SanctionSchema{UserId: 1, Type: schema.SanctionBan, Reason: "cheating", ExpiresAt: &future}, true},
+		{"Ban of unknown platform", &schema.SanctionSchema{UserId: 1, Type: schema.SanctionBan, Scope: "chat", Reason: "cheating"}, true},
+		{"Suspension", &schema.SanctionSchema{UserId: 1, Type: schema.SanctionSuspension, Reason: "toxic", ExpiresAt: &future}, false},
+		{"Suspension without expiry", &schema.SanctionSchema{UserId: 1, Type: schema.SanctionSuspension, Reason: "toxic"}, true},
+		{"Suspension expired", &schema.SanctionSchema{UserId: 1, Type: schema.SanctionSuspension, Reason: "toxic", ExpiresAt: &past}, true},
+		{"Mute", &schema.SanctionSchema{UserId: 1, Type: schema.SanctionMute, Scope: "chat", Reason: "spam", ExpiresAt: &future}, false},
+		{"Mute without feature", &schema.SanctionSchema{UserId: 1, Type: schema.SanctionMute, Reason: "spam"}, true},
+		{"Empty reason", &schema.SanctionSchema{UserId: 1, Type: schema.SanctionBan, Reason: "  "}, true},
+		{"Unknown type", &schema.SanctionSchema{UserId: 1, Type: "warning", Reason: "spam"}, true},
+		{"No user", &schema.SanctionSchema{Type: schema.SanctionBan, Reason: "cheating"}, true},
+		{"Nil", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.sanction, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSanction) {
+				t.Errorf("Validate() error = %v, want ErrInvalidSanction", err)
+			}
+		})
+	}
+}
+
+func TestFindLoginBlock(t *testing.T) {
+	now := time.Now()
+	soon := now.Add(time.Hour)
+	later := now.Add(24 * time.Hour)
+	past := now.Add(-time.Hour)
+	tests := []struct {
+		name      string
+		sanctions []schema.SanctionSchema
+		platform  string
+		wantId    int32
+	}{
+		{"No sanctions", nil, schema.PlatformWeb, 0},
+		{"Account ban", []schema.SanctionSchema{{Id: 1, Type: schema.SanctionBan}}, schema.PlatformWeb, 1},
+		{"Platform ban on other platform", []schema.SanctionSchema{{Id: 1, Type: schema.SanctionBan, Scope: schema.PlatformSteam}}, schema.PlatformWeb, 0},
+		{"Platform ban on same platform", []schema.SanctionSchema{{Id: 1, Type: schema.SanctionBan, Scope: schema.PlatformSteam}}, schema.PlatformSteam, 1},
+		{"Mute doesn't block", []schema.SanctionSchema{{Id: 1, Type: schema.SanctionMute, Scope: "chat"}}, schema.PlatformWeb, 0},
+		{"Expired suspension", []schema.SanctionSchema{{Id: 1, Type: schema.SanctionSuspension, ExpiresAt: &past}}, schema.PlatformWeb, 0},
+		{"Revoked ban", []schema.SanctionSchema{{Id: 1, Type: schema.SanctionBan, RevokedAt: &past}}, schema.PlatformWeb, 0},
+		{"Longest suspension wins", []schema.SanctionSchema{
+			{Id: 1, Type: schema.SanctionSuspension, ExpiresAt: &soon},
+			{Id: 2, Type: schema.SanctionSuspension, ExpiresAt: &later},
+		}, schema.PlatformWeb, 2},
+		{"Ban wins over suspension", []schema.SanctionSchema{
+			{Id: 1, Type: schema.SanctionSuspension, ExpiresAt: &later},
+			{Id: 2, Type: schema.SanctionBan},
+		}, schema.PlatformWeb, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FindLoginBlock(tt.sanctions, tt.platform, now)
+			var gotId int32
+			if got != nil {
+				gotId = got.Id
+			}
+			if gotId != tt.wantId {
+				t.Errorf("FindLoginBlock() = %d, want %d", gotId, tt.wantId)
+			}
+		})
+	}
+}
+
+func TestFindMute(t *testing.T) {
+	now := time.Now()
+	sanctions := []schema.SanctionSchema{
+		{Id: 1, Type: schema.SanctionMute, Scope: "chat"},
+		{Id: 2, Type: schema.SanctionBan, Scope: schema.PlatformSteam},
+	}
+	if got := FindMute(sanctions, "chat", now); got == nil || got.Id != 1 {
+		t.Errorf("FindMute(chat) = %v, want 1", got)
+	}
+	if got := FindMute(sanctions, "voice", now); got != nil {
+		t.Errorf("FindMute(voice) = %v, want nil", got)
+	}
+	sanctions = append(sanctions, schema.SanctionSchema{Id: 3, Type: schema.SanctionBan})
+	if got := FindMute(sanctions, "voice", now); got == nil || got.Id != 3 {
+		t.Errorf("FindMute(voice) with account ban = %v, want 3", got)
+	}
+}
@@ -1,18 +1,95 @@
 package schema
 
-import "time"
+import (
+	"github.com/jmoiron/sqlx/types"
+	"github.com/lib/pq"
+	"time"
+)
 
 type UserSchema struct {
-	Id        int32               `db:"id"`
-	Username  string              `db:"username"`
-	Password  string              `db:"password"`
-	Email     string              `db:"email"`
-	CreatedAt time.Time           `db:"created_at"`
-	UpdatedAt time.Time           `db:"updated_at"`
-	DeletedAt *time.Time          `db:"deleted_at"`
-	Platforms []PlatformSchema    `db:"-"`
-	Sessions  []UserSessionSchema `db:"-"` // User can have multiple sessions from browser/platforms @TODO: Implement conflicting sessions - for example can't play from two platforms at the same time
-	Groups    []GroupSchema       `db:"-"`
+	Id          int32               `db:"id"`
+	Username    string              `db:"username"`
+	Password    *string             `db:"password"` // Guest accounts have no password until upgraded
+	Email       *string             `db:"email"`    // Guest accounts have no email until upgraded
+	IsGuest     bool                `db:"is_guest"`
+	GuestSecret *string             `db:"guest_secret"` // SHA-256 of the device-bound secret. Only set for guests
+	CreatedAt   time.Time           `db:"created_at"`
+	UpdatedAt   time.Time           `db:"updated_at"`
+	DeletedAt   *time.Time          `db:"deleted_at"`
+	Platforms   []PlatformSchema    `db:"-"`
+	Sessions    []UserSessionSchema `db:"-"` // User can have multiple sessions from browser/platforms @TODO: Implement conflicting sessions - for example can't play from two platforms at the same time
+	Groups      []GroupSchema       `db:"-"`
+}
+
+// Sort fields accepted by user search
+const (
+	UserSortId        string = "id"
+	UserSortUsername  string = "username"
+	UserSortCreatedAt string = "created_at"
+)
+
+// UserSearchSchema holds filters of an admin user search. Empty fields are not applied
+type UserSearchSchema struct {
+	Username       string // Case-insensitive substring
+	Email          string // Case-insensitive substring
+	Platform       string
+	PlatformUserId string // Exact match, optionally together with Platform
+	GroupId        int32
+	CreatedAfter   *time.Time
+	CreatedBefore  *time.Time
+	IncludeDeleted bool
+	SortBy         string
+	Descending     bool
+	Cursor         string // Opaque value returned with the previous page
+	Limit          int
+}
+
+// UserSearchResultSchema is a single user found by search. Secrets are never loaded
+type UserSearchResultSchema struct {
+	UserSchema
+	GroupIds pq.Int32Array `db:"group_ids"`
+}
+
+// ProfileSchema is a public profile of the user. Users without a stored profile get empty values
+type ProfileSchema struct {
+	UserId      int32          `db:"user_id"`
+	Username    string         `db:"username"`
+	DisplayName string         `db:"display_name"`
+	AvatarUrl   string         `db:"avatar_url"`
+	Locale      string         `db:"locale"`
+	Timezone    string         `db:"timezone"`
+	Country     string         `db:"country"`
+	Attributes  types.JSONText `db:"attributes"`
+	UpdatedAt   *time.Time     `db:"updated_at"`
+}
+
+// ProfileUpdateSchema holds fields to change. Nil fields are left untouched.
+// Attributes is a JSON object merged into existing attributes, keys with null values are removed
+type ProfileUpdateSchema struct {
+	DisplayName *string
+	AvatarUrl   *string
+	Locale      *string
+	Timezone    *string
+	Country     *string
+	Attributes  *string
+}
+
+// Platform names must match platform_type enum in the database
+const (
+	PlatformSteam    string = "steam"
+	PlatformEOS      string = "eos"
+	PlatformWinStore string = "winstore"
+	PlatformXbox     string = "xbox"
+	PlatformPS       string = "ps"
+	PlatformWeb      string = "web"
+)
+
+func IsKnownPlatform(name string) bool {
+	switch name {
+	case PlatformSteam, PlatformEOS, PlatformWinStore, PlatformXbox, PlatformPS, PlatformWeb:
+		return true
+	}
+	return false
 }
 
 type PlatformSchema struct {
@@ -47,15 +124,42 @@ type GroupSchema struct {
 	Permissions []GroupPermissionSchema `db:"-"`
 }
 
+// GroupMemberSchema is a membership of the user in the group. Memberships with ExpiresAt in the past
+// are ignored until they are soft-deleted by the sweeper
 type GroupMemberSchema struct {
 	Id        int32      `db:"id"`
 	GroupId   int32      `db:"group_id"`
 	UserId    int32      `db:"user_id"`
+	ExpiresAt *time.Time `db:"expires_at"` // Nil for permanent membership
 	CreatedAt time.Time  `db:"created_at"`
 	UpdatedAt time.Time  `db:"updated_at"`
 	DeletedAt *time.Time `db:"deleted_at"`
 }
 
+// Membership changes and approval statuses must match membership_change and approval_status enums in the database
+const (
+	MembershipChangeAdd    string = "add"
+	MembershipChangeRemove string = "remove"
+
+	ApprovalPending  string = "pending"
+	ApprovalApproved string = "approved"
+	ApprovalRejected string = "rejected"
+)
+
+// MembershipRequestSchema is a membership change of a special group waiting for approval of another admin
+type MembershipRequestSchema struct {
+	Id          int32      `db:"id"`
+	GroupId     int32      `db:"group_id"`
+	UserId      int32      `db:"user_id"`
+	Change      string     `db:"change"`
+	ExpiresAt   *time.Time `db:"expires_at"`
+	Status      string     `db:"status"`
+	RequestedBy int32      `db:"requested_by"`
+	DecidedBy   *int32     `db:"decided_by"`
+	CreatedAt   time.Time  `db:"created_at"`
+	DecidedAt   *time.Time `db:"decided_at"`
+}
+
 type GroupPermissionSchema struct {
 	Id         int32      `db:"id"`
 	GroupId    int32      `db:"group_id"`
@@ -63,12 +167,149 @@ type GroupPermissionSchema struct {
 	Read       bool       `db:"read"`
 	Write      bool       `db:"write"`
 	Delete     bool       `db:"delete"`
+	DenyRead   bool       `db:"deny_read"`
+	DenyWrite  bool       `db:"deny_write"`
+	DenyDelete bool       `db:"deny_delete"`
 	Domain     string     `db:"domain"`
 	CreatedAt  time.Time  `db:"created_at"`
 	UpdatedAt  time.Time  `db:"updated_at"`
 	DeletedAt  *time.Time `db:"deleted_at"`
 }
 
+// Kinds of changes produced when a policy file is compared to the database
+const (
+	PolicyCreateGroup      = "create_group"
+	PolicyUpdateGroup      = "update_group"
+	PolicyDeleteGroup      = "delete_group"
+	PolicySetPermission    = "set_permission"
+	PolicyRemovePermission = "remove_permission"
+)
+
+// PolicyChangeSchema is a single change needed to bring the database to the state declared by a policy file.
+// Groups are referenced by name because groups created by the same apply have no id yet
+type PolicyChangeSchema struct {
+	Kind       string
+	GroupName  string
+	ParentName string
+	Group      GroupSchema
+	Permission GroupPermissionSchema
+	Summary    string
+}
+
+// PermissionCatalogSchema is a permission registered by the service that owns it.
+// Version is increased every time the definition changes
+type PermissionCatalogSchema struct {
+	Id          int32     `db:"id"`
+	Service     string    `db:"service"`
+	Name        string    `db:"name"`
+	Domain      string    `db:"domain"`
+	Description string    `db:"description"`
+	Version     int32     `db:"version"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+// UsernameHistorySchema records a single rename. OldUsername is held for the previous owner until HeldUntil
+type UsernameHistorySchema struct {
+	Id          int32     `db:"id"`
+	UserId      int32     `db:"user_id"`
+	OldUsername string    `db:"old_username"`
+	NewUsername string    `db:"new_username"`
+	HeldUntil   time.Time `db:"held_until"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// Sanction types must match sanction_type enum in the database
+const (
+	SanctionBan        string = "ban"        // Permanent. Blocks login on the scoped platform or everywhere
+	SanctionSuspension string = "suspension" // Same as ban but with expiry
+	SanctionMute       string = "mute"       // Blocks a feature, e.g. chat. Login is not affected
+)
+
+// SanctionBlocksLogin returns true for sanction types that keep the user from logging in
+func SanctionBlocksLogin(sanctionType string) bool {
+	return sanctionType == SanctionBan || sanctionType == SanctionSuspension
+}
+
+type SanctionSchema struct {
+	Id           int32                `db:"id"`
+	UserId       int32                `db:"user_id"`
+	Type         string               `db:"type"`
+	Scope        string               `db:"scope"` // Platform for bans and suspensions, feature for mutes. Empty means whole account
+	Reason       string               `db:"reason"`
+	IssuedBy     *int32               `db:"issued_by"` // Nil when issued from CLI
+	ExpiresAt    *time.Time           `db:"expires_at"`
+	EndedAt      *time.Time           `db:"ended_at"`
+	RevokedAt    *time.Time           `db:"revoked_at"`
+	RevokedBy    *int32               `db:"revoked_by"`
+	RevokeReason *string              `db:"revoke_reason"`
+	CreatedAt    time.Time            `db:"created_at"`
+	Notes        []SanctionNoteSchema `db:"-"`
+}
+
+// SanctionNoteSchema is a note attached to a sanction, e.g. during an appeal
+type SanctionNoteSchema struct {
+	Id         int32     `db:"id"`
+	SanctionId int32     `db:"sanction_id"`
+	AuthorId   *int32    `db:"author_id"`
+	Note       string    `db:"note"`
+	CreatedAt  time.Time `db:"created_at"`
+}
+
+// Erasure request statuses must match erasure_status enum in the database
+const (
+	ErasurePending   string = "pending"
+	ErasureCompleted string = "completed"
+)
+
+type ErasureRequestSchema struct {
+	Id          int32              `db:"id"`
+	UserId      int32              `db:"user_id"`
+	Status      string             `db:"status"`
+	Reason      string             `db:"reason"`
+	RequestedBy *int32             `db:"requested_by"`
+	CreatedAt   time.Time          `db:"created_at"`
+	CompletedAt *time.Time         `db:"completed_at"`
+	Acks        []ErasureAckSchema `db:"-"`
+}
+
+// ErasureAckSchema tracks whether a service has erased its data of the user. AcknowledgedAt is nil until then
+type ErasureAckSchema struct {
+	Id             int32      `db:"id"`
+	RequestId      int32      `db:"request_id"`
+	Service        string     `db:"service"`
+	AcknowledgedAt *time.Time `db:"acknowledged_at"`
+}
+
+// AuditLogSchema records administrative actions. ActorId is nil for actions started from CLI
+type AuditLogSchema struct {
+	Id           int32          `db:"id"`
+	ActorId      *int32         `db:"actor_id"`
+	Action       string         `db:"action"`
+	TargetUserId *int32         `db:"target_user_id"`
+	Details      types.JSONText `db:"details"`
+	CreatedAt    time.Time      `db:"created_at"`
+}
+
+// MergeResultSchema summarizes what was moved from source to target user during a merge
+type MergeResultSchema struct {
+	TargetUserId    int32 `json:"target_user_id"`
+	SourceUserId    int32 `json:"source_user_id"`
+	MovedPlatforms  int64 `json:"moved_platforms"`
+	AddedGroups     int64 `json:"added_groups"`
+	RevokedSessions int64 `json:"revoked_sessions"`
+	// Special groups of the source are not copied by admins but requested for the target, pending approval
+	MembershipRequests []int32 `json:"membership_requests"`
+}
+
+// SchemaMigrationSchema records a migration applied to the database. Checksum is sha256 of its up script
+type SchemaMigrationSchema struct {
+	Version   int32     `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
 func BoolToInt32(b bool) int32 {
 	if b {
 		return 1
@@ -12,36 +12,69 @@ import (
 	"github.com/savageking-io/ogbuser/db"
 	"github.com/savageking-io/ogbuser/group"
 	"github.com/savageking-io/ogbuser/kafka"
+	"github.com/savageking-io/ogbuser/naming"
+	"github.com/savageking-io/ogbuser/perm"
 	"github.com/savageking-io/ogbuser/proto"
+	"github.com/savageking-io/ogbuser/schema"
 	"github.com/savageking-io/ogbuser/user"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/types/known/timestamppb"
 	"net"
+	"strings"
+	"sync"
 	"time"
 )
 
+var (
+	ErrMissingToken        = errors.New("missing session token")
+	ErrInvalidToken        = errors.New("invalid session token")
+	ErrUnsupportedPlatform = errors.New("unsupported platform")
+	ErrPermissionDenied    = errors.New("permission denied")
+)
+
+// PermissionManageUsers is a global permission required by administrative user operations
+const PermissionManageUsers = "manage_users"
+
 type Service struct {
-	rest   *restlib.RestInterServiceServer
-	config *ServiceConfig
-	db     *db.Database
-	groups *group.GroupsData
-	users  *user.UsersData
-	kafka  kafka.Publisher
-	steam  *steam.Client
+	rest      *restlib.RestInterServiceServer
+	config    *ServiceConfig
+	db        *db.Database
+	groups    *group.GroupsData
+	users     *user.UsersData
+	kafka     kafka.Publisher
+	steam     *steam.Client
+	usernames *naming.Policy
+	members   perm.MembershipResolver
+
+	reloadMutex    sync.Mutex    // Serializes group reloads
+	reloadRequests chan struct{} // Pending reload from a signal or database notification
+	invalidations  *invalidationHub
+	instanceId     string // Tells invalidations of this replica apart from those of others
 
 	proto.UnimplementedUserServiceServer
 }
 
 func NewService(config *ServiceConfig, steam *steam.Client) *Service {
 	return &Service{
-		config: config,
-		users:  user.NewUsersData(nil),
-		groups: group.NewGroupsData(),
-		steam:  steam,
+		config:    config,
+		users:     user.NewUsersData(nil),
+		groups:    group.NewGroupsData(),
+		steam:     steam,
+		usernames: naming.NewPolicy(&config.Usernames),
+
+		reloadRequests: make(chan struct{}, 1),
+		invalidations:  newInvalidationHub(),
+		instanceId:     newInstanceId(),
 	}
 }
 
+// SetMembershipResolver sets the resolver used for party and guild permission checks.
+// Without a resolver party and guild permissions never apply to a resource
+func (s *Service) SetMembershipResolver(resolver perm.MembershipResolver) {
+	s.members = resolver
+}
+
 func (s *Service) Init() error {
 	log.Infof("Initializing service")
 
@@ -49,12 +82,28 @@ func (s *Service) Init() error {
 		return err
 	}
 
+	if err := s.MigrateDatabase(context.Background()); err != nil {
+		log.Errorf("Failed to migrate database: %v", err)
+		return err
+	}
+
 	s.users.SetDb(s.db)
 
 	if err := s.InitGroups(); err != nil {
 		log.Errorf("Failed to initialize groups: %v", err)
 		return err
 	}
+	s.users.SetGroups(s.groups)
+
+	if err := s.checkGuestGroup(); err != nil {
+		log.Errorf("Failed to initialize guest accounts: %v", err)
+		return err
+	}
+
+	if _, _, err := s.registerPermissions(context.Background(), ServiceName, ownPermissions); err != nil {
+		log.Errorf("Failed to register own permissions: %v", err)
+		return err
+	}
 
 	if err := s.InitializeRest(s.config.Rest); err != nil {
 		log.Errorf("Failed to initialize REST server: %v", err)
@@ -67,6 +116,18 @@ func (s *Service) Init() error {
 	}
 
 	go s.kafka.LogServerStarted()
+	go s.WatchSanctionExpiry(context.Background())
+	go s.WatchMembershipExpiry(context.Background())
+	go s.RunAccountPurge(context.Background())
+	go s.RunGroupReloads(context.Background())
+	go s.WatchReloadSignal(context.Background())
+	go s.WatchReplicaInvalidations(context.Background())
+	if s.config.Groups.ListenChanges {
+		go s.WatchGroupChanges(context.Background())
+	}
+	if s.config.Policy.File != "" {
+		go s.WatchPolicyFile(context.Background())
+	}
 
 	return nil
 }
@@ -82,11 +143,6 @@ func (s *Service) ConnectToDatabase() error {
 	}
 
 	log.Infof("Connected to database %s", s.config.Postgres.Database)
-	if err := s.db.TryPopulate(); err != nil {
-		log.Errorf("Failed to populate database: %s", err.Error())
-		return err
-	}
-
 	return nil
 }
 
@@ -97,23 +153,12 @@ func (s *Service) InitGroups() error {
 
 	log.Infof("Initializing groups")
 
-	groups, err := s.db.LoadGroups(context.Background())
+	groups, _, err := s.rebuildGroups(context.Background())
 	if err != nil {
-		return fmt.Errorf("failed to load groups: %w", err)
-	}
-
-	log.Infof("Found %d groups", len(groups))
-
-	for _, userGroup := range groups {
-		g := group.NewGroupFromSchema(s.db, &userGroup)
-		if err := g.Init(context.Background()); err != nil {
-			log.Errorf("Failed to initialize group %d: %v", userGroup.Id, err)
-			continue
-		}
-		s.groups.Add(g)
+		return err
 	}
 
-	log.Infof("Groups initialized. Total: %d", len(s.groups.GetAll()))
+	log.Infof("Groups initialized. Total: %d", groups)
 
 	return nil
 }
@@ -135,9 +180,75 @@ func (s *Service) InitializeRest(config restlib.RestInterServiceConfig) error {
 	if err := s.rest.RegisterHandler("/auth/server", "POST", s.HandleAuthServerRequest, true); err != nil {
 		log.Warnf("Failed to register handler for /auth/server: %v", err)
 	}
+	if err := s.rest.RegisterHandler("/auth/guest", "POST", s.HandleAuthGuestRequest, true); err != nil {
+		log.Warnf("Failed to register handler for /auth/guest: %v", err)
+	}
 	if err := s.rest.RegisterHandler("/token", "POST", s.HandleVerifyTokenRequest, false); err != nil {
 		log.Warnf("Failed to register handler for /token: %v", err)
 	}
+	if err := s.rest.RegisterHandler("/account/upgrade", "POST", s.HandleUpgradeAccountRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /account/upgrade: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/account/username", "POST", s.HandleChangeUsernameRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /account/username: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/account/export", "GET", s.HandleExportRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /account/export: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/admin/users/search", "POST", s.HandleSearchUsersRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /admin/users/search: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/platforms", "GET", s.HandleListPlatformsRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /platforms: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/platforms/link", "POST", s.HandleLinkPlatformRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /platforms/link: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/platforms/unlink", "POST", s.HandleUnlinkPlatformRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /platforms/unlink: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/admin/groups", "GET", s.HandleListGroupsRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /admin/groups: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/admin/groups/create", "POST", s.HandleCreateGroupRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /admin/groups/create: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/admin/groups/update", "POST", s.HandleUpdateGroupRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /admin/groups/update: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/admin/groups/delete", "POST", s.HandleDeleteGroupRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /admin/groups/delete: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/admin/groups/permissions/set", "POST", s.HandleSetGroupPermissionRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /admin/groups/permissions/set: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/admin/groups/permissions/remove", "POST", s.HandleRemoveGroupPermissionRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /admin/groups/permissions/remove: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/admin/groups/members", "POST", s.HandleListGroupMembersRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /admin/groups/members: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/admin/groups/members/add", "POST", s.HandleAddGroupMemberRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /admin/groups/members/add: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/admin/groups/members/remove", "POST", s.HandleRemoveGroupMemberRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /admin/groups/members/remove: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/admin/groups/requests", "POST", s.HandleListMembershipRequestsRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /admin/groups/requests: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/admin/groups/requests/approve", "POST", s.HandleApproveMembershipRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /admin/groups/requests/approve: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/admin/groups/requests/reject", "POST", s.HandleRejectMembershipRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /admin/groups/requests/reject: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/admin/groups/reload", "POST", s.HandleReloadGroupsRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /admin/groups/reload: %v", err)
+	}
+	if err := s.rest.RegisterHandler("/admin/permissions/explain", "POST", s.HandleExplainPermissionRequest, false); err != nil {
+		log.Warnf("Failed to register handler for /admin/permissions/explain: %v", err)
+	}
 
 	for _, key := range s.rest.GetRegisteredHandlerKeys() {
 		log.Infof("Registered handler: %s", key)
@@ -254,6 +365,16 @@ func (s *Service) HandleAuthCredentialsRequest(ctx context.Context, in *restprot
 		}, nil
 	}
 
+	if !u.HasPassword() {
+		// Guests and platform-only accounts can't log in with credentials
+		log.Debugf("User %d has no password", u.GetId())
+		return &restproto.RestApiResponse{
+			Code:     12003,
+			HttpCode: 401,
+			Error:    "wrong credentials",
+		}, nil
+	}
+
 	ok, err := VerifyPassword(credentials.Password, u.GetPassword())
 	if err != nil {
 		log.Debugf("Password verification failed: %v", err)
@@ -273,8 +394,11 @@ func (s *Service) HandleAuthCredentialsRequest(ctx context.Context, in *restprot
 		}, nil
 	}
 
-	groupIds, err := u.LoadGroups(ctx)
-	if err != nil {
+	if blocked := s.loginBlockedResponse(ctx, u.GetId(), schema.PlatformWeb); blocked != nil {
+		return blocked, nil
+	}
+
+	if err := s.attachGroups(ctx, u); err != nil {
 		log.Errorf("Failed to load groups: %v", err)
 		return &restproto.RestApiResponse{
 			HttpCode: 401,
@@ -283,16 +407,6 @@ func (s *Service) HandleAuthCredentialsRequest(ctx context.Context, in *restprot
 		}, nil
 	}
 
-	log.Infof("Loaded %d groups", len(groupIds))
-	for _, groupId := range groupIds {
-		userGroup, exists := s.groups.Get(groupId)
-		if !exists {
-			log.Errorf("Service::HandleAuthCredentialsRequest: Group %d not found for user %d", groupId, u.GetId())
-			continue
-		}
-		u.AddGroup(userGroup)
-	}
-
 	// We keep users cached until they log out or we didn't receive anything from them for a long period of time
 	// @TODO: Handle timeout
 	// @TODO: Handle cleanup of duplicates
@@ -307,7 +421,7 @@ func (s *Service) HandleAuthCredentialsRequest(ctx context.Context, in *restprot
 	}
 
 	// @TOOO: Properly determine user platform
-	session, err := u.InitializeSession(ctx, "web")
+	session, err := u.InitializeSession(ctx, schema.PlatformWeb)
 	if err != nil {
 		log.Errorf("Failed to initialize session: %v", err)
 		return &restproto.RestApiResponse{
@@ -384,8 +498,11 @@ func (s *Service) HandleAuthPlatformRequest(ctx context.Context, in *restproto.R
 		}, nil
 	}
 
-	groupIds, err := u.LoadGroups(ctx)
-	if err != nil {
+	if blocked := s.loginBlockedResponse(ctx, u.GetId(), schema.PlatformSteam); blocked != nil {
+		return blocked, nil
+	}
+
+	if err := s.attachGroups(ctx, u); err != nil {
 		log.Errorf("Failed to load groups: %v", err)
 		return &restproto.RestApiResponse{
 			HttpCode: 401,
@@ -394,16 +511,6 @@ func (s *Service) HandleAuthPlatformRequest(ctx context.Context, in *restproto.R
 		}, nil
 	}
 
-	log.Infof("Loaded %d groups", len(groupIds))
-	for _, groupId := range groupIds {
-		userGroup, exists := s.groups.Get(groupId)
-		if !exists {
-			log.Errorf("Service::HandleAuthCredentialsRequest: Group %d not found for user %d", groupId, u.GetId())
-			continue
-		}
-		u.AddGroup(userGroup)
-	}
-
 	// We keep users cached until they log out or we didn't receive anything from them for a long period of time
 	// @TODO: Handle timeout
 	// @TODO: Handle cleanup of duplicates
@@ -417,8 +524,8 @@ func (s *Service) HandleAuthPlatformRequest(ctx context.Context, in *restproto.R
 		}, nil
 	}
 
-	// @TOOO: Properly determine user platform
-	session, err := u.InitializeSession(ctx, "web")
+	// Platform-scoped sanctions revoke and block sessions by their platform
+	session, err := u.InitializeSession(ctx, schema.PlatformSteam)
 	if err != nil {
 		log.Errorf("Failed to initialize session: %v", err)
 		return &restproto.RestApiResponse{
@@ -469,6 +576,9 @@ func (s *Service) ValidateToken(ctx context.Context, in *proto.ValidateTokenRequ
 	session, err := s.db.GetUserSessionByToken(ctx, in.Token)
 	if err != nil {
 		if errors.Is(err, db.ErrSessionNotFound) {
+			if response := s.validateRevokedToken(ctx, in.Token); response != nil {
+				return response, nil
+			}
 			return &proto.ValidateTokenResponse{
 				Code:    0,
 				IsValid: false,
@@ -498,6 +608,22 @@ func (s *Service) ValidateToken(ctx context.Context, in *proto.ValidateTokenRequ
 		}, nil
 	}
 
+	block, err := s.findLoginBlock(ctx, session.UserId, session.PlatformName)
+	if err != nil {
+		return &proto.ValidateTokenResponse{
+			Code:  1,
+			Error: err.Error(),
+		}, nil
+	}
+	if block != nil {
+		return &proto.ValidateTokenResponse{
+			Code:    19000,
+			Error:   sanctionMessage(block),
+			IsValid: false,
+			UserId:  session.UserId,
+		}, nil
+	}
+
 	log.Debugf("Token is valid: %s", in.Token)
 	return &proto.ValidateTokenResponse{
 		Code:    0,
@@ -536,7 +662,8 @@ func (s *Service) HasPermission(ctx context.Context, in *proto.HasPermissionRequ
 		}, fmt.Errorf("user not found")
 	}
 
-	permission, err := u.HasPermission(ctx, in.Permission, in.Domain)
+	resource := perm.Resource{OwnerId: in.ResourceOwnerId, PartyId: in.PartyId, GuildId: in.GuildId}
+	permission, err := s.checkPermission(ctx, u, in.Permission, in.Domain, resource, s.members)
 	if err != nil {
 		return &proto.HasPermissionResponse{
 			Read:   0,
@@ -554,12 +681,117 @@ func (s *Service) HasPermission(ctx context.Context, in *proto.HasPermissionRequ
 	}
 
 	return &proto.HasPermissionResponse{
-		Read:   permission.Read,
-		Write:  permission.Write,
-		Delete: permission.Delete,
+		Read:      permission.Effective(perm.AccessRead),
+		Write:     permission.Effective(perm.AccessWrite),
+		Delete:    permission.Effective(perm.AccessDelete),
+		Decisions: permissionDecisions(permission),
 	}, nil
 }
 
+// permissionDecisions explains every access bit of the permission
+func permissionDecisions(permission *perm.Permission) []*proto.PermissionDecision {
+	bits := []struct {
+		name   string
+		access perm.Access
+	}{
+		{"read", perm.AccessRead},
+		{"write", perm.AccessWrite},
+		{"delete", perm.AccessDelete},
+	}
+
+	result := make([]*proto.PermissionDecision, 0, len(bits))
+	for _, bit := range bits {
+		decision := permission.Decide(bit.access)
+		result = append(result, &proto.PermissionDecision{
+			Access:  bit.name,
+			Allowed: decision.Allowed,
+			GroupId: decision.GroupId,
+			Rule:    decision.Rule,
+		})
+	}
+	return result
+}
+
+// getRequestUserId resolves the user behind the session token of an authenticated REST request
+func (s *Service) getRequestUserId(ctx context.Context, in *restproto.RestApiRequest) (int32, error) {
+	sessionToken := ""
+	for _, header := range in.Headers {
+		if strings.EqualFold(header.Key, "Authorization") {
+			sessionToken = strings.TrimSpace(strings.TrimPrefix(header.Value, "Bearer "))
+			break
+		}
+	}
+	if sessionToken == "" {
+		return 0, ErrMissingToken
+	}
+
+	result, err := s.ValidateToken(ctx, &proto.ValidateTokenRequest{Token: sessionToken})
+	if err != nil {
+		return 0, err
+	}
+	if result.Code != 0 {
+		return 0, fmt.Errorf("token validation failed with code %d: %s", result.Code, result.Error)
+	}
+	if !result.IsValid || result.UserId == 0 {
+		return 0, ErrInvalidToken
+	}
+
+	return result.UserId, nil
+}
+
+// requireGlobalPermission returns ErrPermissionDenied unless the user holds the global permission
+// with every requested access bit
+func (s *Service) requireGlobalPermission(ctx context.Context, userId int32, permission string, access perm.Access) error {
+	if userId == 0 {
+		return ErrPermissionDenied
+	}
+
+	u, err := s.users.GetById(userId)
+	if err != nil {
+		return fmt.Errorf("failed to load requester: %w", err)
+	}
+
+	result, err := s.checkPermission(ctx, u, permission, perm.DomainGlobal, perm.Resource{}, nil)
+	if err != nil {
+		return err
+	}
+
+	if result == nil {
+		return ErrPermissionDenied
+	}
+
+	if decision := result.Decide(access); !decision.Allowed {
+		log.Infof("User %d was denied %s [%d]: %s by group %d", userId, permission, decision.Access, decision.Rule, decision.GroupId)
+		return ErrPermissionDenied
+	}
+
+	return nil
+}
+
+// attachGroups loads group membership of the user and attaches groups known to the service
+func (s *Service) attachGroups(ctx context.Context, u *user.User) error {
+	return s.users.AttachGroups(ctx, u)
+}
+
+// verifyPlatformTicket asks the platform provider to verify the ticket and returns platform user id
+func (s *Service) verifyPlatformTicket(ctx context.Context, platform, ticket string) (string, error) {
+	switch platform {
+	case schema.PlatformSteam:
+		if s.steam == nil {
+			return "", fmt.Errorf("steam client not initialized")
+		}
+		result, err := s.steam.AuthenticateTicket(ctx, ticket)
+		if err != nil {
+			return "", err
+		}
+		if result == nil || result.SteamId == "" {
+			return "", fmt.Errorf("steam returned empty steam id")
+		}
+		return result.SteamId, nil
+	}
+	return "", ErrUnsupportedPlatform
+}
+
 func boolToInt32(b bool) int32 {
 	if b {
 		return 1
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/savageking-io/ogbuser/db"
+	"github.com/savageking-io/ogbuser/kafka"
+	"github.com/savageking-io/ogbuser/perm"
+	"github.com/savageking-io/ogbuser/proto"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+const defaultPurgeBatchSize = 100
+
+// restoreWindow is how long a deleted user can be restored
+func (s *Service) restoreWindow() time.Duration {
+	return time.Duration(s.config.Accounts.RestoreDays) * 24 * time.Hour
+}
+
+// retention is how long a deleted user is kept before purge. Never shorter than restore window
+func (s *Service) retention() time.Duration {
+	retention := time.Duration(s.config.Accounts.RetentionDays) * 24 * time.Hour
+	if retention < s.restoreWindow() {
+		return s.restoreWindow()
+	}
+	return retention
+}
+
+// deleteUser soft-deletes the user, drops it from the cache and notifies other services.
+// actorId is 0 when deleted from CLI
+func (s *Service) deleteUser(ctx context.Context, userId, actorId int32, reason string) (int64, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("database is not initialized")
+	}
+
+	revoked, err := s.db.DeleteUser(ctx, userId, actorId, reason)
+	if err != nil {
+		return 0, err
+	}
+
+	_ = s.users.Delete(userId)
+	log.Infof("User %d deleted by %d, revoked %d sessions", userId, actorId, revoked)
+
+	event := &kafka.UserDeletedSchema{UserId: userId, DeletedBy: actorId, DeletedAt: time.Now()}
+	if err := s.kafka.PublishEvent(ctx, kafka.EventUserDeleted, event); err != nil {
+		log.Errorf("Failed to publish %s event for %d: %v", kafka.EventUserDeleted, userId, err)
+	}
+	s.invalidateUsers(ctx, InvalidationAccount, userId)
+
+	return revoked, nil
+}
+
+func (s *Service) restoreUser(ctx context.Context, userId, actorId int32) error {
+	if s.db == nil {
+		return fmt.Errorf("database is not initialized")
+	}
+
+	if err := s.db.RestoreUser(ctx, userId, actorId, s.restoreWindow()); err != nil {
+		return err
+	}
+
+	log.Infof("User %d restored by %d", userId, actorId)
+
+	event := &kafka.UserRestoredSchema{UserId: userId, RestoredBy: actorId, RestoredAt: time.Now()}
+	if err := s.kafka.PublishEvent(ctx, kafka.EventUserRestored, event); err != nil {
+		log.Errorf("Failed to publish %s event for %d: %v", kafka.EventUserRestored, userId, err)
+	}
+	s.invalidateUsers(ctx, InvalidationAccount, userId)
+
+	return nil
+}
+
+// purgeDeletedUsers purges users deleted longer than retention ago batch by batch and returns their number
+func (s *Service) purgeDeletedUsers(ctx context.Context) (int, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("database is not initialized")
+	}
+
+	mode := s.config.Accounts.PurgeMode
+	if mode == "" {
+		mode = db.PurgeModeAnonymize
+	}
+	batchSize := s.config.Accounts.PurgeBatchSize
+	if batchSize <= 0 {
+		batchSize = defaultPurgeBatchSize
+	}
+	cutoff := time.Now().Add(-s.retention())
+
+	total := 0
+	for {
+		ids, err := s.db.PurgeDeletedUsers(ctx, cutoff, mode, batchSize)
+		if err != nil {
+			return total, err
+		}
+
+		for _, id := range ids {
+			_ = s.users.Delete(id)
+			event := &kafka.UserPurgedSchema{UserId: id, Mode: mode, PurgedAt: time.Now()}
+			if err := s.kafka.PublishEvent(ctx, kafka.EventUserPurged, event); err != nil {
+				log.Errorf("Failed to publish %s event for %d: %v", kafka.EventUserPurged, id, err)
+			}
+		}
+		s.invalidateUsers(ctx, InvalidationAccount, ids...)
+		total += len(ids)
+
+		if len(ids) < batchSize {
+			return total, nil
+		}
+	}
+}
+
+// RunAccountPurge purges deleted users past retention on the configured interval
+func (s *Service) RunAccountPurge(ctx context.Context) {
+	if s.config.Accounts.PurgeIntervalMinutes <= 0 {
+		log.Infof("Account purge is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(s.config.Accounts.PurgeIntervalMinutes) * time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			purged, err := s.purgeDeletedUsers(ctx)
+			if err != nil {
+				log.Errorf("Failed to purge deleted users: %v", err)
+			}
+			if purged > 0 {
+				log.Infof("Purged %d deleted users", purged)
+			}
+		}
+	}
+}
+
+func accountErrorCode(err error) int32 {
+	switch {
+	case errors.Is(err, ErrPermissionDenied):
+		return 21001
+	case errors.Is(err, db.ErrUserNotFound):
+		return 21002
+	case errors.Is(err, db.ErrUserDeleted):
+		return 21003
+	case errors.Is(err, db.ErrUserNotDeleted):
+		return 21004
+	case errors.Is(err, db.ErrRestoreWindowEnded):
+		return 21005
+	case errors.Is(err, db.ErrUserMerged):
+		return 21006
+	case errors.Is(err, db.ErrErasurePending):
+		return 21008
+	}
+	return 21007
+}
+
+// DeleteUser soft-deletes the user. Users can delete themselves, anyone else needs
+// global manage_users with delete access
+func (s *Service) DeleteUser(ctx context.Context, in *proto.DeleteUserRequest) (*proto.DeleteUserResponse, error) {
+	log.Tracef("DeleteUser")
+
+	if in.UserId == 0 {
+		return &proto.DeleteUserResponse{Code: 21000, Error: "invalid user id"}, nil
+	}
+
+	if in.RequesterId != in.UserId {
+		if err := s.requireGlobalPermission(ctx, in.RequesterId, PermissionManageUsers, perm.AccessDelete); err != nil {
+			return &proto.DeleteUserResponse{Code: accountErrorCode(err), Error: err.Error()}, nil
+		}
+	}
+
+	revoked, err := s.deleteUser(ctx, in.UserId, in.RequesterId, in.Reason)
+	if err != nil {
+		code := accountErrorCode(err)
+		if code == 21007 {
+			log.Errorf("Failed to delete user %d: %v", in.UserId, err)
+		}
+		return &proto.DeleteUserResponse{Code: code, Error: err.Error()}, nil
+	}
+
+	return &proto.DeleteUserResponse{
+		Code:            0,
+		RevokedSessions: revoked,
+		RestorableUntil: timestamppb.New(time.Now().Add(s.restoreWindow())),
+	}, nil
+}
+
+// RestoreUser brings back a user deleted within the restore window.
+// Requester must have global manage_users with write access
+func (s *Service) RestoreUser(ctx context.Context, in *proto.RestoreUserRequest) (*proto.RestoreUserResponse, error) {
+	log.Tracef("RestoreUser")
+
+	if in.UserId == 0 {
+		return &proto.RestoreUserResponse{Code: 21000, Error: "invalid user id"}, nil
+	}
+
+	if err := s.requireGlobalPermission(ctx, in.RequesterId, PermissionManageUsers, perm.AccessWrite); err != nil {
+		return &proto.RestoreUserResponse{Code: accountErrorCode(err), Error: err.Error()}, nil
+	}
+
+	if err := s.restoreUser(ctx, in.UserId, in.RequesterId); err != nil {
+		code := accountErrorCode(err)
+		if code == 21007 {
+			log.Errorf("Failed to restore user %d: %v", in.UserId, err)
+		}
+		return &proto.RestoreUserResponse{Code: code, Error: err.Error()}, nil
+	}
+
+	return &proto.RestoreUserResponse{Code: 0}, nil
+}
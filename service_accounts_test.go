@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"github.com/savageking-io/ogbuser/db"
+	"testing"
+)
+
+func TestService_purgeDeletedUsers_Batches(t *testing.T) {
+	config := &ServiceConfig{Accounts: AccountsConfig{RestoreDays: 7, RetentionDays: 30, PurgeMode: db.PurgeModeDelete, PurgeBatchSize: 2}}
+	s, conn := testService(t, config)
+	// Five users are past retention, more than two full batches, and one was deleted recently
+	setup := `
+		INSERT INTO users (username, password, email, deleted_at)
+		SELECT 'expired_' || n, 'hash', 'expired_' || n || '@localhost', CURRENT_TIMESTAMP - INTERVAL '40 days'
+		FROM generate_series(1, 5) AS n;
+		INSERT INTO users (username, password, email, deleted_at)
+		VALUES ('recent', 'hash', 'recent@localhost', CURRENT_TIMESTAMP - INTERVAL '1 day');`
+	if _, err := conn.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+
+	purged, err := s.purgeDeletedUsers(context.Background())
+	if err != nil {
+		t.Fatalf("purgeDeletedUsers() error = %v", err)
+	}
+	if purged != 5 {
+		t.Errorf("purgeDeletedUsers() = %d, want 5", purged)
+	}
+
+	var left []string
+	if err := conn.Select(&left, `SELECT username FROM users`); err != nil {
+		t.Fatal(err)
+	}
+	if len(left) != 1 || left[0] != "recent" {
+		t.Errorf("users after purge = %v, want [recent]", left)
+	}
+
+	var audited int
+	if err := conn.Get(&audited, `SELECT COUNT(*) FROM audit_log WHERE action = $1`, db.AuditActionUserPurged); err != nil {
+		t.Fatal(err)
+	}
+	if audited != 5 {
+		t.Errorf("%d purges audited, want 5", audited)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/savageking-io/ogbuser/db"
+	"github.com/savageking-io/ogbuser/kafka"
+	"github.com/savageking-io/ogbuser/perm"
+	"github.com/savageking-io/ogbuser/proto"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultErasureListLimit = 50
+	MaxErasureListLimit     = 200
+)
+
+// requestErasure starts erasure of the user and asks registered services to erase their data.
+// actorId is 0 when requested from CLI
+func (s *Service) requestErasure(ctx context.Context, userId, actorId int32, reason string) (*schema.ErasureRequestSchema, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+
+	services := s.config.Erasure.Services
+	request, revoked, err := s.db.CreateErasureRequest(ctx, userId, actorId, strings.TrimSpace(reason), services)
+	if err != nil {
+		return nil, err
+	}
+
+	_ = s.users.Delete(userId)
+	log.Infof("Erasure %d of user %d requested by %d, revoked %d sessions, waiting for %d services",
+		request.Id, userId, actorId, revoked, len(services))
+
+	event := &kafka.UserErasureRequestedSchema{
+		RequestId:   request.Id,
+		UserId:      userId,
+		Services:    services,
+		RequestedAt: request.CreatedAt,
+	}
+	if err := s.kafka.PublishEvent(ctx, kafka.EventUserErasureRequested, event); err != nil {
+		log.Errorf("Failed to publish %s event for request %d: %v", kafka.EventUserErasureRequested, request.Id, err)
+	}
+
+	if request.Status == schema.ErasureCompleted {
+		s.publishErasureCompleted(ctx, request)
+	}
+
+	return request, nil
+}
+
+func (s *Service) publishErasureCompleted(ctx context.Context, request *schema.ErasureRequestSchema) {
+	log.Infof("Erasure %d of user %d completed", request.Id, request.UserId)
+
+	event := &kafka.UserErasureCompletedSchema{
+		RequestId:   request.Id,
+		UserId:      request.UserId,
+		CompletedAt: time.Now(),
+	}
+	if request.CompletedAt != nil {
+		event.CompletedAt = *request.CompletedAt
+	}
+	if err := s.kafka.PublishEvent(ctx, kafka.EventUserErasureCompleted, event); err != nil {
+		log.Errorf("Failed to publish %s event for request %d: %v", kafka.EventUserErasureCompleted, request.Id, err)
+	}
+}
+
+func erasureErrorCode(err error) int32 {
+	switch {
+	case errors.Is(err, ErrPermissionDenied):
+		return 23001
+	case errors.Is(err, db.ErrUserNotFound):
+		return 23002
+	case errors.Is(err, db.ErrErasureRequested):
+		return 23003
+	case errors.Is(err, db.ErrErasureNotFound):
+		return 23004
+	case errors.Is(err, db.ErrUnknownErasureService):
+		return 23005
+	}
+	return 23006
+}
+
+func erasureRequestToProto(request *schema.ErasureRequestSchema) *proto.ErasureRequest {
+	result := &proto.ErasureRequest{
+		Id:          request.Id,
+		UserId:      request.UserId,
+		Status:      request.Status,
+		Reason:      request.Reason,
+		RequestedBy: derefUserId(request.RequestedBy),
+		CreatedAt:   timestamppb.New(request.CreatedAt),
+		CompletedAt: optionalTimestamp(request.CompletedAt),
+	}
+	for _, ack := range request.Acks {
+		result.Acks = append(result.Acks, &proto.ErasureAck{
+			Service:        ack.Service,
+			AcknowledgedAt: optionalTimestamp(ack.AcknowledgedAt),
+		})
+	}
+	return result
+}
+
+// RequestErasure starts erasure of the user. Users can erase themselves, anyone else needs
+// global manage_users with delete access
+func (s *Service) RequestErasure(ctx context.Context, in *proto.RequestErasureRequest) (*proto.RequestErasureResponse, error) {
+	log.Tracef("RequestErasure")
+
+	if in.UserId == 0 {
+		return &proto.RequestErasureResponse{Code: 23000, Error: "invalid user id"}, nil
+	}
+
+	if in.RequesterId != in.UserId {
+		if err := s.requireGlobalPermission(ctx, in.RequesterId, PermissionManageUsers, perm.AccessDelete); err != nil {
+			return &proto.RequestErasureResponse{Code: erasureErrorCode(err), Error: err.Error()}, nil
+		}
+	}
+
+	request, err := s.requestErasure(ctx, in.UserId, in.RequesterId, in.Reason)
+	if err != nil {
+		code := erasureErrorCode(err)
+		if code == 23006 {
+			log.Errorf("Failed to request erasure of user %d: %v", in.UserId, err)
+		}
+		return &proto.RequestErasureResponse{Code: code, Error: err.Error()}, nil
+	}
+
+	return &proto.RequestErasureResponse{Code: 0, Request: erasureRequestToProto(request)}, nil
+}
+
+// AcknowledgeErasure is called by a registered service once it erased its data of the user
+func (s *Service) AcknowledgeErasure(ctx context.Context, in *proto.AcknowledgeErasureRequest) (*proto.AcknowledgeErasureResponse, error) {
+	log.Tracef("AcknowledgeErasure")
+
+	if in.RequestId == 0 || in.Service == "" {
+		return &proto.AcknowledgeErasureResponse{Code: 23000, Error: "request id and service are required"}, nil
+	}
+
+	if s.db == nil {
+		return &proto.AcknowledgeErasureResponse{Code: 23006, Error: "database is not initialized"}, nil
+	}
+
+	request, completed, err := s.db.AcknowledgeErasure(ctx, in.RequestId, in.Service)
+	if err != nil {
+		code := erasureErrorCode(err)
+		if code == 23006 {
+			log.Errorf("Failed to acknowledge erasure %d by %s: %v", in.RequestId, in.Service, err)
+		}
+		return &proto.AcknowledgeErasureResponse{Code: code, Error: err.Error()}, nil
+	}
+
+	log.Infof("Erasure %d acknowledged by %s", in.RequestId, in.Service)
+	if completed {
+		s.publishErasureCompleted(ctx, request)
+	}
+
+	return &proto.AcknowledgeErasureResponse{Code: 0, Completed: completed}, nil
+}
+
+// ListErasureRequests shows progress of erasure requests. Requester must have global manage_users with read access
+func (s *Service) ListErasureRequests(ctx context.Context, in *proto.ListErasureRequestsRequest) (*proto.ListErasureRequestsResponse, error) {
+	log.Tracef("ListErasureRequests")
+
+	if err := s.requireGlobalPermission(ctx, in.RequesterId, PermissionManageUsers, perm.AccessRead); err != nil {
+		return &proto.ListErasureRequestsResponse{Code: erasureErrorCode(err), Error: err.Error()}, nil
+	}
+
+	if s.db == nil {
+		return &proto.ListErasureRequestsResponse{Code: 23006, Error: "database is not initialized"}, nil
+	}
+
+	limit := int(in.Limit)
+	if limit <= 0 {
+		limit = DefaultErasureListLimit
+	}
+	if limit > MaxErasureListLimit {
+		limit = MaxErasureListLimit
+	}
+
+	requests, err := s.db.LoadErasureRequests(ctx, in.UserId, in.PendingOnly, limit)
+	if err != nil {
+		log.Errorf("Failed to load erasure requests: %v", err)
+		return &proto.ListErasureRequestsResponse{Code: 23006, Error: err.Error()}, nil
+	}
+
+	result := &proto.ListErasureRequestsResponse{Code: 0}
+	for i := range requests {
+		result.Requests = append(result.Requests, erasureRequestToProto(&requests[i]))
+	}
+	return result, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/savageking-io/ogbuser/db"
+	"github.com/savageking-io/ogbuser/proto"
+	"github.com/savageking-io/ogbuser/schema"
+	"testing"
+)
+
+func TestService_requestErasure(t *testing.T) {
+	config := &ServiceConfig{Erasure: ErasureConfig{Services: []string{"inventory", "chat"}}, Accounts: AccountsConfig{PurgeMode: db.PurgeModeDelete}}
+	s, conn := testService(t, config)
+	ctx := context.Background()
+	// User 2 is past retention and purged as usual
+	setup := `
+		INSERT INTO users (username, password, email) VALUES ('player', 'hash', 'player@localhost');
+		INSERT INTO users (username, password, email, deleted_at)
+		VALUES ('expired', 'hash', 'expired@localhost', CURRENT_TIMESTAMP - INTERVAL '40 days');
+		INSERT INTO user_sessions (user_id, token, platform_name) VALUES (1, 'web_token', 'web');
+		INSERT INTO user_profiles (user_id, display_name) VALUES (1, 'Player');`
+	if _, err := conn.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := s.requestErasure(ctx, 1, 0, "requested by the player")
+	if err != nil {
+		t.Fatalf("requestErasure() error = %v", err)
+	}
+	if request.Status != schema.ErasurePending || len(request.Acks) != 2 {
+		t.Fatalf("requestErasure() = %+v, want pending request waiting for 2 services", request)
+	}
+	if _, err := s.requestErasure(ctx, 1, 0, ""); !errors.Is(err, db.ErrErasureRequested) {
+		t.Errorf("requestErasure() of a pending user error = %v, want %v", err, db.ErrErasureRequested)
+	}
+	if err := s.restoreUser(ctx, 1, 0); !errors.Is(err, db.ErrErasurePending) {
+		t.Errorf("restoreUser() of a pending user error = %v, want %v", err, db.ErrErasurePending)
+	}
+
+	// Pending erasure is left to the erasure workflow even past retention, in delete mode too
+	if _, err := conn.Exec(`UPDATE users SET deleted_at = CURRENT_TIMESTAMP - INTERVAL '40 days' WHERE id = 1`); err != nil {
+		t.Fatal(err)
+	}
+	purged, err := s.purgeDeletedUsers(ctx)
+	if err != nil {
+		t.Fatalf("purgeDeletedUsers() error = %v", err)
+	}
+	if purged != 1 {
+		t.Errorf("purgeDeletedUsers() = %d, want only user 2 purged", purged)
+	}
+
+	acks := []struct {
+		service       string
+		wantCode      int32
+		wantCompleted bool
+	}{
+		{"inventory", 0, false},
+		{"billing", erasureErrorCode(db.ErrUnknownErasureService), false},
+		{"chat", 0, true},
+		{"chat", 0, false},
+	}
+	for _, ack := range acks {
+		response, err := s.AcknowledgeErasure(ctx, &proto.AcknowledgeErasureRequest{RequestId: request.Id, Service: ack.service})
+		if err != nil {
+			t.Fatalf("AcknowledgeErasure(%s) error = %v", ack.service, err)
+		}
+		if response.Code != ack.wantCode || response.Completed != ack.wantCompleted {
+			t.Errorf("AcknowledgeErasure(%s) = code %d completed %v, want code %d completed %v",
+				ack.service, response.Code, response.Completed, ack.wantCode, ack.wantCompleted)
+		}
+	}
+
+	var erased struct {
+		Username string `db:"username"`
+		Purged   bool   `db:"purged"`
+		Profiles int    `db:"profiles"`
+		Status   string `db:"status"`
+	}
+	query := `
+		SELECT u.username, u.purged_at IS NOT NULL AS purged,
+		       (SELECT COUNT(*) FROM user_profiles WHERE user_id = u.id) AS profiles,
+		       (SELECT status FROM erasure_requests WHERE user_id = u.id) AS status
+		FROM users u WHERE u.id = 1`
+	if err := conn.Get(&erased, query); err != nil {
+		t.Fatal(err)
+	}
+	if erased.Username != "deleted_1" || !erased.Purged || erased.Profiles != 0 || erased.Status != schema.ErasureCompleted {
+		t.Errorf("user after erasure = %+v, want anonymized user with completed request", erased)
+	}
+
+	// Erased user is anonymized and kept as evidence, purge doesn't pick it up again
+	if purged, err := s.purgeDeletedUsers(ctx); err != nil || purged != 0 {
+		t.Errorf("purgeDeletedUsers() after erasure = %d, %v, want nothing purged", purged, err)
+	}
+}
+
+func TestService_requestErasure_NoServices(t *testing.T) {
+	s, conn := testService(t, &ServiceConfig{})
+	if _, err := conn.Exec(`INSERT INTO users (username, password, email) VALUES ('player', 'hash', 'player@localhost')`); err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := s.requestErasure(context.Background(), 1, 0, "")
+	if err != nil {
+		t.Fatalf("requestErasure() error = %v", err)
+	}
+	if request.Status != schema.ErasureCompleted || request.CompletedAt == nil {
+		t.Errorf("requestErasure() without services = %+v, want completed request", request)
+	}
+}
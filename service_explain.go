@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	restproto "github.com/savageking-io/ogbrest/proto"
+	"github.com/savageking-io/ogbuser/db"
+	"github.com/savageking-io/ogbuser/perm"
+	"github.com/savageking-io/ogbuser/proto"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+type explainedRule struct {
+	Name    string `json:"name"`
+	Domain  string `json:"domain"`
+	Access  string `json:"access"`
+	Rule    string `json:"rule"`
+	GroupId int32  `json:"group_id"`
+	Applied bool   `json:"applied"`
+}
+
+type explainedGroup struct {
+	Id        int32           `json:"id"`
+	Name      string          `json:"name"`
+	Chain     []int32         `json:"chain"`
+	IsSpecial bool            `json:"is_special"`
+	ExpiresAt *time.Time      `json:"expires_at"`
+	Rules     []explainedRule `json:"rules"`
+	Read      int32           `json:"read"`
+	Write     int32           `json:"write"`
+	Delete    int32           `json:"delete"`
+}
+
+type explainedDecision struct {
+	Access  string `json:"access"`
+	Allowed bool   `json:"allowed"`
+	GroupId int32  `json:"group_id"`
+	Rule    string `json:"rule"`
+}
+
+// permissionExplanation shows how a permission check of a user is evaluated: which groups are considered,
+// which of their own and inherited rules match and what the check returns
+type permissionExplanation struct {
+	UserId           int32               `json:"user_id"`
+	Permission       string              `json:"permission"`
+	Domains          []string            `json:"domains"`
+	Groups           []explainedGroup    `json:"groups"`
+	SuperuserGroupId int32               `json:"superuser_group_id"`
+	Read             int32               `json:"read"`
+	Write            int32               `json:"write"`
+	Delete           int32               `json:"delete"`
+	Decisions        []explainedDecision `json:"decisions"`
+}
+
+// explainPermission walks the evaluation of the permission for the user. The final result comes from the
+// same evaluation HasPermission uses, without recording a superuser bypass. Requester must have global
+// manage_users with read access
+func (s *Service) explainPermission(ctx context.Context, actorId, userId int32, permission, domain string, resource perm.Resource) (*permissionExplanation, error) {
+	if userId == 0 || permission == "" {
+		return nil, fmt.Errorf("%w: user id and permission are required", perm.ErrInvalidPermission)
+	}
+	if err := perm.ValidateTarget(domain, resource); err != nil {
+		return nil, err
+	}
+	if err := s.requireGlobalPermission(ctx, actorId, PermissionManageUsers, perm.AccessRead); err != nil {
+		return nil, err
+	}
+
+	u, err := s.users.GetById(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver := perm.NewCachingResolver(s.members)
+	domains := []string{domain}
+	if resource.IsSet() {
+		if domains, err = perm.ApplicableDomains(ctx, userId, resource, resolver); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &permissionExplanation{UserId: userId, Permission: permission, Domains: domains}
+	for _, g := range u.GetGroups() {
+		groupPerms := g.GetPermissions()
+		explained := explainedGroup{
+			Id:        g.GetId(),
+			Name:      g.GetName(),
+			Chain:     g.GetChain(),
+			IsSpecial: g.IsSpecial(),
+			ExpiresAt: u.GetMembershipExpiry(g.GetId()),
+			Rules:     []explainedRule{},
+		}
+		if groupPerms != nil {
+			for _, d := range domains {
+				for _, match := range groupPerms.Matches(d, permission) {
+					explained.Rules = append(explained.Rules, explainedRule{
+						Name:    match.Name,
+						Domain:  match.Domain,
+						Access:  match.Access.String(),
+						Rule:    match.Rule,
+						GroupId: match.GroupId,
+						Applied: match.Applied,
+					})
+				}
+			}
+			combined := groupPerms.Combine(permission, domains)
+			explained.Read = combined.Effective(perm.AccessRead)
+			explained.Write = combined.Effective(perm.AccessWrite)
+			explained.Delete = combined.Effective(perm.AccessDelete)
+		}
+		result.Groups = append(result.Groups, explained)
+	}
+	if special := u.SuperuserGroup(); special != nil {
+		result.SuperuserGroupId = special.GetId()
+	}
+
+	final, err := s.evaluatePermission(ctx, u, permission, domain, resource, resolver, false)
+	if err != nil {
+		return nil, err
+	}
+	result.Read = final.Effective(perm.AccessRead)
+	result.Write = final.Effective(perm.AccessWrite)
+	result.Delete = final.Effective(perm.AccessDelete)
+	for _, decision := range permissionDecisions(final) {
+		result.Decisions = append(result.Decisions, explainedDecision{
+			Access:  decision.Access,
+			Allowed: decision.Allowed,
+			GroupId: decision.GroupId,
+			Rule:    decision.Rule,
+		})
+	}
+
+	return result, nil
+}
+
+func explainErrorCode(err error) (int32, int32) {
+	switch {
+	case errors.Is(err, perm.ErrInvalidPermission):
+		return 27000, 400
+	case errors.Is(err, ErrPermissionDenied):
+		return 27001, 403
+	case errors.Is(err, db.ErrUserNotFound):
+		return 27002, 404
+	}
+	return 27003, 500
+}
+
+func explanationToProto(explanation *permissionExplanation) *proto.ExplainPermissionResponse {
+	result := &proto.ExplainPermissionResponse{
+		Code:             0,
+		Domains:          explanation.Domains,
+		SuperuserGroupId: explanation.SuperuserGroupId,
+		Read:             explanation.Read,
+		Write:            explanation.Write,
+		Delete:           explanation.Delete,
+	}
+	for _, g := range explanation.Groups {
+		explained := &proto.ExplainedGroup{
+			Id:        g.Id,
+			Name:      g.Name,
+			Chain:     g.Chain,
+			IsSpecial: g.IsSpecial,
+			ExpiresAt: optionalTimestamp(g.ExpiresAt),
+			Read:      g.Read,
+			Write:     g.Write,
+			Delete:    g.Delete,
+		}
+		for _, rule := range g.Rules {
+			explained.Rules = append(explained.Rules, &proto.PermissionRule{
+				Name:    rule.Name,
+				Domain:  rule.Domain,
+				Access:  rule.Access,
+				Rule:    rule.Rule,
+				GroupId: rule.GroupId,
+				Applied: rule.Applied,
+			})
+		}
+		result.Groups = append(result.Groups, explained)
+	}
+	for _, decision := range explanation.Decisions {
+		result.Decisions = append(result.Decisions, &proto.PermissionDecision{
+			Access:  decision.Access,
+			Allowed: decision.Allowed,
+			GroupId: decision.GroupId,
+			Rule:    decision.Rule,
+		})
+	}
+	return result
+}
+
+// ExplainPermission shows how a permission check of the user is evaluated. Requester must have global
+// manage_users with read access
+func (s *Service) ExplainPermission(ctx context.Context, in *proto.ExplainPermissionRequest) (*proto.ExplainPermissionResponse, error) {
+	log.Tracef("ExplainPermission")
+
+	resource := perm.Resource{OwnerId: in.ResourceOwnerId, PartyId: in.PartyId, GuildId: in.GuildId}
+	explanation, err := s.explainPermission(ctx, in.RequesterId, in.UserId, in.Permission, in.Domain, resource)
+	if err != nil {
+		code, _ := explainErrorCode(err)
+		if code == 27003 {
+			log.Errorf("Failed to explain %s of user %d: %v", in.Permission, in.UserId, err)
+		}
+		return &proto.ExplainPermissionResponse{Code: code, Error: err.Error()}, nil
+	}
+
+	return explanationToProto(explanation), nil
+}
+
+// HandleExplainPermissionRequest shows how a permission check is evaluated from
+// {"user_id": 10, "permission": "inventory.items.trade", "domain": "global"} or with resource ids
+// "resource_owner_id", "party_id" and "guild_id" instead of the domain
+func (s *Service) HandleExplainPermissionRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleExplainPermissionRequest")
+
+	requesterId, err := s.getRequestUserId(ctx, in)
+	if err != nil {
+		log.Debugf("Failed to authenticate request: %v", err)
+		return &restproto.RestApiResponse{Code: 27004, HttpCode: 401, Error: "unauthorized"}, nil
+	}
+
+	request := struct {
+		UserId          int32  `json:"user_id"`
+		Permission      string `json:"permission"`
+		Domain          string `json:"domain"`
+		ResourceOwnerId int32  `json:"resource_owner_id"`
+		PartyId         int32  `json:"party_id"`
+		GuildId         int32  `json:"guild_id"`
+	}{}
+	if err := json.Unmarshal([]byte(in.Body), &request); err != nil {
+		log.Debugf("Failed to unmarshal request body: %v", err)
+		return &restproto.RestApiResponse{Code: 27000, HttpCode: 400, Error: "failed to parse request"}, nil
+	}
+
+	resource := perm.Resource{OwnerId: request.ResourceOwnerId, PartyId: request.PartyId, GuildId: request.GuildId}
+	explanation, err := s.explainPermission(ctx, requesterId, request.UserId, request.Permission, request.Domain, resource)
+	if err != nil {
+		code, httpCode := explainErrorCode(err)
+		if code == 27003 {
+			log.Errorf("Failed to explain %s of user %d: %v", request.Permission, request.UserId, err)
+		}
+		return &restproto.RestApiResponse{Code: code, HttpCode: httpCode, Error: err.Error()}, nil
+	}
+
+	body, err := json.Marshal(explanation)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restproto.RestApiResponse{Code: 0, HttpCode: 200, Body: string(body)}, nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	restproto "github.com/savageking-io/ogbrest/proto"
+	"github.com/savageking-io/ogbuser/db"
+	"github.com/savageking-io/ogbuser/kafka"
+	"github.com/savageking-io/ogbuser/perm"
+	"github.com/savageking-io/ogbuser/proto"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// ExportArchiveSchema is the top level of a data export archive
+type ExportArchiveSchema struct {
+	ExportId    string          `json:"export_id"`
+	Service     string          `json:"service"`
+	UserId      int32           `json:"user_id"`
+	GeneratedAt time.Time       `json:"generated_at"`
+	Data        json.RawMessage `json:"data"`
+}
+
+func generateExportId() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// exportUser builds the archive of everything ogbuser holds about the user and asks other services
+// to prepare their parts. actorId is 0 when export is started from CLI
+func (s *Service) exportUser(ctx context.Context, userId, actorId int32) (*ExportArchiveSchema, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+
+	data, err := s.db.ExportUser(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	exportId, err := generateExportId()
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &ExportArchiveSchema{
+		ExportId:    exportId,
+		Service:     "ogbuser",
+		UserId:      userId,
+		GeneratedAt: time.Now().UTC(),
+		Data:        json.RawMessage(data),
+	}
+
+	if err := s.db.RecordAuditLog(ctx, actorId, db.AuditActionUserExported, userId, map[string]any{"export_id": exportId}); err != nil {
+		log.Errorf("Failed to record export %s of user %d: %v", exportId, userId, err)
+	}
+
+	log.Infof("Exported data of user %d for %d [%s]", userId, actorId, exportId)
+
+	event := &kafka.UserExportRequestedSchema{
+		ExportId:    exportId,
+		UserId:      userId,
+		RequestedBy: actorId,
+		RequestedAt: archive.GeneratedAt,
+	}
+	if err := s.kafka.PublishEvent(ctx, kafka.EventUserExportRequested, event); err != nil {
+		log.Errorf("Failed to publish %s event for %d: %v", kafka.EventUserExportRequested, userId, err)
+	}
+
+	return archive, nil
+}
+
+func exportErrorCode(err error) (int32, int32) {
+	switch {
+	case errors.Is(err, ErrPermissionDenied):
+		return 22002, 403
+	case errors.Is(err, db.ErrUserNotFound):
+		return 22003, 404
+	}
+	return 22004, 500
+}
+
+// ExportUser returns data export of the user. Users can export themselves, anyone else needs
+// global manage_users with read access
+func (s *Service) ExportUser(ctx context.Context, in *proto.ExportUserRequest) (*proto.ExportUserResponse, error) {
+	log.Tracef("ExportUser")
+
+	if in.UserId == 0 {
+		return &proto.ExportUserResponse{Code: 22000, Error: "invalid user id"}, nil
+	}
+
+	if in.RequesterId != in.UserId {
+		if err := s.requireGlobalPermission(ctx, in.RequesterId, PermissionManageUsers, perm.AccessRead); err != nil {
+			code, _ := exportErrorCode(err)
+			return &proto.ExportUserResponse{Code: code, Error: err.Error()}, nil
+		}
+	}
+
+	archive, err := s.exportUser(ctx, in.UserId, in.RequesterId)
+	if err != nil {
+		code, _ := exportErrorCode(err)
+		if code == 22004 {
+			log.Errorf("Failed to export user %d: %v", in.UserId, err)
+		}
+		return &proto.ExportUserResponse{Code: code, Error: err.Error()}, nil
+	}
+
+	body, err := json.Marshal(archive)
+	if err != nil {
+		return &proto.ExportUserResponse{Code: 22004, Error: err.Error()}, nil
+	}
+
+	return &proto.ExportUserResponse{Code: 0, ExportId: archive.ExportId, Archive: string(body)}, nil
+}
+
+// HandleExportRequest returns data export of the requesting user
+func (s *Service) HandleExportRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleExportRequest")
+
+	userId, err := s.getRequestUserId(ctx, in)
+	if err != nil {
+		log.Debugf("Failed to authenticate request: %v", err)
+		return &restproto.RestApiResponse{Code: 22001, HttpCode: 401, Error: "unauthorized"}, nil
+	}
+
+	archive, err := s.exportUser(ctx, userId, userId)
+	if err != nil {
+		code, httpCode := exportErrorCode(err)
+		if httpCode == 500 {
+			log.Errorf("Failed to export user %d: %v", userId, err)
+		}
+		return &restproto.RestApiResponse{Code: code, HttpCode: httpCode, Error: err.Error()}, nil
+	}
+
+	body, err := json.Marshal(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restproto.RestApiResponse{Code: 0, HttpCode: 200, Body: string(body)}, nil
+}
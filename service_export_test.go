@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/savageking-io/ogbuser/db"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestService_exportUser(t *testing.T) {
+	s, conn := testService(t, &ServiceConfig{})
+	ctx := context.Background()
+	// Both users have a row in every exported table. Values of user 2 are prefixed with "other" and
+	// secrets with "secret" so leaks are easy to find in the archive
+	setup := `
+		INSERT INTO users (username, password, email) VALUES
+			('player', 'secret_hash_1', 'player@localhost'), ('other', 'secret_hash_2', 'other@localhost');
+		INSERT INTO users (username, deleted_at, purged_at) VALUES ('deleted_3', CURRENT_TIMESTAMP, CURRENT_TIMESTAMP);
+		INSERT INTO user_profiles (user_id, display_name) VALUES (1, 'Player'), (2, 'other_name');
+		INSERT INTO platforms (user_id, platform_name, platform_user_id) VALUES (1, 'steam', 'steam_1'), (2, 'steam', 'other_steam');
+		INSERT INTO user_sessions (user_id, token, platform_name) VALUES (1, 'secret_token_1', 'web'), (2, 'secret_token_2', 'web');
+		INSERT INTO groups (name) VALUES ('Players');
+		INSERT INTO group_members (group_id, user_id) VALUES (1, 1), (1, 2);
+		INSERT INTO username_history (user_id, old_username, new_username, held_until) VALUES
+			(1, 'first', 'player', CURRENT_TIMESTAMP), (2, 'other_first', 'other', CURRENT_TIMESTAMP);
+		INSERT INTO sanctions (user_id, type, scope, reason) VALUES (1, 'mute', 'chat', 'spam'), (2, 'mute', 'chat', 'other_reason');
+		INSERT INTO sanction_notes (sanction_id, note) VALUES (1, 'warned'), (2, 'other_note');
+		INSERT INTO audit_log (actor_id, action, target_user_id) VALUES (0, 'user.restored', 1), (0, 'user.restored', 2);`
+	if _, err := conn.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := s.exportUser(ctx, 1, 1)
+	if err != nil {
+		t.Fatalf("exportUser() error = %v", err)
+	}
+	if archive.UserId != 1 || archive.ExportId == "" {
+		t.Errorf("exportUser() = %+v, want archive of user 1", archive)
+	}
+
+	var data map[string]json.RawMessage
+	if err := json.Unmarshal(archive.Data, &data); err != nil {
+		t.Fatalf("archive data %s: %v", archive.Data, err)
+	}
+	wantSections := []string{"account", "account_events", "groups", "login_history", "platforms", "profile", "sanctions", "username_history"}
+	if sections := slices.Sorted(maps.Keys(data)); !slices.Equal(sections, wantSections) {
+		t.Errorf("archive sections = %v, want %v", sections, wantSections)
+	}
+	for _, section := range wantSections {
+		var rows []json.RawMessage
+		if json.Unmarshal(data[section], &rows) == nil && len(rows) != 1 {
+			t.Errorf("archive section %s has %d rows, want 1", section, len(rows))
+		}
+	}
+	if leaked := strings.Count(string(archive.Data), "other") + strings.Count(string(archive.Data), "secret"); leaked > 0 {
+		t.Errorf("archive contains data of another user or secrets: %s", archive.Data)
+	}
+
+	var exports int
+	query := `SELECT COUNT(*) FROM audit_log WHERE action = $1 AND target_user_id = 1`
+	if err := conn.Get(&exports, query, db.AuditActionUserExported); err != nil {
+		t.Fatal(err)
+	}
+	if exports != 1 {
+		t.Errorf("%d exports audited, want 1", exports)
+	}
+
+	if _, err := s.exportUser(ctx, 3, 0); !errors.Is(err, db.ErrUserNotFound) {
+		t.Errorf("exportUser() of a purged user error = %v, want %v", err, db.ErrUserNotFound)
+	}
+}
@@ -0,0 +1,1051 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	restproto "github.com/savageking-io/ogbrest/proto"
+	"github.com/savageking-io/ogbuser/db"
+	"github.com/savageking-io/ogbuser/group"
+	"github.com/savageking-io/ogbuser/kafka"
+	"github.com/savageking-io/ogbuser/perm"
+	"github.com/savageking-io/ogbuser/proto"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"slices"
+	"strings"
+	"time"
+)
+
+const maxGroupNameLength = 100
+
+// MembershipExpiryInterval is how often expired group memberships are swept
+const MembershipExpiryInterval = time.Minute
+
+var (
+	ErrGroupNotLoaded    = errors.New("group not found")
+	ErrInvalidGroupName  = errors.New("group name must be 1 to 100 characters")
+	ErrInvalidMembership = errors.New("membership expiry must be in the future")
+)
+
+func groupErrorCode(err error) (int32, int32) {
+	switch {
+	case errors.Is(err, perm.ErrInvalidPermission), errors.Is(err, ErrInvalidGroupName), errors.Is(err, ErrInvalidMembership):
+		return 24000, 400
+	case errors.Is(err, ErrPermissionDenied):
+		return 24001, 403
+	case errors.Is(err, ErrGroupNotLoaded), errors.Is(err, db.ErrGroupNotFound):
+		return 24002, 404
+	case errors.Is(err, db.ErrUnknownPermission):
+		return 24004, 400
+	case errors.Is(err, db.ErrGroupNameTaken):
+		return 24005, 409
+	case errors.Is(err, db.ErrGroupParentCycle):
+		return 24006, 400
+	case errors.Is(err, db.ErrGroupHasChildren):
+		return 24007, 409
+	case errors.Is(err, db.ErrUserNotFound):
+		return 24008, 404
+	case errors.Is(err, db.ErrAlreadyMember):
+		return 24009, 409
+	case errors.Is(err, db.ErrNotMember):
+		return 24010, 404
+	case errors.Is(err, db.ErrGroupPermissionNotFound):
+		return 24011, 404
+	case errors.Is(err, db.ErrGroupProtected):
+		return 24013, 403
+	case errors.Is(err, db.ErrMembershipRequestNotFound):
+		return 24014, 404
+	case errors.Is(err, db.ErrMembershipRequestDecided):
+		return 24015, 409
+	case errors.Is(err, db.ErrSelfApproval):
+		return 24016, 403
+	case errors.Is(err, db.ErrMembershipRequestPending):
+		return 24017, 409
+	}
+	return 24003, 500
+}
+
+func validateGroupName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxGroupNameLength {
+		return "", ErrInvalidGroupName
+	}
+	return name, nil
+}
+
+// refreshGroup initializes the group again together with every group inheriting from it,
+// so cached users pick up the change on their next permission check
+func (s *Service) refreshGroup(ctx context.Context, groupId int32) error {
+	for _, id := range s.inheritingGroups(groupId) {
+		g, ok := s.groups.Get(id)
+		if !ok {
+			continue
+		}
+		if err := g.Init(ctx); err != nil {
+			return fmt.Errorf("failed to refresh group %d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// inheritingGroups returns the groups together with every loaded group inheriting from any of them
+func (s *Service) inheritingGroups(groupIds ...int32) []int32 {
+	result := append([]int32(nil), groupIds...)
+	for _, g := range s.groups.GetAll() {
+		if slices.Contains(result, g.GetId()) {
+			continue
+		}
+		for _, id := range g.GetChain() {
+			if slices.Contains(groupIds, id) {
+				result = append(result, g.GetId())
+				break
+			}
+		}
+	}
+	return result
+}
+
+// reloadMembers refreshes membership of cached users after it changed in the database
+func (s *Service) reloadMembers(ctx context.Context, userIds ...int32) {
+	for _, userId := range userIds {
+		if err := s.users.ReloadGroups(ctx, userId); err != nil {
+			log.Errorf("Failed to reload groups of user %d: %v", userId, err)
+		}
+	}
+}
+
+func (s *Service) listGroups(ctx context.Context, actorId int32) ([]*group.Group, error) {
+	if err := s.requireGlobalPermission(ctx, actorId, PermissionManageUsers, perm.AccessRead); err != nil {
+		return nil, err
+	}
+	return s.groups.GetAll(), nil
+}
+
+func (s *Service) createGroup(ctx context.Context, actorId int32, name, description string, parentId int32) (*group.Group, error) {
+	name, err := validateGroupName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireGlobalPermission(ctx, actorId, PermissionManageUsers, perm.AccessWrite); err != nil {
+		return nil, err
+	}
+	if s.db == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+
+	raw := &schema.GroupSchema{Name: name, ParentId: parentId}
+	if description = strings.TrimSpace(description); description != "" {
+		raw.Description = &description
+	}
+	created, err := s.db.CreateGroup(ctx, actorId, raw)
+	if err != nil {
+		return nil, err
+	}
+
+	g := group.NewGroupFromSchema(s.db, created)
+	if err := g.Init(ctx); err != nil {
+		return nil, err
+	}
+	s.groups.Add(g)
+	// Nobody is a member yet, but other replicas learn about the group from the invalidation
+	s.invalidateGroups(ctx, InvalidationGroup, created.Id)
+
+	log.Infof("Group %s [%d] created by %d", created.Name, created.Id, actorId)
+	return g, nil
+}
+
+// updateGroup changes fields that are not nil
+func (s *Service) updateGroup(ctx context.Context, actorId, groupId int32, name, description *string, parentId *int32) (*group.Group, error) {
+	if err := s.requireGlobalPermission(ctx, actorId, PermissionManageUsers, perm.AccessWrite); err != nil {
+		return nil, err
+	}
+	if s.db == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+
+	current, err := s.db.LoadGroupById(ctx, groupId)
+	if err != nil {
+		return nil, err
+	}
+	if name != nil {
+		if current.Name, err = validateGroupName(*name); err != nil {
+			return nil, err
+		}
+	}
+	if description != nil {
+		current.Description = nil
+		if trimmed := strings.TrimSpace(*description); trimmed != "" {
+			current.Description = &trimmed
+		}
+	}
+	if parentId != nil {
+		current.ParentId = *parentId
+	}
+
+	updated, err := s.db.UpdateGroup(ctx, actorId, current)
+	if err != nil {
+		return nil, err
+	}
+
+	g, exists := s.groups.Get(groupId)
+	if !exists {
+		g = group.NewGroupFromSchema(s.db, updated)
+		if err := g.Init(ctx); err != nil {
+			return nil, err
+		}
+		s.groups.Add(g)
+	}
+	g.SetSchema(*updated)
+	if err := s.refreshGroup(ctx, groupId); err != nil {
+		log.Errorf("Group %d is updated, but groups were not refreshed: %v", groupId, err)
+	}
+	s.invalidateGroups(ctx, InvalidationGroup, groupId)
+
+	log.Infof("Group %s [%d] updated by %d", updated.Name, updated.Id, actorId)
+	return g, nil
+}
+
+func (s *Service) deleteGroup(ctx context.Context, actorId, groupId int32) error {
+	if err := s.requireGlobalPermission(ctx, actorId, PermissionManageUsers, perm.AccessDelete); err != nil {
+		return err
+	}
+	if s.db == nil {
+		return fmt.Errorf("database is not initialized")
+	}
+
+	members, err := s.db.DeleteGroup(ctx, actorId, groupId)
+	if err != nil {
+		return err
+	}
+
+	s.groups.Remove(groupId)
+	s.reloadMembers(ctx, members...)
+	s.invalidateGroups(ctx, InvalidationGroup, groupId)
+
+	log.Infof("Group %d deleted by %d, %d members removed", groupId, actorId, len(members))
+	return nil
+}
+
+func (s *Service) setGroupPermission(ctx context.Context, actorId int32, permission *schema.GroupPermissionSchema) error {
+	if err := perm.ValidateGrant(permission.Permission, permission.Domain); err != nil {
+		return err
+	}
+	if err := s.requireGlobalPermission(ctx, actorId, PermissionManageUsers, perm.AccessWrite); err != nil {
+		return err
+	}
+	if s.db == nil {
+		return fmt.Errorf("database is not initialized")
+	}
+
+	if err := s.db.SetGroupPermission(ctx, actorId, permission); err != nil {
+		return err
+	}
+
+	log.Infof("Permission %s [%s] of group %d set by %d", permission.Permission, permission.Domain, permission.GroupId, actorId)
+	if err := s.refreshGroup(ctx, permission.GroupId); err != nil {
+		log.Errorf("Permission of group %d is stored, but groups were not refreshed: %v", permission.GroupId, err)
+	}
+	s.invalidateGroups(ctx, InvalidationGroup, permission.GroupId)
+	return nil
+}
+
+func (s *Service) removeGroupPermission(ctx context.Context, actorId, groupId int32, name, domain string) error {
+	if err := s.requireGlobalPermission(ctx, actorId, PermissionManageUsers, perm.AccessWrite); err != nil {
+		return err
+	}
+	if s.db == nil {
+		return fmt.Errorf("database is not initialized")
+	}
+
+	if err := s.db.RemoveGroupPermission(ctx, actorId, groupId, name, domain); err != nil {
+		return err
+	}
+
+	log.Infof("Permission %s [%s] of group %d removed by %d", name, domain, groupId, actorId)
+	if err := s.refreshGroup(ctx, groupId); err != nil {
+		log.Errorf("Permission of group %d is removed, but groups were not refreshed: %v", groupId, err)
+	}
+	s.invalidateGroups(ctx, InvalidationGroup, groupId)
+	return nil
+}
+
+// addGroupMember adds the user to the group until expiresAt, or permanently when it's nil. Changes of special
+// groups are not applied but wait for approval, in which case id of the request is returned
+func (s *Service) addGroupMember(ctx context.Context, actorId, groupId, userId int32, expiresAt *time.Time) (int32, error) {
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return 0, ErrInvalidMembership
+	}
+	if err := s.requireGlobalPermission(ctx, actorId, PermissionManageUsers, perm.AccessWrite); err != nil {
+		return 0, err
+	}
+	if s.db == nil {
+		return 0, fmt.Errorf("database is not initialized")
+	}
+
+	err := s.db.AddGroupMember(ctx, actorId, groupId, userId, expiresAt)
+	if errors.Is(err, db.ErrApprovalRequired) {
+		return s.requestMembershipChange(ctx, actorId, groupId, userId, schema.MembershipChangeAdd, expiresAt)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if expiresAt != nil {
+		log.Infof("User %d added to group %d by %d until %s", userId, groupId, actorId, expiresAt.Format(time.RFC3339))
+	} else {
+		log.Infof("User %d added to group %d by %d", userId, groupId, actorId)
+	}
+	s.reloadMembers(ctx, userId)
+	s.invalidateUsers(ctx, InvalidationMembership, userId)
+	return 0, nil
+}
+
+// addGroupMemberFromCli adds the user to the group by name, without approval even when the group is special
+func (s *Service) addGroupMemberFromCli(ctx context.Context, groupName string, userId int32) (int32, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("database is not initialized")
+	}
+
+	groupId, err := s.db.AddGroupMemberByName(ctx, groupName, userId)
+	if err != nil {
+		return 0, err
+	}
+
+	log.Infof("User %d added to group %d from CLI", userId, groupId)
+	s.invalidateUsers(ctx, InvalidationMembership, userId)
+	return groupId, nil
+}
+
+// removeGroupMember removes the user from the group. Changes of special groups are not applied but wait
+// for approval, in which case id of the request is returned
+func (s *Service) removeGroupMember(ctx context.Context, actorId, groupId, userId int32) (int32, error) {
+	if err := s.requireGlobalPermission(ctx, actorId, PermissionManageUsers, perm.AccessWrite); err != nil {
+		return 0, err
+	}
+	if s.db == nil {
+		return 0, fmt.Errorf("database is not initialized")
+	}
+
+	err := s.db.RemoveGroupMember(ctx, actorId, groupId, userId)
+	if errors.Is(err, db.ErrApprovalRequired) {
+		return s.requestMembershipChange(ctx, actorId, groupId, userId, schema.MembershipChangeRemove, nil)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	log.Infof("User %d removed from group %d by %d", userId, groupId, actorId)
+	s.reloadMembers(ctx, userId)
+	s.invalidateUsers(ctx, InvalidationMembership, userId)
+	return 0, nil
+}
+
+func (s *Service) requestMembershipChange(ctx context.Context, actorId, groupId, userId int32, change string, expiresAt *time.Time) (int32, error) {
+	request, err := s.db.RequestMembershipChange(ctx, &schema.MembershipRequestSchema{
+		GroupId:     groupId,
+		UserId:      userId,
+		Change:      change,
+		ExpiresAt:   expiresAt,
+		RequestedBy: actorId,
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	log.Infof("Membership request %d to %s user %d in special group %d made by %d", request.Id, change, userId, groupId, actorId)
+	return request.Id, nil
+}
+
+func (s *Service) listMembershipRequests(ctx context.Context, actorId int32) ([]schema.MembershipRequestSchema, error) {
+	if err := s.requireGlobalPermission(ctx, actorId, PermissionManageUsers, perm.AccessRead); err != nil {
+		return nil, err
+	}
+	if s.db == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+	return s.db.LoadPendingMembershipRequests(ctx)
+}
+
+// decideMembershipRequest approves or rejects a pending membership change of a special group. Approval
+// has to come from an admin other than the one who requested the change
+func (s *Service) decideMembershipRequest(ctx context.Context, actorId, requestId int32, approve bool) (*schema.MembershipRequestSchema, error) {
+	if err := s.requireGlobalPermission(ctx, actorId, PermissionManageUsers, perm.AccessWrite); err != nil {
+		return nil, err
+	}
+	if s.db == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+
+	request, err := s.db.DecideMembershipRequest(ctx, actorId, requestId, approve)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Membership request %d %s by %d", request.Id, request.Status, actorId)
+	if approve {
+		s.reloadMembers(ctx, request.UserId)
+		s.invalidateUsers(ctx, InvalidationMembership, request.UserId)
+	}
+	return request, nil
+}
+
+func (s *Service) listGroupMembers(ctx context.Context, actorId, groupId int32) ([]schema.GroupMemberSchema, error) {
+	if err := s.requireGlobalPermission(ctx, actorId, PermissionManageUsers, perm.AccessRead); err != nil {
+		return nil, err
+	}
+	if s.db == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+	return s.db.LoadGroupMembers(ctx, groupId)
+}
+
+// WatchMembershipExpiry periodically removes expired group memberships, reloads groups of affected cached
+// users and publishes group.membership_expired for each membership
+func (s *Service) WatchMembershipExpiry(ctx context.Context) {
+	ticker := time.NewTicker(MembershipExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := s.db.ExpireGroupMemberships(ctx)
+			if err != nil {
+				log.Errorf("Failed to expire group memberships: %v", err)
+				continue
+			}
+			userIds := make([]int32, 0, len(expired))
+			for i := range expired {
+				log.Infof("Membership of user %d in group %d expired", expired[i].UserId, expired[i].GroupId)
+				s.reloadMembers(ctx, expired[i].UserId)
+				s.publishMembershipExpired(ctx, &expired[i])
+				userIds = append(userIds, expired[i].UserId)
+			}
+			s.invalidateUsers(ctx, InvalidationMembership, userIds...)
+		}
+	}
+}
+
+func (s *Service) publishMembershipExpired(ctx context.Context, membership *schema.GroupMemberSchema) {
+	event := &kafka.GroupMembershipExpiredSchema{
+		GroupId:   membership.GroupId,
+		UserId:    membership.UserId,
+		ExpiredAt: time.Now(),
+	}
+	if membership.ExpiresAt != nil {
+		event.ExpiredAt = *membership.ExpiresAt
+	}
+	if err := s.kafka.PublishEvent(ctx, kafka.EventGroupMembershipExpired, event); err != nil {
+		log.Errorf("Failed to publish %s event for membership %d: %v", kafka.EventGroupMembershipExpired, membership.Id, err)
+	}
+}
+
+func groupMemberToProto(member *schema.GroupMemberSchema) *proto.GroupMember {
+	return &proto.GroupMember{
+		UserId:    member.UserId,
+		ExpiresAt: optionalTimestamp(member.ExpiresAt),
+		CreatedAt: timestamppb.New(member.CreatedAt),
+	}
+}
+
+func membershipRequestToProto(request *schema.MembershipRequestSchema) *proto.MembershipRequest {
+	return &proto.MembershipRequest{
+		Id:          request.Id,
+		GroupId:     request.GroupId,
+		UserId:      request.UserId,
+		Change:      request.Change,
+		ExpiresAt:   optionalTimestamp(request.ExpiresAt),
+		Status:      request.Status,
+		RequestedBy: request.RequestedBy,
+		DecidedBy:   derefUserId(request.DecidedBy),
+		CreatedAt:   timestamppb.New(request.CreatedAt),
+		DecidedAt:   optionalTimestamp(request.DecidedAt),
+	}
+}
+
+func groupToProto(g *group.Group) *proto.Group {
+	raw := g.GetSchema()
+	result := &proto.Group{
+		Id:        raw.Id,
+		ParentId:  raw.ParentId,
+		Name:      raw.Name,
+		IsSpecial: raw.IsSpecial,
+		CreatedAt: timestamppb.New(raw.CreatedAt),
+		UpdatedAt: timestamppb.New(raw.UpdatedAt),
+	}
+	if raw.Description != nil {
+		result.Description = *raw.Description
+	}
+	return result
+}
+
+// groupErrorResult logs internal errors and returns codes for a response
+func groupErrorResult(err error, action string) (int32, string) {
+	code, _ := groupErrorCode(err)
+	if code == 24003 {
+		log.Errorf("Failed to %s: %v", action, err)
+	}
+	return code, err.Error()
+}
+
+// GetGroupPermissions returns effective permissions of a group including the ones inherited from its
+// ancestors. Requester must have global manage_users with read access
+func (s *Service) GetGroupPermissions(ctx context.Context, in *proto.GetGroupPermissionsRequest) (*proto.GetGroupPermissionsResponse, error) {
+	log.Tracef("GetGroupPermissions")
+
+	if in.GroupId == 0 {
+		return &proto.GetGroupPermissionsResponse{Code: 24000, Error: "invalid group id"}, nil
+	}
+
+	if err := s.requireGlobalPermission(ctx, in.RequesterId, PermissionManageUsers, perm.AccessRead); err != nil {
+		code, message := groupErrorResult(err, "check permissions")
+		return &proto.GetGroupPermissionsResponse{Code: code, Error: message}, nil
+	}
+
+	g, exists := s.groups.Get(in.GroupId)
+	if !exists {
+		return &proto.GetGroupPermissionsResponse{Code: 24002, Error: ErrGroupNotLoaded.Error()}, nil
+	}
+
+	result := &proto.GetGroupPermissionsResponse{Code: 0, Name: g.GetName(), Chain: g.GetChain()}
+	for _, domain := range perm.Domains {
+		for _, permission := range g.GetPermissions().Get(domain) {
+			result.Permissions = append(result.Permissions, &proto.GroupPermission{
+				Name:       permission.Name,
+				Domain:     domain,
+				Read:       permission.Read,
+				Write:      permission.Write,
+				Delete:     permission.Delete,
+				GroupId:    permission.GetGroupId(),
+				DenyRead:   permission.DenyRead,
+				DenyWrite:  permission.DenyWrite,
+				DenyDelete: permission.DenyDelete,
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// SetGroupPermission creates or replaces a permission of the group. The permission has to be registered
+// in the catalog. Requester must have global manage_users with write access
+func (s *Service) SetGroupPermission(ctx context.Context, in *proto.SetGroupPermissionRequest) (*proto.SetGroupPermissionResponse, error) {
+	log.Tracef("SetGroupPermission")
+
+	if in.GroupId == 0 || in.Permission == nil {
+		return &proto.SetGroupPermissionResponse{Code: 24000, Error: "group id and permission are required"}, nil
+	}
+
+	permission := &schema.GroupPermissionSchema{
+		GroupId:    in.GroupId,
+		Permission: in.Permission.Name,
+		Domain:     in.Permission.Domain,
+		Read:       in.Permission.Read != 0,
+		Write:      in.Permission.Write != 0,
+		Delete:     in.Permission.Delete != 0,
+		DenyRead:   in.Permission.DenyRead != 0,
+		DenyWrite:  in.Permission.DenyWrite != 0,
+		DenyDelete: in.Permission.DenyDelete != 0,
+	}
+	if err := s.setGroupPermission(ctx, in.RequesterId, permission); err != nil {
+		code, message := groupErrorResult(err, "set group permission")
+		return &proto.SetGroupPermissionResponse{Code: code, Error: message}, nil
+	}
+
+	return &proto.SetGroupPermissionResponse{Code: 0}, nil
+}
+
+// RemoveGroupPermission removes a permission of the group. Requester must have global manage_users
+// with write access
+func (s *Service) RemoveGroupPermission(ctx context.Context, in *proto.RemoveGroupPermissionRequest) (*proto.RemoveGroupPermissionResponse, error) {
+	log.Tracef("RemoveGroupPermission")
+
+	if in.GroupId == 0 || in.Name == "" {
+		return &proto.RemoveGroupPermissionResponse{Code: 24000, Error: "group id and permission are required"}, nil
+	}
+
+	if err := s.removeGroupPermission(ctx, in.RequesterId, in.GroupId, in.Name, in.Domain); err != nil {
+		code, message := groupErrorResult(err, "remove group permission")
+		return &proto.RemoveGroupPermissionResponse{Code: code, Error: message}, nil
+	}
+
+	return &proto.RemoveGroupPermissionResponse{Code: 0}, nil
+}
+
+// ListGroups returns every initialized group. Requester must have global manage_users with read access
+func (s *Service) ListGroups(ctx context.Context, in *proto.ListGroupsRequest) (*proto.ListGroupsResponse, error) {
+	log.Tracef("ListGroups")
+
+	groups, err := s.listGroups(ctx, in.RequesterId)
+	if err != nil {
+		code, message := groupErrorResult(err, "list groups")
+		return &proto.ListGroupsResponse{Code: code, Error: message}, nil
+	}
+
+	result := &proto.ListGroupsResponse{Code: 0}
+	for _, g := range groups {
+		result.Groups = append(result.Groups, groupToProto(g))
+	}
+	return result, nil
+}
+
+// CreateGroup creates a group. Requester must have global manage_users with write access
+func (s *Service) CreateGroup(ctx context.Context, in *proto.CreateGroupRequest) (*proto.CreateGroupResponse, error) {
+	log.Tracef("CreateGroup")
+
+	g, err := s.createGroup(ctx, in.RequesterId, in.Name, in.Description, in.ParentId)
+	if err != nil {
+		code, message := groupErrorResult(err, "create group")
+		return &proto.CreateGroupResponse{Code: code, Error: message}, nil
+	}
+
+	return &proto.CreateGroupResponse{Code: 0, Group: groupToProto(g)}, nil
+}
+
+// UpdateGroup changes name, description or parent of the group. Requester must have global manage_users
+// with write access
+func (s *Service) UpdateGroup(ctx context.Context, in *proto.UpdateGroupRequest) (*proto.UpdateGroupResponse, error) {
+	log.Tracef("UpdateGroup")
+
+	if in.GroupId == 0 {
+		return &proto.UpdateGroupResponse{Code: 24000, Error: "invalid group id"}, nil
+	}
+
+	g, err := s.updateGroup(ctx, in.RequesterId, in.GroupId, in.Name, in.Description, in.ParentId)
+	if err != nil {
+		code, message := groupErrorResult(err, "update group")
+		return &proto.UpdateGroupResponse{Code: code, Error: message}, nil
+	}
+
+	return &proto.UpdateGroupResponse{Code: 0, Group: groupToProto(g)}, nil
+}
+
+// DeleteGroup deletes a group without child groups together with its permissions and memberships.
+// Requester must have global manage_users with delete access
+func (s *Service) DeleteGroup(ctx context.Context, in *proto.DeleteGroupRequest) (*proto.DeleteGroupResponse, error) {
+	log.Tracef("DeleteGroup")
+
+	if in.GroupId == 0 {
+		return &proto.DeleteGroupResponse{Code: 24000, Error: "invalid group id"}, nil
+	}
+
+	if err := s.deleteGroup(ctx, in.RequesterId, in.GroupId); err != nil {
+		code, message := groupErrorResult(err, "delete group")
+		return &proto.DeleteGroupResponse{Code: code, Error: message}, nil
+	}
+
+	return &proto.DeleteGroupResponse{Code: 0}, nil
+}
+
+// AddGroupMember adds the user to the group, until ExpiresAt when it's set. Additions to special groups
+// wait for approval and return PendingRequestId. Requester must have global manage_users with write access
+func (s *Service) AddGroupMember(ctx context.Context, in *proto.GroupMemberRequest) (*proto.GroupMemberResponse, error) {
+	log.Tracef("AddGroupMember")
+
+	if in.GroupId == 0 || in.UserId == 0 {
+		return &proto.GroupMemberResponse{Code: 24000, Error: "group id and user id are required"}, nil
+	}
+
+	var expiresAt *time.Time
+	if in.ExpiresAt != nil {
+		t := in.ExpiresAt.AsTime()
+		expiresAt = &t
+	}
+
+	requestId, err := s.addGroupMember(ctx, in.RequesterId, in.GroupId, in.UserId, expiresAt)
+	if err != nil {
+		code, message := groupErrorResult(err, "add group member")
+		return &proto.GroupMemberResponse{Code: code, Error: message}, nil
+	}
+
+	return &proto.GroupMemberResponse{Code: 0, PendingRequestId: requestId}, nil
+}
+
+// RemoveGroupMember removes the user from the group. Removals from special groups wait for approval and return
+// PendingRequestId. Requester must have global manage_users with write access
+func (s *Service) RemoveGroupMember(ctx context.Context, in *proto.GroupMemberRequest) (*proto.GroupMemberResponse, error) {
+	log.Tracef("RemoveGroupMember")
+
+	if in.GroupId == 0 || in.UserId == 0 {
+		return &proto.GroupMemberResponse{Code: 24000, Error: "group id and user id are required"}, nil
+	}
+
+	requestId, err := s.removeGroupMember(ctx, in.RequesterId, in.GroupId, in.UserId)
+	if err != nil {
+		code, message := groupErrorResult(err, "remove group member")
+		return &proto.GroupMemberResponse{Code: code, Error: message}, nil
+	}
+
+	return &proto.GroupMemberResponse{Code: 0, PendingRequestId: requestId}, nil
+}
+
+// ListGroupMembers returns active memberships of the group. Requester must have global manage_users with read access
+func (s *Service) ListGroupMembers(ctx context.Context, in *proto.ListGroupMembersRequest) (*proto.ListGroupMembersResponse, error) {
+	log.Tracef("ListGroupMembers")
+
+	if in.GroupId == 0 {
+		return &proto.ListGroupMembersResponse{Code: 24000, Error: "invalid group id"}, nil
+	}
+
+	members, err := s.listGroupMembers(ctx, in.RequesterId, in.GroupId)
+	if err != nil {
+		code, message := groupErrorResult(err, "list group members")
+		return &proto.ListGroupMembersResponse{Code: code, Error: message}, nil
+	}
+
+	result := &proto.ListGroupMembersResponse{Code: 0}
+	for i := range members {
+		result.UserIds = append(result.UserIds, members[i].UserId)
+		result.Members = append(result.Members, groupMemberToProto(&members[i]))
+	}
+	return result, nil
+}
+
+// ListMembershipRequests returns membership changes of special groups waiting for approval. Requester must
+// have global manage_users with read access
+func (s *Service) ListMembershipRequests(ctx context.Context, in *proto.ListMembershipRequestsRequest) (*proto.ListMembershipRequestsResponse, error) {
+	log.Tracef("ListMembershipRequests")
+
+	requests, err := s.listMembershipRequests(ctx, in.RequesterId)
+	if err != nil {
+		code, message := groupErrorResult(err, "list membership requests")
+		return &proto.ListMembershipRequestsResponse{Code: code, Error: message}, nil
+	}
+
+	result := &proto.ListMembershipRequestsResponse{Code: 0}
+	for i := range requests {
+		result.Requests = append(result.Requests, membershipRequestToProto(&requests[i]))
+	}
+	return result, nil
+}
+
+// ApproveMembershipRequest applies a pending membership change of a special group. Requester must have global
+// manage_users with write access and must not be the admin who requested the change
+func (s *Service) ApproveMembershipRequest(ctx context.Context, in *proto.MembershipDecisionRequest) (*proto.MembershipDecisionResponse, error) {
+	log.Tracef("ApproveMembershipRequest")
+	return s.membershipDecision(ctx, in, true)
+}
+
+// RejectMembershipRequest rejects a pending membership change of a special group. The admin who requested
+// the change can reject it to withdraw it. Requester must have global manage_users with write access
+func (s *Service) RejectMembershipRequest(ctx context.Context, in *proto.MembershipDecisionRequest) (*proto.MembershipDecisionResponse, error) {
+	log.Tracef("RejectMembershipRequest")
+	return s.membershipDecision(ctx, in, false)
+}
+
+func (s *Service) membershipDecision(ctx context.Context, in *proto.MembershipDecisionRequest, approve bool) (*proto.MembershipDecisionResponse, error) {
+	if in.RequestId == 0 {
+		return &proto.MembershipDecisionResponse{Code: 24000, Error: "invalid request id"}, nil
+	}
+
+	request, err := s.decideMembershipRequest(ctx, in.RequesterId, in.RequestId, approve)
+	if err != nil {
+		code, message := groupErrorResult(err, "decide membership request")
+		return &proto.MembershipDecisionResponse{Code: code, Error: message}, nil
+	}
+
+	return &proto.MembershipDecisionResponse{Code: 0, Request: membershipRequestToProto(request)}, nil
+}
+
+type groupResponse struct {
+	Id          int32     `json:"id"`
+	ParentId    int32     `json:"parent_id"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description"`
+	IsSpecial   bool      `json:"is_special"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type groupMemberResponse struct {
+	UserId    int32      `json:"user_id"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type membershipRequestResponse struct {
+	Id          int32      `json:"id"`
+	GroupId     int32      `json:"group_id"`
+	UserId      int32      `json:"user_id"`
+	Change      string     `json:"change"`
+	ExpiresAt   *time.Time `json:"expires_at"`
+	Status      string     `json:"status"`
+	RequestedBy int32      `json:"requested_by"`
+	DecidedBy   *int32     `json:"decided_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	DecidedAt   *time.Time `json:"decided_at"`
+}
+
+func newMembershipRequestResponse(request *schema.MembershipRequestSchema) *membershipRequestResponse {
+	return &membershipRequestResponse{
+		Id:          request.Id,
+		GroupId:     request.GroupId,
+		UserId:      request.UserId,
+		Change:      request.Change,
+		ExpiresAt:   request.ExpiresAt,
+		Status:      request.Status,
+		RequestedBy: request.RequestedBy,
+		DecidedBy:   request.DecidedBy,
+		CreatedAt:   request.CreatedAt,
+		DecidedAt:   request.DecidedAt,
+	}
+}
+
+func newGroupResponse(g *group.Group) *groupResponse {
+	raw := g.GetSchema()
+	return &groupResponse{
+		Id:          raw.Id,
+		ParentId:    raw.ParentId,
+		Name:        raw.Name,
+		Description: raw.Description,
+		IsSpecial:   raw.IsSpecial,
+		CreatedAt:   raw.CreatedAt,
+		UpdatedAt:   raw.UpdatedAt,
+	}
+}
+
+// handleGroupAdminRequest authenticates a REST request of the group administration API, decodes its body
+// into request and runs action on behalf of the requester. Result of the action is returned as JSON body
+func (s *Service) handleGroupAdminRequest(ctx context.Context, in *restproto.RestApiRequest, request any, action func(requesterId int32) (any, error)) (*restproto.RestApiResponse, error) {
+	requesterId, err := s.getRequestUserId(ctx, in)
+	if err != nil {
+		log.Debugf("Failed to authenticate request: %v", err)
+		return &restproto.RestApiResponse{Code: 24012, HttpCode: 401, Error: "unauthorized"}, nil
+	}
+
+	if request != nil && in.Body != "" {
+		if err := json.Unmarshal([]byte(in.Body), request); err != nil {
+			log.Debugf("Failed to unmarshal request body: %v", err)
+			return &restproto.RestApiResponse{Code: 24000, HttpCode: 400, Error: "failed to parse request"}, nil
+		}
+	}
+
+	result, err := action(requesterId)
+	if err != nil {
+		code, httpCode := groupErrorCode(err)
+		if httpCode == 500 {
+			log.Errorf("Group administration request failed: %v", err)
+		}
+		return &restproto.RestApiResponse{Code: code, HttpCode: httpCode, Error: err.Error()}, nil
+	}
+
+	if result == nil {
+		result = struct{}{}
+	}
+	body, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restproto.RestApiResponse{Code: 0, HttpCode: 200, Body: string(body)}, nil
+}
+
+// HandleListGroupsRequest returns all groups
+func (s *Service) HandleListGroupsRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleListGroupsRequest")
+	return s.handleGroupAdminRequest(ctx, in, nil, func(requesterId int32) (any, error) {
+		groups, err := s.listGroups(ctx, requesterId)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]*groupResponse, 0, len(groups))
+		for _, g := range groups {
+			result = append(result, newGroupResponse(g))
+		}
+		return map[string]any{"groups": result}, nil
+	})
+}
+
+// HandleCreateGroupRequest creates a group from {"name": "...", "description": "...", "parent_id": 2}
+func (s *Service) HandleCreateGroupRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleCreateGroupRequest")
+	request := struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		ParentId    int32  `json:"parent_id"`
+	}{}
+	return s.handleGroupAdminRequest(ctx, in, &request, func(requesterId int32) (any, error) {
+		g, err := s.createGroup(ctx, requesterId, request.Name, request.Description, request.ParentId)
+		if err != nil {
+			return nil, err
+		}
+		return newGroupResponse(g), nil
+	})
+}
+
+// HandleUpdateGroupRequest changes fields present in {"group_id": 5, "name": "...", "description": "...", "parent_id": 0}
+func (s *Service) HandleUpdateGroupRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleUpdateGroupRequest")
+	request := struct {
+		GroupId     int32   `json:"group_id"`
+		Name        *string `json:"name"`
+		Description *string `json:"description"`
+		ParentId    *int32  `json:"parent_id"`
+	}{}
+	return s.handleGroupAdminRequest(ctx, in, &request, func(requesterId int32) (any, error) {
+		g, err := s.updateGroup(ctx, requesterId, request.GroupId, request.Name, request.Description, request.ParentId)
+		if err != nil {
+			return nil, err
+		}
+		return newGroupResponse(g), nil
+	})
+}
+
+// HandleDeleteGroupRequest deletes a group from {"group_id": 5}
+func (s *Service) HandleDeleteGroupRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleDeleteGroupRequest")
+	request := struct {
+		GroupId int32 `json:"group_id"`
+	}{}
+	return s.handleGroupAdminRequest(ctx, in, &request, func(requesterId int32) (any, error) {
+		return nil, s.deleteGroup(ctx, requesterId, request.GroupId)
+	})
+}
+
+// HandleSetGroupPermissionRequest sets a permission of a group from
+// {"group_id": 5, "permission": "inventory.*", "domain": "global", "read": true, "deny_delete": true}
+func (s *Service) HandleSetGroupPermissionRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleSetGroupPermissionRequest")
+	request := struct {
+		GroupId    int32  `json:"group_id"`
+		Permission string `json:"permission"`
+		Domain     string `json:"domain"`
+		Read       bool   `json:"read"`
+		Write      bool   `json:"write"`
+		Delete     bool   `json:"delete"`
+		DenyRead   bool   `json:"deny_read"`
+		DenyWrite  bool   `json:"deny_write"`
+		DenyDelete bool   `json:"deny_delete"`
+	}{}
+	return s.handleGroupAdminRequest(ctx, in, &request, func(requesterId int32) (any, error) {
+		return nil, s.setGroupPermission(ctx, requesterId, &schema.GroupPermissionSchema{
+			GroupId:    request.GroupId,
+			Permission: request.Permission,
+			Domain:     request.Domain,
+			Read:       request.Read,
+			Write:      request.Write,
+			Delete:     request.Delete,
+			DenyRead:   request.DenyRead,
+			DenyWrite:  request.DenyWrite,
+			DenyDelete: request.DenyDelete,
+		})
+	})
+}
+
+// HandleRemoveGroupPermissionRequest removes a permission of a group from
+// {"group_id": 5, "permission": "inventory.*", "domain": "global"}
+func (s *Service) HandleRemoveGroupPermissionRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleRemoveGroupPermissionRequest")
+	request := struct {
+		GroupId    int32  `json:"group_id"`
+		Permission string `json:"permission"`
+		Domain     string `json:"domain"`
+	}{}
+	return s.handleGroupAdminRequest(ctx, in, &request, func(requesterId int32) (any, error) {
+		return nil, s.removeGroupPermission(ctx, requesterId, request.GroupId, request.Permission, request.Domain)
+	})
+}
+
+// HandleListGroupMembersRequest returns active memberships of a group from {"group_id": 5}
+func (s *Service) HandleListGroupMembersRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleListGroupMembersRequest")
+	request := struct {
+		GroupId int32 `json:"group_id"`
+	}{}
+	return s.handleGroupAdminRequest(ctx, in, &request, func(requesterId int32) (any, error) {
+		members, err := s.listGroupMembers(ctx, requesterId, request.GroupId)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]*groupMemberResponse, 0, len(members))
+		for _, member := range members {
+			result = append(result, &groupMemberResponse{
+				UserId:    member.UserId,
+				ExpiresAt: member.ExpiresAt,
+				CreatedAt: member.CreatedAt,
+			})
+		}
+		return map[string]any{"members": result}, nil
+	})
+}
+
+// HandleAddGroupMemberRequest adds a user to a group from {"group_id": 5, "user_id": 10}.
+// Optional "expires_at" in RFC 3339 format makes the membership time-limited
+func (s *Service) HandleAddGroupMemberRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleAddGroupMemberRequest")
+	request := struct {
+		GroupId   int32      `json:"group_id"`
+		UserId    int32      `json:"user_id"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}{}
+	return s.handleGroupAdminRequest(ctx, in, &request, func(requesterId int32) (any, error) {
+		requestId, err := s.addGroupMember(ctx, requesterId, request.GroupId, request.UserId, request.ExpiresAt)
+		return pendingResponse(requestId), err
+	})
+}
+
+// HandleRemoveGroupMemberRequest removes a user from a group from {"group_id": 5, "user_id": 10}
+func (s *Service) HandleRemoveGroupMemberRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleRemoveGroupMemberRequest")
+	request := struct {
+		GroupId int32 `json:"group_id"`
+		UserId  int32 `json:"user_id"`
+	}{}
+	return s.handleGroupAdminRequest(ctx, in, &request, func(requesterId int32) (any, error) {
+		requestId, err := s.removeGroupMember(ctx, requesterId, request.GroupId, request.UserId)
+		return pendingResponse(requestId), err
+	})
+}
+
+// pendingResponse tells REST clients that a membership change waits for approval
+func pendingResponse(requestId int32) any {
+	if requestId == 0 {
+		return nil
+	}
+	return map[string]any{"pending_request_id": requestId}
+}
+
+// HandleListMembershipRequestsRequest returns membership changes of special groups waiting for approval
+func (s *Service) HandleListMembershipRequestsRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleListMembershipRequestsRequest")
+	return s.handleGroupAdminRequest(ctx, in, nil, func(requesterId int32) (any, error) {
+		requests, err := s.listMembershipRequests(ctx, requesterId)
+		if err != nil {
+			return nil, err
+		}
+		result := make([]*membershipRequestResponse, 0, len(requests))
+		for i := range requests {
+			result = append(result, newMembershipRequestResponse(&requests[i]))
+		}
+		return map[string]any{"requests": result}, nil
+	})
+}
+
+// HandleApproveMembershipRequest applies a pending membership change from {"request_id": 3}
+func (s *Service) HandleApproveMembershipRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleApproveMembershipRequest")
+	return s.handleMembershipDecision(ctx, in, true)
+}
+
+// HandleRejectMembershipRequest rejects a pending membership change from {"request_id": 3}
+func (s *Service) HandleRejectMembershipRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleRejectMembershipRequest")
+	return s.handleMembershipDecision(ctx, in, false)
+}
+
+func (s *Service) handleMembershipDecision(ctx context.Context, in *restproto.RestApiRequest, approve bool) (*restproto.RestApiResponse, error) {
+	request := struct {
+		RequestId int32 `json:"request_id"`
+	}{}
+	return s.handleGroupAdminRequest(ctx, in, &request, func(requesterId int32) (any, error) {
+		decided, err := s.decideMembershipRequest(ctx, requesterId, request.RequestId, approve)
+		if err != nil {
+			return nil, err
+		}
+		return newMembershipRequestResponse(decided), nil
+	})
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/savageking-io/ogbuser/db"
+	"testing"
+)
+
+func TestService_GroupLifecycle(t *testing.T) {
+	s, conn := testService(t, &ServiceConfig{})
+	ctx := context.Background()
+	// Admin 1 is a superuser, player 2 has no permissions
+	setup := `
+		INSERT INTO users (username, password, email) VALUES
+			('admin', 'hash', 'admin@localhost'), ('player', 'hash', 'player@localhost');
+		INSERT INTO groups (name, is_special) VALUES ('Super Administrators', TRUE);
+		INSERT INTO group_members (group_id, user_id) VALUES (1, 1);`
+	if _, err := conn.Exec(setup); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.InitGroups(); err != nil {
+		t.Fatal(err)
+	}
+	name := func(s string) *string { return &s }
+
+	if _, err := s.createGroup(ctx, 2, "Moderators", "", 0); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("createGroup() by a player error = %v, want %v", err, ErrPermissionDenied)
+	}
+	if _, err := s.createGroup(ctx, 1, "  ", "", 0); !errors.Is(err, ErrInvalidGroupName) {
+		t.Errorf("createGroup() with a blank name error = %v, want %v", err, ErrInvalidGroupName)
+	}
+
+	moderators, err := s.createGroup(ctx, 1, " Moderators ", "Keep the chat clean", 0)
+	if err != nil {
+		t.Fatalf("createGroup() error = %v", err)
+	}
+	if moderators.GetName() != "Moderators" {
+		t.Errorf("createGroup() name = %q, want trimmed Moderators", moderators.GetName())
+	}
+	if _, ok := s.groups.Get(moderators.GetId()); !ok {
+		t.Errorf("created group isn't loaded")
+	}
+	if _, err := s.createGroup(ctx, 1, "Moderators", "", 0); !errors.Is(err, db.ErrGroupNameTaken) {
+		t.Errorf("createGroup() with a taken name error = %v, want %v", err, db.ErrGroupNameTaken)
+	}
+	juniors, err := s.createGroup(ctx, 1, "Junior Moderators", "", moderators.GetId())
+	if err != nil {
+		t.Fatalf("createGroup() of a child error = %v", err)
+	}
+
+	renamed, err := s.updateGroup(ctx, 1, moderators.GetId(), name("Mods"), nil, nil)
+	if err != nil {
+		t.Fatalf("updateGroup() error = %v", err)
+	}
+	if loaded, _ := s.groups.Get(moderators.GetId()); renamed.GetName() != "Mods" || loaded.GetName() != "Mods" {
+		t.Errorf("updateGroup() name = %q, loaded %q, want Mods", renamed.GetName(), loaded.GetName())
+	}
+	parent := juniors.GetId()
+	if _, err := s.updateGroup(ctx, 1, moderators.GetId(), nil, nil, &parent); !errors.Is(err, db.ErrGroupParentCycle) {
+		t.Errorf("updateGroup() with a descendant as parent error = %v, want %v", err, db.ErrGroupParentCycle)
+	}
+	if _, err := s.updateGroup(ctx, 2, moderators.GetId(), name("Players"), nil, nil); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("updateGroup() by a player error = %v, want %v", err, ErrPermissionDenied)
+	}
+
+	// Special groups keep their name and can't be deleted, other fields can change
+	if _, err := s.updateGroup(ctx, 1, 1, name("Admins"), nil, nil); !errors.Is(err, db.ErrGroupProtected) {
+		t.Errorf("updateGroup() renaming a special group error = %v, want %v", err, db.ErrGroupProtected)
+	}
+	if _, err := s.updateGroup(ctx, 1, 1, nil, name("Full access"), nil); err != nil {
+		t.Errorf("updateGroup() of a special group description error = %v", err)
+	}
+	if err := s.deleteGroup(ctx, 1, 1); !errors.Is(err, db.ErrGroupProtected) {
+		t.Errorf("deleteGroup() of a special group error = %v, want %v", err, db.ErrGroupProtected)
+	}
+
+	if err := s.deleteGroup(ctx, 1, moderators.GetId()); !errors.Is(err, db.ErrGroupHasChildren) {
+		t.Errorf("deleteGroup() of a parent error = %v, want %v", err, db.ErrGroupHasChildren)
+	}
+	if err := s.deleteGroup(ctx, 2, juniors.GetId()); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("deleteGroup() by a player error = %v, want %v", err, ErrPermissionDenied)
+	}
+	if err := s.deleteGroup(ctx, 1, juniors.GetId()); err != nil {
+		t.Fatalf("deleteGroup() error = %v", err)
+	}
+	if _, ok := s.groups.Get(juniors.GetId()); ok {
+		t.Errorf("deleted group is still loaded")
+	}
+	if err := s.deleteGroup(ctx, 1, juniors.GetId()); !errors.Is(err, db.ErrGroupNotFound) {
+		t.Errorf("deleteGroup() of a deleted group error = %v, want %v", err, db.ErrGroupNotFound)
+	}
+}
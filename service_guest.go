@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	restproto "github.com/savageking-io/ogbrest/proto"
+	"github.com/savageking-io/ogbuser/db"
+	"github.com/savageking-io/ogbuser/schema"
+	"github.com/savageking-io/ogbuser/user"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+const (
+	guestSecretLength        = 32
+	guestUsernameSuffixBytes = 5
+	guestUsernameAttempts    = 3
+)
+
+// checkGuestGroup makes sure the group new guests are added to exists and doesn't make them superusers.
+// Migrations don't create groups, so it has to be created first, e.g. with a policy file
+func (s *Service) checkGuestGroup() error {
+	config := s.config.Guest
+	if !config.Enabled || config.GroupId == 0 {
+		return nil
+	}
+
+	g, ok := s.groups.Get(config.GroupId)
+	if !ok {
+		return fmt.Errorf("guest.group_id %d doesn't exist. Create the group, e.g. with a policy file, or set guest.group_id to 0", config.GroupId)
+	}
+	if g.IsSpecial() {
+		return fmt.Errorf("guest.group_id %d is special group %q, guests would be superusers", config.GroupId, g.GetName())
+	}
+	return nil
+}
+
+// HandleAuthGuestRequest will authenticate a guest by the device-bound secret. When the secret is omitted
+// a new guest account is created and the generated secret is returned. Client must store it on the device
+func (s *Service) HandleAuthGuestRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleAuthGuestRequest")
+
+	if !s.config.Guest.Enabled {
+		return &restproto.RestApiResponse{
+			Code:     14000,
+			HttpCode: 403,
+			Error:    "guest accounts are disabled",
+		}, nil
+	}
+
+	request := struct {
+		Secret string `json:"secret"`
+	}{}
+
+	if in.Body != "" {
+		if err := json.Unmarshal([]byte(in.Body), &request); err != nil {
+			log.Debugf("Failed to unmarshal request body: %v", err)
+			return &restproto.RestApiResponse{
+				Code:     14001,
+				HttpCode: 400,
+				Error:    "failed to parse request",
+			}, nil
+		}
+	}
+
+	var u *user.User
+	secret := request.Secret
+	if secret == "" {
+		var err error
+		secret, err = generateGuestSecret()
+		if err != nil {
+			log.Errorf("Failed to generate guest secret: %v", err)
+			return &restproto.RestApiResponse{
+				Code:     14003,
+				HttpCode: 500,
+				Error:    "failed to create guest",
+			}, nil
+		}
+		u, err = s.createGuest(ctx, hashGuestSecret(secret))
+		if err != nil {
+			log.Errorf("Failed to create guest: %v", err)
+			return &restproto.RestApiResponse{
+				Code:     14003,
+				HttpCode: 500,
+				Error:    "failed to create guest",
+			}, nil
+		}
+		log.Infof("Created guest user %d [%s]", u.GetId(), u.GetUsername())
+	} else {
+		u = user.NewUser(s.db, nil)
+		if err := u.LoadByGuestSecret(ctx, hashGuestSecret(secret)); err != nil {
+			if errors.Is(err, db.ErrUserNotFound) {
+				log.Debugf("Guest not found by secret")
+				return &restproto.RestApiResponse{
+					Code:     14002,
+					HttpCode: 401,
+					Error:    "unknown guest secret",
+				}, nil
+			}
+			log.Errorf("Failed to load guest: %v", err)
+			return &restproto.RestApiResponse{
+				Code:     14004,
+				HttpCode: 500,
+				Error:    err.Error(),
+			}, nil
+		}
+	}
+
+	if blocked := s.loginBlockedResponse(ctx, u.GetId(), schema.PlatformWeb); blocked != nil {
+		return blocked, nil
+	}
+
+	if err := s.attachGroups(ctx, u); err != nil {
+		log.Errorf("Failed to load groups: %v", err)
+		return &restproto.RestApiResponse{
+			Code:     14005,
+			HttpCode: 500,
+			Error:    err.Error(),
+		}, nil
+	}
+
+	if err := s.users.Add(u); err != nil {
+		log.Errorf("failed to add user to cache: %v", err)
+		return &restproto.RestApiResponse{
+			Code:     14005,
+			HttpCode: 500,
+			Error:    "user load error",
+		}, nil
+	}
+
+	session, err := u.InitializeSession(ctx, schema.PlatformWeb)
+	if err != nil {
+		log.Errorf("Failed to initialize session: %v", err)
+		return &restproto.RestApiResponse{
+			Code:     14006,
+			HttpCode: 500,
+			Error:    err.Error(),
+		}, nil
+	}
+
+	body, err := json.Marshal(struct {
+		Id       int32  `json:"id"`
+		Username string `json:"username"`
+		Token    string `json:"token"`
+		Secret   string `json:"secret"`
+	}{u.GetId(), u.GetUsername(), session.Token, secret})
+	if err != nil {
+		return nil, err
+	}
+
+	return &restproto.RestApiResponse{
+		Code:     0,
+		HttpCode: 200,
+		Body:     string(body),
+	}, nil
+}
+
+// HandleUpgradeAccountRequest will turn the guest behind the session into a full account.
+// Request must contain either email and password (username is optional) or a platform ticket.
+// User id, groups and all linked data are kept
+func (s *Service) HandleUpgradeAccountRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleUpgradeAccountRequest")
+
+	userId, err := s.getRequestUserId(ctx, in)
+	if err != nil {
+		log.Debugf("Failed to authenticate request: %v", err)
+		return &restproto.RestApiResponse{
+			Code:     14101,
+			HttpCode: 401,
+			Error:    "unauthorized",
+		}, nil
+	}
+
+	request := struct {
+		Username string `json:"username"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+		Platform string `json:"platform"`
+		Token    string `json:"token"`
+	}{}
+
+	if err := json.Unmarshal([]byte(in.Body), &request); err != nil {
+		log.Debugf("Failed to unmarshal request body: %v", err)
+		return &restproto.RestApiResponse{
+			Code:     14100,
+			HttpCode: 400,
+			Error:    "failed to parse request",
+		}, nil
+	}
+
+	var upgradeErr error
+	if request.Platform != "" {
+		platformUserId, err := s.verifyPlatformTicket(ctx, request.Platform, request.Token)
+		if err != nil {
+			log.Debugf("Platform verification failed: %v", err)
+			return &restproto.RestApiResponse{
+				Code:     14106,
+				HttpCode: 400,
+				Error:    "failed to verify platform",
+			}, nil
+		}
+		_, upgradeErr = s.db.UpgradeGuestWithPlatform(ctx, userId, request.Platform, platformUserId)
+	} else {
+		if err := validateCredentials(request.Username, request.Email, request.Password); err != nil {
+			return &restproto.RestApiResponse{
+				Code:     14103,
+				HttpCode: 400,
+				Error:    err.Error(),
+			}, nil
+		}
+		if request.Username != "" {
+			if err := s.usernames.Validate(request.Username); err != nil {
+				return &restproto.RestApiResponse{
+					Code:     14103,
+					HttpCode: 400,
+					Error:    err.Error(),
+				}, nil
+			}
+		}
+		passwordHash, err := HashPassword(request.Password)
+		if err != nil {
+			log.Errorf("Failed to hash password: %v", err)
+			return &restproto.RestApiResponse{
+				Code:     14108,
+				HttpCode: 500,
+				Error:    "failed to upgrade account",
+			}, nil
+		}
+		_, upgradeErr = s.db.UpgradeGuestWithCredentials(ctx, userId, request.Username, request.Email, passwordHash)
+	}
+
+	if upgradeErr != nil {
+		switch {
+		case errors.Is(upgradeErr, db.ErrNotGuest):
+			return &restproto.RestApiResponse{Code: 14102, HttpCode: 409, Error: upgradeErr.Error()}, nil
+		case errors.Is(upgradeErr, db.ErrUsernameTaken), errors.Is(upgradeErr, db.ErrUsernameHeld):
+			return &restproto.RestApiResponse{Code: 14104, HttpCode: 409, Error: upgradeErr.Error()}, nil
+		case errors.Is(upgradeErr, db.ErrEmailTaken):
+			return &restproto.RestApiResponse{Code: 14105, HttpCode: 409, Error: upgradeErr.Error()}, nil
+		case errors.Is(upgradeErr, db.ErrPlatformLinked):
+			return &restproto.RestApiResponse{Code: 14107, HttpCode: 409, Error: upgradeErr.Error()}, nil
+		}
+		log.Errorf("Failed to upgrade guest %d: %v", userId, upgradeErr)
+		return &restproto.RestApiResponse{
+			Code:     14108,
+			HttpCode: 500,
+			Error:    "failed to upgrade account",
+		}, nil
+	}
+
+	// Drop the cached guest so the next lookup picks up new username and credentials
+	_ = s.users.Delete(userId)
+	u, err := s.users.GetById(userId)
+	if err != nil {
+		log.Errorf("Failed to reload upgraded user %d: %v", userId, err)
+		return &restproto.RestApiResponse{
+			Code:     14108,
+			HttpCode: 500,
+			Error:    "failed to upgrade account",
+		}, nil
+	}
+
+	log.Infof("Guest %d upgraded to a full account [%s]", u.GetId(), u.GetUsername())
+
+	body, err := json.Marshal(struct {
+		Id       int32  `json:"id"`
+		Username string `json:"username"`
+		Email    string `json:"email"`
+	}{u.GetId(), u.GetUsername(), u.GetEmail()})
+	if err != nil {
+		return nil, err
+	}
+
+	return &restproto.RestApiResponse{
+		Code:     0,
+		HttpCode: 200,
+		Body:     string(body),
+	}, nil
+}
+
+// createGuest stores a new guest with a generated username
+func (s *Service) createGuest(ctx context.Context, secretHash string) (*user.User, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+
+	for attempt := 0; attempt < guestUsernameAttempts; attempt++ {
+		username, err := generateGuestUsername(s.config.Guest.UsernamePrefix)
+		if err != nil {
+			return nil, err
+		}
+
+		raw, err := s.db.CreateGuestUser(ctx, username, secretHash, s.config.Guest.GroupId)
+		if errors.Is(err, db.ErrUsernameTaken) {
+			log.Debugf("Generated guest username %s is taken, retrying", username)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return user.NewUser(s.db, raw), nil
+	}
+
+	return nil, fmt.Errorf("failed to generate unique guest username after %d attempts", guestUsernameAttempts)
+}
+
+func generateGuestSecret() (string, error) {
+	secret := make([]byte, guestSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// hashGuestSecret returns hex encoded SHA-256 of the secret. Secrets are random and long enough
+// for a plain hash, which also allows looking guests up by it
+func hashGuestSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateGuestUsername(prefix string) (string, error) {
+	if prefix == "" {
+		prefix = "guest_"
+	}
+	suffix := make([]byte, guestUsernameSuffixBytes)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(suffix), nil
+}
+
+// validateCredentials checks credentials provided for a new full account
+func validateCredentials(username, email, password string) error {
+	if strings.Contains(username, "@") {
+		return fmt.Errorf("username can't contain @")
+	}
+	if len(username) > 50 {
+		return fmt.Errorf("username is too long")
+	}
+	if email == "" || !strings.Contains(email, "@") || len(email) > 100 {
+		return fmt.Errorf("invalid email")
+	}
+	if password == "" {
+		return fmt.Errorf("empty password")
+	}
+	return nil
+}
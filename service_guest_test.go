@@ -0,0 +1,55 @@
+package main
+
+import (
+	"github.com/savageking-io/ogbuser/group"
+	"github.com/savageking-io/ogbuser/schema"
+	"testing"
+)
+
+func TestService_checkGuestGroup(t *testing.T) {
+	groups := group.NewGroupsData()
+	groups.Add(group.NewGroupFromSchema(nil, &schema.GroupSchema{Id: 1, Name: "Super Administrators", IsSpecial: true}))
+	groups.Add(group.NewGroupFromSchema(nil, &schema.GroupSchema{Id: 2, Name: "Guests"}))
+
+	tests := []struct {
+		name    string
+		config  GuestConfig
+		wantErr bool
+	}{
+		{"Disabled", GuestConfig{Enabled: false, GroupId: 3}, false},
+		{"No group", GuestConfig{Enabled: true, GroupId: 0}, false},
+		{"Regular group", GuestConfig{Enabled: true, GroupId: 2}, false},
+		{"Missing group", GuestConfig{Enabled: true, GroupId: 3}, true},
+		{"Special group", GuestConfig{Enabled: true, GroupId: 1}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{config: &ServiceConfig{Guest: tt.config}, groups: groups}
+			if err := s.checkGuestGroup(); (err != nil) != tt.wantErr {
+				t.Errorf("checkGuestGroup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGenerateGuestUsername(t *testing.T) {
+	tests := []struct {
+		name       string
+		prefix     string
+		wantPrefix string
+	}{
+		{"Default prefix", "", "guest_"},
+		{"Configured prefix", "player_", "player_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := generateGuestUsername(tt.prefix)
+			if err != nil {
+				t.Fatalf("generateGuestUsername() error = %v", err)
+			}
+			if len(got) != len(tt.wantPrefix)+2*guestUsernameSuffixBytes || got[:len(tt.wantPrefix)] != tt.wantPrefix {
+				t.Errorf("generateGuestUsername() = %q, want %s followed by %d hex characters", got, tt.wantPrefix, 2*guestUsernameSuffixBytes)
+			}
+		})
+	}
+}
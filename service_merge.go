@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/savageking-io/ogbuser/db"
+	"github.com/savageking-io/ogbuser/kafka"
+	"github.com/savageking-io/ogbuser/perm"
+	"github.com/savageking-io/ogbuser/proto"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
+	"time"
+)
+
+// mergeUsers merges source user into target user, drops both from the cache and notifies other services.
+// actorId is 0 when merge is started from CLI
+func (s *Service) mergeUsers(ctx context.Context, targetId, sourceId, actorId int32) (*schema.MergeResultSchema, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+
+	result, err := s.db.MergeUsers(ctx, targetId, sourceId, actorId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Target is reloaded with new platforms and groups on the next lookup
+	_ = s.users.Delete(sourceId)
+	_ = s.users.Delete(targetId)
+
+	log.Infof("User %d merged into %d: platforms=%d groups=%d sessions=%d membership requests=%v",
+		sourceId, targetId, result.MovedPlatforms, result.AddedGroups, result.RevokedSessions, result.MembershipRequests)
+
+	event := &kafka.UserMergedSchema{
+		TargetUserId: targetId,
+		SourceUserId: sourceId,
+		MergedBy:     actorId,
+		MergedAt:     time.Now(),
+	}
+	if err := s.kafka.PublishEvent(ctx, kafka.EventUserMerged, event); err != nil {
+		// Merge is already committed. Consumers have to be re-notified manually
+		log.Errorf("Failed to publish %s event for %d -> %d: %v", kafka.EventUserMerged, sourceId, targetId, err)
+	}
+	s.invalidateUsers(ctx, InvalidationAccount, targetId, sourceId)
+
+	return result, nil
+}
+
+func mergeErrorCode(err error) int32 {
+	switch {
+	case errors.Is(err, db.ErrMergeSameUser):
+		return 16000
+	case errors.Is(err, ErrPermissionDenied):
+		return 16001
+	case errors.Is(err, db.ErrUserNotFound):
+		return 16002
+	case errors.Is(err, db.ErrMergeConflict):
+		return 16003
+	}
+	return 16004
+}
+
+// MergeUsers merges SourceUserId into TargetUserId. Requester must have global manage_users with write access
+func (s *Service) MergeUsers(ctx context.Context, in *proto.MergeUsersRequest) (*proto.MergeUsersResponse, error) {
+	log.Tracef("MergeUsers")
+
+	if in.TargetUserId == 0 || in.SourceUserId == 0 {
+		return &proto.MergeUsersResponse{Code: 16000, Error: "invalid user id"}, nil
+	}
+
+	if err := s.requireGlobalPermission(ctx, in.RequesterId, PermissionManageUsers, perm.AccessWrite); err != nil {
+		return &proto.MergeUsersResponse{Code: mergeErrorCode(err), Error: err.Error()}, nil
+	}
+
+	result, err := s.mergeUsers(ctx, in.TargetUserId, in.SourceUserId, in.RequesterId)
+	if err != nil {
+		code := mergeErrorCode(err)
+		if code == 16004 {
+			log.Errorf("Failed to merge user %d into %d: %v", in.SourceUserId, in.TargetUserId, err)
+		}
+		return &proto.MergeUsersResponse{Code: code, Error: err.Error()}, nil
+	}
+
+	return &proto.MergeUsersResponse{
+		Code:               0,
+		MovedPlatforms:     result.MovedPlatforms,
+		AddedGroups:        result.AddedGroups,
+		RevokedSessions:    result.RevokedSessions,
+		MembershipRequests: result.MembershipRequests,
+	}, nil
+}
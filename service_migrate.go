@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+)
+
+// MigrateDatabase applies pending migrations when postgres.auto_migrate is enabled. Otherwise it fails
+// until the schema is migrated with "ogbuser migrate up", so the service never runs against a schema it
+// doesn't expect. Dev fixtures are inserted afterwards when enabled
+func (s *Service) MigrateDatabase(ctx context.Context) error {
+	if s.db == nil {
+		return fmt.Errorf("database is not initialized")
+	}
+	config := s.config.Postgres
+
+	if config.AutoMigrate {
+		migrations, err := s.db.MigrateUp(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to migrate database: %w", err)
+		}
+		if len(migrations) > 0 {
+			log.Infof("Database migrated to version %d", migrations[len(migrations)-1].Version)
+		}
+	} else if err := s.db.CheckMigrations(ctx); err != nil {
+		return fmt.Errorf("%w. Run \"ogbuser migrate up\" or enable postgres.auto_migrate", err)
+	}
+
+	if config.DevFixtures {
+		log.Warnf("Dev fixtures are enabled. Never enable them in production")
+		applied, err := s.db.ApplyDevFixtures(ctx)
+		if err != nil {
+			return err
+		}
+		if applied {
+			log.Infof("Dev fixtures inserted")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/savageking-io/ogbuser/db"
+	"github.com/savageking-io/ogbuser/group"
+	"github.com/savageking-io/ogbuser/perm"
+	"github.com/savageking-io/ogbuser/proto"
+	"github.com/savageking-io/ogbuser/schema"
+	"github.com/savageking-io/ogbuser/user"
+	log "github.com/sirupsen/logrus"
+	"strings"
+)
+
+// ServiceName is the name ogbuser registers its own permissions under
+const ServiceName = "ogbuser"
+
+// ownPermissions are permissions checked by ogbuser itself
+var ownPermissions = []schema.PermissionCatalogSchema{
+	{Name: PermissionManageUsers, Domain: perm.DomainGlobal, Description: "Administer users, groups and sanctions"},
+}
+
+// registerPermissions validates and stores permissions owned by the service
+func (s *Service) registerPermissions(ctx context.Context, service string, permissions []schema.PermissionCatalogSchema) (int, int, error) {
+	if s.db == nil {
+		return 0, 0, fmt.Errorf("database is not initialized")
+	}
+
+	if service == "" {
+		return 0, 0, fmt.Errorf("%w: service name is empty", perm.ErrInvalidPermission)
+	}
+
+	for i := range permissions {
+		permissions[i].Description = strings.TrimSpace(permissions[i].Description)
+		if err := perm.Validate(permissions[i].Name, permissions[i].Domain); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	registered, updated, err := s.db.RegisterPermissions(ctx, service, permissions)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if registered > 0 || updated > 0 {
+		log.Infof("Service %s registered %d and updated %d permissions", service, registered, updated)
+	}
+	return registered, updated, nil
+}
+
+func catalogErrorCode(err error) int32 {
+	switch {
+	case errors.Is(err, perm.ErrInvalidPermission):
+		return 25000
+	case errors.Is(err, db.ErrPermissionOwned):
+		return 25001
+	}
+	return 25002
+}
+
+// RegisterPermission stores permissions owned by the calling service in the catalog. Only registered
+// permissions can be assigned to groups
+func (s *Service) RegisterPermission(ctx context.Context, in *proto.RegisterPermissionRequest) (*proto.RegisterPermissionResponse, error) {
+	log.Tracef("RegisterPermission")
+
+	permissions := make([]schema.PermissionCatalogSchema, 0, len(in.Permissions))
+	for _, definition := range in.Permissions {
+		permissions = append(permissions, schema.PermissionCatalogSchema{
+			Name:        definition.Name,
+			Domain:      definition.Domain,
+			Description: definition.Description,
+		})
+	}
+
+	registered, updated, err := s.registerPermissions(ctx, in.Service, permissions)
+	if err != nil {
+		code := catalogErrorCode(err)
+		if code == 25002 {
+			log.Errorf("Failed to register permissions of %s: %v", in.Service, err)
+		}
+		return &proto.RegisterPermissionResponse{Code: code, Error: err.Error()}, nil
+	}
+
+	return &proto.RegisterPermissionResponse{Code: 0, Registered: int32(registered), Updated: int32(updated)}, nil
+}
+
+// ListPermissions returns the permission catalog
+func (s *Service) ListPermissions(ctx context.Context, in *proto.ListPermissionsRequest) (*proto.ListPermissionsResponse, error) {
+	log.Tracef("ListPermissions")
+
+	if s.db == nil {
+		return &proto.ListPermissionsResponse{Code: 25002, Error: "database is not initialized"}, nil
+	}
+
+	catalog, err := s.db.LoadPermissionCatalog(ctx, in.Service)
+	if err != nil {
+		log.Errorf("Failed to load permission catalog: %v", err)
+		return &proto.ListPermissionsResponse{Code: 25002, Error: err.Error()}, nil
+	}
+
+	result := &proto.ListPermissionsResponse{Code: 0}
+	for _, entry := range catalog {
+		result.Permissions = append(result.Permissions, &proto.PermissionDefinition{
+			Name:        entry.Name,
+			Domain:      entry.Domain,
+			Description: entry.Description,
+			Version:     entry.Version,
+			Service:     entry.Service,
+		})
+	}
+	return result, nil
+}
+
+// superuserBypass returns the special group through which the user bypasses permission checks, or nil.
+// Every bypass is recorded in the audit log together with checks, and when it can't be recorded the user
+// is checked like everyone else
+func (s *Service) superuserBypass(ctx context.Context, u *user.User, checks ...map[string]any) *group.Group {
+	special := u.SuperuserGroup()
+	if special == nil {
+		return nil
+	}
+	if s.db == nil {
+		log.Errorf("Can't record superuser bypass of user %d without database, checking permissions instead", u.GetId())
+		return nil
+	}
+
+	details := map[string]any{"group_id": special.GetId(), "checks": checks}
+	if err := s.db.RecordAudit(ctx, u.GetId(), db.AuditActionSuperuserBypass, u.GetId(), details); err != nil {
+		log.Errorf("Failed to record superuser bypass of user %d, checking permissions instead: %v", u.GetId(), err)
+		return nil
+	}
+
+	log.Infof("User %d bypassed %d permission checks as member of special group %d", u.GetId(), len(checks), special.GetId())
+	return special
+}
+
+// bypassedCheck describes a single check for the audit log of a superuser bypass
+func bypassedCheck(permission, domain string, resource perm.Resource) map[string]any {
+	check := map[string]any{"permission": permission, "domain": domain}
+	if resource.IsSet() {
+		check["resource_owner_id"] = resource.OwnerId
+		check["party_id"] = resource.PartyId
+		check["guild_id"] = resource.GuildId
+	}
+	return check
+}
+
+// checkPermission returns the permission of the user in the domain, or for the resource when it's set.
+// Members of special groups get full access
+func (s *Service) checkPermission(ctx context.Context, u *user.User, permission, domain string, resource perm.Resource, resolver perm.MembershipResolver) (*perm.Permission, error) {
+	return s.evaluatePermission(ctx, u, permission, domain, resource, resolver, true)
+}
+
+// evaluatePermission is checkPermission that records a superuser bypass only when record is set, so
+// explaining a check to an admin doesn't show up as a check made by the user
+func (s *Service) evaluatePermission(ctx context.Context, u *user.User, permission, domain string, resource perm.Resource, resolver perm.MembershipResolver, record bool) (*perm.Permission, error) {
+	if err := perm.ValidateTarget(domain, resource); err != nil {
+		return nil, err
+	}
+	if special := u.SuperuserGroup(); special != nil {
+		if !record || s.superuserBypass(ctx, u, bypassedCheck(permission, domain, resource)) != nil {
+			return perm.NewSuperuserPermission(permission, domain, special.GetId()), nil
+		}
+	}
+	return u.Permissions().Check(ctx, u.GetId(), permission, domain, resource, resolver)
+}
+
+// MaxPermissionChecks limits users times queries of a single CheckPermissions call
+const MaxPermissionChecks = 10000
+
+// CheckPermissions answers every query for every user. Effective permissions of each user are taken once
+// and membership answers are shared by all queries of the call
+func (s *Service) CheckPermissions(ctx context.Context, in *proto.CheckPermissionsRequest) (*proto.CheckPermissionsResponse, error) {
+	log.Tracef("CheckPermissions")
+
+	if len(in.UserIds) == 0 || len(in.Queries) == 0 {
+		return &proto.CheckPermissionsResponse{Code: 26000, Error: "user ids and queries are required"}, nil
+	}
+	if len(in.UserIds)*len(in.Queries) > MaxPermissionChecks {
+		return &proto.CheckPermissionsResponse{Code: 26000, Error: fmt.Sprintf("at most %d checks are allowed", MaxPermissionChecks)}, nil
+	}
+
+	queries := make([]perm.Resource, len(in.Queries))
+	for i, query := range in.Queries {
+		queries[i] = perm.Resource{OwnerId: query.ResourceOwnerId, PartyId: query.PartyId, GuildId: query.GuildId}
+		if err := perm.ValidateTarget(query.Domain, queries[i]); err != nil {
+			return &proto.CheckPermissionsResponse{Code: 26000, Error: fmt.Sprintf("query %d: %v", i, err)}, nil
+		}
+	}
+
+	resolver := perm.NewCachingResolver(s.members)
+	result := &proto.CheckPermissionsResponse{Code: 0, Users: make([]*proto.UserPermissionResults, 0, len(in.UserIds))}
+	for _, userId := range in.UserIds {
+		userResults := &proto.UserPermissionResults{UserId: userId}
+		result.Users = append(result.Users, userResults)
+
+		u, err := s.users.GetById(userId)
+		if err != nil {
+			userResults.Code, userResults.Error = 26001, err.Error()
+			if !errors.Is(err, db.ErrUserNotFound) {
+				userResults.Code = 26002
+				log.Errorf("Failed to load user %d: %v", userId, err)
+			}
+			continue
+		}
+
+		userResults.Results = make([]*proto.PermissionResult, 0, len(in.Queries))
+		if s.bypassBatch(ctx, u, in.Queries, queries) {
+			for range in.Queries {
+				userResults.Results = append(userResults.Results, &proto.PermissionResult{Read: 1, Write: 1, Delete: 1})
+			}
+			continue
+		}
+
+		perms := u.Permissions()
+		for i, query := range in.Queries {
+			permission, err := perms.Check(ctx, userId, query.Permission, query.Domain, queries[i], resolver)
+			if err != nil {
+				log.Errorf("Failed to check %s of user %d: %v", query.Permission, userId, err)
+				userResults.Code, userResults.Error, userResults.Results = 26002, err.Error(), nil
+				break
+			}
+			userResults.Results = append(userResults.Results, &proto.PermissionResult{
+				Read:   permission.Effective(perm.AccessRead),
+				Write:  permission.Effective(perm.AccessWrite),
+				Delete: permission.Effective(perm.AccessDelete),
+			})
+		}
+	}
+
+	return result, nil
+}
+
+// bypassBatch reports whether the user bypasses all queries of CheckPermissions as a superuser.
+// The whole batch is recorded as a single audit entry
+func (s *Service) bypassBatch(ctx context.Context, u *user.User, queries []*proto.PermissionQuery, resources []perm.Resource) bool {
+	if u.SuperuserGroup() == nil {
+		return false
+	}
+	checks := make([]map[string]any, 0, len(queries))
+	for i, query := range queries {
+		checks = append(checks, bypassedCheck(query.Permission, query.Domain, resources[i]))
+	}
+	return s.superuserBypass(ctx, u, checks...) != nil
+}
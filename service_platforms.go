@@ -0,0 +1,270 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	restproto "github.com/savageking-io/ogbrest/proto"
+	"github.com/savageking-io/ogbuser/db"
+	"github.com/savageking-io/ogbuser/proto"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+var (
+	ErrPlatformVerification = errors.New("failed to verify platform")
+	ErrGuestNotUpgraded     = errors.New("guest accounts must be upgraded first")
+)
+
+// linkPlatform verifies the ticket with the platform provider and attaches the identity to the user
+func (s *Service) linkPlatform(ctx context.Context, userId int32, platform, ticket string) (*schema.PlatformSchema, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+
+	u, err := s.users.GetById(userId)
+	if err != nil {
+		return nil, err
+	}
+	if u.IsGuest() {
+		return nil, ErrGuestNotUpgraded
+	}
+
+	platformUserId, err := s.verifyPlatformTicket(ctx, platform, ticket)
+	if err != nil {
+		if errors.Is(err, ErrUnsupportedPlatform) {
+			return nil, err
+		}
+		log.Debugf("Platform verification failed for user %d: %v", userId, err)
+		return nil, ErrPlatformVerification
+	}
+
+	result, err := s.db.LinkPlatform(ctx, userId, platform, platformUserId)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Linked %s identity to user %d", platform, userId)
+	return result, nil
+}
+
+// unlinkPlatform detaches the platform identity unless it's the last way for the user to log in
+func (s *Service) unlinkPlatform(ctx context.Context, userId int32, platform string) error {
+	if s.db == nil {
+		return fmt.Errorf("database is not initialized")
+	}
+
+	if !schema.IsKnownPlatform(platform) {
+		return ErrUnsupportedPlatform
+	}
+
+	if err := s.db.UnlinkPlatform(ctx, userId, platform); err != nil {
+		return err
+	}
+
+	log.Infof("Unlinked %s identity from user %d", platform, userId)
+	return nil
+}
+
+// platformErrorCode maps errors of platform operations to API code and HTTP code
+func platformErrorCode(err error) (int32, int32) {
+	switch {
+	case errors.Is(err, ErrUnsupportedPlatform):
+		return 15002, 400
+	case errors.Is(err, ErrPlatformVerification):
+		return 15003, 400
+	case errors.Is(err, db.ErrPlatformLinked):
+		return 15004, 409
+	case errors.Is(err, db.ErrPlatformExists):
+		return 15005, 409
+	case errors.Is(err, db.ErrPlatformMissing):
+		return 15006, 404
+	case errors.Is(err, db.ErrLastLoginMethod):
+		return 15007, 409
+	case errors.Is(err, ErrGuestNotUpgraded):
+		return 15010, 409
+	case errors.Is(err, db.ErrUserNotFound):
+		return 15009, 404
+	}
+	return 15008, 500
+}
+
+func platformToProto(platform *schema.PlatformSchema) *proto.Platform {
+	return &proto.Platform{
+		Id:             platform.Id,
+		PlatformName:   platform.PlatformName,
+		PlatformUserId: platform.PlatformUserId,
+		CreatedAt:      timestamppb.New(platform.CreatedAt),
+	}
+}
+
+func (s *Service) LinkPlatform(ctx context.Context, in *proto.LinkPlatformRequest) (*proto.LinkPlatformResponse, error) {
+	log.Tracef("LinkPlatform")
+
+	if in.UserId == 0 {
+		return &proto.LinkPlatformResponse{Code: 15009, Error: "invalid user id"}, nil
+	}
+
+	platform, err := s.linkPlatform(ctx, in.UserId, in.Platform, in.Ticket)
+	if err != nil {
+		code, _ := platformErrorCode(err)
+		return &proto.LinkPlatformResponse{Code: code, Error: err.Error()}, nil
+	}
+
+	return &proto.LinkPlatformResponse{
+		Code:     0,
+		Platform: platformToProto(platform),
+	}, nil
+}
+
+func (s *Service) UnlinkPlatform(ctx context.Context, in *proto.UnlinkPlatformRequest) (*proto.UnlinkPlatformResponse, error) {
+	log.Tracef("UnlinkPlatform")
+
+	if in.UserId == 0 {
+		return &proto.UnlinkPlatformResponse{Code: 15009, Error: "invalid user id"}, nil
+	}
+
+	if err := s.unlinkPlatform(ctx, in.UserId, in.Platform); err != nil {
+		code, _ := platformErrorCode(err)
+		return &proto.UnlinkPlatformResponse{Code: code, Error: err.Error()}, nil
+	}
+
+	return &proto.UnlinkPlatformResponse{Code: 0}, nil
+}
+
+func (s *Service) ListPlatforms(ctx context.Context, in *proto.ListPlatformsRequest) (*proto.ListPlatformsResponse, error) {
+	log.Tracef("ListPlatforms")
+
+	if in.UserId == 0 {
+		return &proto.ListPlatformsResponse{Code: 15009, Error: "invalid user id"}, nil
+	}
+
+	if s.db == nil {
+		return &proto.ListPlatformsResponse{Code: 15008, Error: "database is not initialized"}, nil
+	}
+
+	platforms, err := s.db.LoadUserPlatforms(ctx, in.UserId)
+	if err != nil {
+		log.Errorf("Failed to load platforms of user %d: %v", in.UserId, err)
+		return &proto.ListPlatformsResponse{Code: 15008, Error: err.Error()}, nil
+	}
+
+	result := &proto.ListPlatformsResponse{Code: 0}
+	for i := range platforms {
+		result.Platforms = append(result.Platforms, platformToProto(&platforms[i]))
+	}
+	return result, nil
+}
+
+// HandleListPlatformsRequest returns platform identities linked to the requesting user
+func (s *Service) HandleListPlatformsRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleListPlatformsRequest")
+
+	userId, err := s.getRequestUserId(ctx, in)
+	if err != nil {
+		log.Debugf("Failed to authenticate request: %v", err)
+		return &restproto.RestApiResponse{Code: 15001, HttpCode: 401, Error: "unauthorized"}, nil
+	}
+
+	platforms, err := s.db.LoadUserPlatforms(ctx, userId)
+	if err != nil {
+		log.Errorf("Failed to load platforms of user %d: %v", userId, err)
+		return &restproto.RestApiResponse{Code: 15008, HttpCode: 500, Error: err.Error()}, nil
+	}
+
+	type platformResponse struct {
+		Platform       string `json:"platform"`
+		PlatformUserId string `json:"platform_user_id"`
+		LinkedAt       string `json:"linked_at"`
+	}
+	response := struct {
+		Platforms []platformResponse `json:"platforms"`
+	}{Platforms: []platformResponse{}}
+	for _, platform := range platforms {
+		response.Platforms = append(response.Platforms, platformResponse{
+			Platform:       platform.PlatformName,
+			PlatformUserId: platform.PlatformUserId,
+			LinkedAt:       platform.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restproto.RestApiResponse{Code: 0, HttpCode: 200, Body: string(body)}, nil
+}
+
+// HandleLinkPlatformRequest links a platform identity to the requesting user.
+// Body: {"platform": "steam", "token": "<platform ticket>"}
+func (s *Service) HandleLinkPlatformRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleLinkPlatformRequest")
+
+	userId, err := s.getRequestUserId(ctx, in)
+	if err != nil {
+		log.Debugf("Failed to authenticate request: %v", err)
+		return &restproto.RestApiResponse{Code: 15001, HttpCode: 401, Error: "unauthorized"}, nil
+	}
+
+	request := struct {
+		Platform string `json:"platform"`
+		Token    string `json:"token"`
+	}{}
+	if err := json.Unmarshal([]byte(in.Body), &request); err != nil {
+		log.Debugf("Failed to unmarshal request body: %v", err)
+		return &restproto.RestApiResponse{Code: 15000, HttpCode: 400, Error: "failed to parse request"}, nil
+	}
+
+	platform, err := s.linkPlatform(ctx, userId, request.Platform, request.Token)
+	if err != nil {
+		code, httpCode := platformErrorCode(err)
+		if httpCode == 500 {
+			log.Errorf("Failed to link platform to user %d: %v", userId, err)
+		}
+		return &restproto.RestApiResponse{Code: code, HttpCode: httpCode, Error: err.Error()}, nil
+	}
+
+	body, err := json.Marshal(struct {
+		Platform       string `json:"platform"`
+		PlatformUserId string `json:"platform_user_id"`
+	}{platform.PlatformName, platform.PlatformUserId})
+	if err != nil {
+		return nil, err
+	}
+
+	return &restproto.RestApiResponse{Code: 0, HttpCode: 200, Body: string(body)}, nil
+}
+
+// HandleUnlinkPlatformRequest unlinks a platform identity from the requesting user.
+// Body: {"platform": "steam"}
+func (s *Service) HandleUnlinkPlatformRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleUnlinkPlatformRequest")
+
+	userId, err := s.getRequestUserId(ctx, in)
+	if err != nil {
+		log.Debugf("Failed to authenticate request: %v", err)
+		return &restproto.RestApiResponse{Code: 15001, HttpCode: 401, Error: "unauthorized"}, nil
+	}
+
+	request := struct {
+		Platform string `json:"platform"`
+	}{}
+	if err := json.Unmarshal([]byte(in.Body), &request); err != nil {
+		log.Debugf("Failed to unmarshal request body: %v", err)
+		return &restproto.RestApiResponse{Code: 15000, HttpCode: 400, Error: "failed to parse request"}, nil
+	}
+
+	if err := s.unlinkPlatform(ctx, userId, request.Platform); err != nil {
+		code, httpCode := platformErrorCode(err)
+		if httpCode == 500 {
+			log.Errorf("Failed to unlink platform from user %d: %v", userId, err)
+		}
+		return &restproto.RestApiResponse{Code: code, HttpCode: httpCode, Error: err.Error()}, nil
+	}
+
+	return &restproto.RestApiResponse{Code: 0, HttpCode: 200}, nil
+}
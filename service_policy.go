@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"github.com/savageking-io/ogbuser/policy"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"time"
+)
+
+// planPolicy returns changes that applying the policy would make right now
+func (s *Service) planPolicy(ctx context.Context, p *policy.Policy, prune bool) ([]schema.PolicyChangeSchema, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+	current, err := s.db.LoadGroupsSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return policy.Diff(p, current, prune)
+}
+
+// applyPolicy reconciles groups with the policy in a single transaction and reloads groups. Changes are
+// planned against the locked state, so they may differ from an earlier plan
+func (s *Service) applyPolicy(ctx context.Context, actorId int32, p *policy.Policy, prune bool) ([]schema.PolicyChangeSchema, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+
+	changes, members, err := s.db.ApplyPolicy(ctx, actorId, func(current []schema.GroupSchema) ([]schema.PolicyChangeSchema, error) {
+		return policy.Diff(p, current, prune)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	if _, _, err := s.reloadGroups(ctx); err != nil {
+		log.Errorf("Failed to reload groups after applying policy: %v", err)
+	}
+
+	log.Infof("Policy applied by %d: %d changes, %d members of deleted groups", actorId, len(changes), len(members))
+	return changes, nil
+}
+
+// WatchPolicyFile applies the configured policy file on start and again every time its content changes.
+// Files that fail to apply are retried on the next check, e.g. until other services register permissions
+// the policy refers to
+func (s *Service) WatchPolicyFile(ctx context.Context) {
+	config := s.config.Policy
+	var applied [sha256.Size]byte
+
+	check := func() {
+		data, err := os.ReadFile(config.File)
+		if err != nil {
+			log.Errorf("Failed to read policy file %s: %v", config.File, err)
+			return
+		}
+		sum := sha256.Sum256(data)
+		if sum == applied {
+			return
+		}
+
+		p, err := policy.Load(config.File)
+		if err != nil {
+			// Invalid file is reported once, until it is edited again
+			log.Errorf("Failed to load policy file %s: %v", config.File, err)
+			applied = sum
+			return
+		}
+		changes, err := s.applyPolicy(ctx, 0, p, config.Prune)
+		if err != nil {
+			log.Errorf("Failed to apply policy file %s: %v", config.File, err)
+			return
+		}
+		applied = sum
+		for _, change := range changes {
+			log.Infof("Policy: %s", change.Summary)
+		}
+	}
+
+	check()
+	if config.ReloadIntervalSeconds <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(time.Duration(config.ReloadIntervalSeconds) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			check()
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"github.com/savageking-io/ogbuser/db"
+	"github.com/savageking-io/ogbuser/proto"
+	"github.com/savageking-io/ogbuser/schema"
+	"github.com/savageking-io/ogbuser/user"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// MaxBatchProfiles limits number of profiles requested in a single BatchGetProfiles call
+const MaxBatchProfiles = 100
+
+func profileToProto(profile *schema.ProfileSchema) *proto.Profile {
+	result := &proto.Profile{
+		UserId:      profile.UserId,
+		Username:    profile.Username,
+		DisplayName: profile.DisplayName,
+		AvatarUrl:   profile.AvatarUrl,
+		Locale:      profile.Locale,
+		Timezone:    profile.Timezone,
+		Country:     profile.Country,
+		Attributes:  profile.Attributes.String(),
+	}
+	if profile.UpdatedAt != nil {
+		result.UpdatedAt = timestamppb.New(*profile.UpdatedAt)
+	}
+	if result.Attributes == "" {
+		result.Attributes = "{}"
+	}
+	return result
+}
+
+func (s *Service) GetProfile(ctx context.Context, in *proto.GetProfileRequest) (*proto.GetProfileResponse, error) {
+	log.Tracef("GetProfile")
+
+	if in.UserId == 0 {
+		return &proto.GetProfileResponse{Code: 17000, Error: "invalid user id"}, nil
+	}
+
+	u, err := s.users.GetById(in.UserId)
+	if err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			return &proto.GetProfileResponse{Code: 17001, Error: err.Error()}, nil
+		}
+		log.Errorf("Failed to load user %d: %v", in.UserId, err)
+		return &proto.GetProfileResponse{Code: 17004, Error: err.Error()}, nil
+	}
+
+	profile, err := u.LoadProfile(ctx)
+	if err != nil {
+		log.Errorf("Failed to load profile of user %d: %v", in.UserId, err)
+		return &proto.GetProfileResponse{Code: 17004, Error: err.Error()}, nil
+	}
+
+	return &proto.GetProfileResponse{Code: 0, Profile: profileToProto(profile)}, nil
+}
+
+func (s *Service) UpdateProfile(ctx context.Context, in *proto.UpdateProfileRequest) (*proto.UpdateProfileResponse, error) {
+	log.Tracef("UpdateProfile")
+
+	if in.UserId == 0 {
+		return &proto.UpdateProfileResponse{Code: 17000, Error: "invalid user id"}, nil
+	}
+
+	u, err := s.users.GetById(in.UserId)
+	if err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			return &proto.UpdateProfileResponse{Code: 17001, Error: err.Error()}, nil
+		}
+		log.Errorf("Failed to load user %d: %v", in.UserId, err)
+		return &proto.UpdateProfileResponse{Code: 17004, Error: err.Error()}, nil
+	}
+
+	update := &schema.ProfileUpdateSchema{
+		DisplayName: in.DisplayName,
+		AvatarUrl:   in.AvatarUrl,
+		Locale:      in.Locale,
+		Timezone:    in.Timezone,
+		Country:     in.Country,
+		Attributes:  in.Attributes,
+	}
+
+	profile, err := u.UpdateProfile(ctx, update)
+	if err != nil {
+		if errors.Is(err, db.ErrUserNotFound) {
+			return &proto.UpdateProfileResponse{Code: 17001, Error: err.Error()}, nil
+		}
+		if errors.Is(err, user.ErrInvalidProfile) {
+			return &proto.UpdateProfileResponse{Code: 17002, Error: err.Error()}, nil
+		}
+		log.Errorf("Failed to update profile of user %d: %v", in.UserId, err)
+		return &proto.UpdateProfileResponse{Code: 17004, Error: err.Error()}, nil
+	}
+
+	return &proto.UpdateProfileResponse{Code: 0, Profile: profileToProto(profile)}, nil
+}
+
+// BatchGetProfiles serves profiles of cached users from memory and loads the rest with a single query
+func (s *Service) BatchGetProfiles(ctx context.Context, in *proto.BatchGetProfilesRequest) (*proto.BatchGetProfilesResponse, error) {
+	log.Tracef("BatchGetProfiles")
+
+	if len(in.UserIds) > MaxBatchProfiles {
+		return &proto.BatchGetProfilesResponse{Code: 17003, Error: "too many user ids"}, nil
+	}
+
+	found, missing := s.users.GetProfiles(in.UserIds)
+	if len(missing) > 0 {
+		if s.db == nil {
+			return &proto.BatchGetProfilesResponse{Code: 17004, Error: "database is not initialized"}, nil
+		}
+		profiles, err := s.db.LoadProfiles(ctx, missing)
+		if err != nil {
+			log.Errorf("Failed to load %d profiles: %v", len(missing), err)
+			return &proto.BatchGetProfilesResponse{Code: 17004, Error: err.Error()}, nil
+		}
+		for i := range profiles {
+			found[profiles[i].UserId] = &profiles[i]
+			s.users.SetProfile(&profiles[i])
+		}
+	}
+
+	result := &proto.BatchGetProfilesResponse{Code: 0}
+	seen := make(map[int32]bool, len(in.UserIds))
+	for _, id := range in.UserIds {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if profile, ok := found[id]; ok {
+			result.Profiles = append(result.Profiles, profileToProto(profile))
+		} else {
+			result.MissingUserIds = append(result.MissingUserIds, id)
+		}
+	}
+
+	log.Debugf("BatchGetProfiles: requested %d, loaded from db %d, missing %d", len(in.UserIds), len(missing), len(result.MissingUserIds))
+	return result, nil
+}
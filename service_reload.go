@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	restproto "github.com/savageking-io/ogbrest/proto"
+	"github.com/savageking-io/ogbuser/group"
+	"github.com/savageking-io/ogbuser/perm"
+	"github.com/savageking-io/ogbuser/proto"
+	log "github.com/sirupsen/logrus"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// GroupReloadDebounce joins reload requests arriving close together, e.g. notifications of several
+// statements, into a single reload
+const GroupReloadDebounce = time.Second
+
+// reloadGroups rebuilds groups and tells watchers that every cached permission result is stale.
+// Returns the number of groups and cached users reloaded
+func (s *Service) reloadGroups(ctx context.Context) (int, int, error) {
+	groups, users, err := s.rebuildGroups(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	s.invalidateAll(ctx, InvalidationReload)
+	return groups, users, nil
+}
+
+// rebuildGroups rebuilds all groups from a single database snapshot and swaps them in at once. Permission
+// checks running meanwhile keep using the previous groups. Cached users are attached to the new groups
+// afterwards, picking up membership edits as well, and rebuild effective permissions on their next check
+func (s *Service) rebuildGroups(ctx context.Context) (int, int, error) {
+	if s.db == nil {
+		return 0, 0, fmt.Errorf("database is not initialized")
+	}
+
+	s.reloadMutex.Lock()
+	defer s.reloadMutex.Unlock()
+
+	snapshot, err := s.db.LoadGroupsSnapshot(ctx)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to load groups: %w", err)
+	}
+
+	groups := group.Build(ctx, s.db, snapshot)
+	s.groups.Replace(groups)
+
+	users, err := s.users.ReloadAllGroups(ctx)
+	if err != nil {
+		log.Errorf("Failed to reload groups of cached users: %v", err)
+	}
+
+	log.Infof("Groups reloaded: %d groups, %d cached users", len(groups), users)
+	return len(groups), users, nil
+}
+
+// reloadGroupsAs reloads groups on behalf of an admin with global manage_users write access
+func (s *Service) reloadGroupsAs(ctx context.Context, actorId int32) (int, int, error) {
+	if err := s.requireGlobalPermission(ctx, actorId, PermissionManageUsers, perm.AccessWrite); err != nil {
+		return 0, 0, err
+	}
+	log.Infof("Groups reload requested by %d", actorId)
+	return s.reloadGroups(ctx)
+}
+
+// requestGroupReload schedules a reload without waiting for it. Requests made while one is pending are joined
+func (s *Service) requestGroupReload() {
+	select {
+	case s.reloadRequests <- struct{}{}:
+	default:
+	}
+}
+
+// RunGroupReloads performs reloads requested by signals and database notifications
+func (s *Service) RunGroupReloads(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.reloadRequests:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(GroupReloadDebounce):
+		}
+		// Requests that arrived during the wait are covered by this reload
+		select {
+		case <-s.reloadRequests:
+		default:
+		}
+
+		if _, _, err := s.reloadGroups(ctx); err != nil {
+			log.Errorf("Failed to reload groups: %v", err)
+		}
+	}
+}
+
+// WatchReloadSignal reloads groups when the process receives SIGHUP
+func (s *Service) WatchReloadSignal(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			log.Infof("Received SIGHUP, reloading groups")
+			s.requestGroupReload()
+		}
+	}
+}
+
+// WatchGroupChanges reloads groups when the database notifies that groups or their permissions changed.
+// The listener is restarted if it fails
+func (s *Service) WatchGroupChanges(ctx context.Context) {
+	for {
+		if err := s.db.ListenGroupChanges(ctx, s.requestGroupReload); err != nil {
+			log.Errorf("Failed to listen for group changes: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Minute):
+		}
+	}
+}
+
+// ReloadGroups rebuilds groups from the database. Requester must have global manage_users with write access
+func (s *Service) ReloadGroups(ctx context.Context, in *proto.ReloadGroupsRequest) (*proto.ReloadGroupsResponse, error) {
+	log.Tracef("ReloadGroups")
+
+	groups, users, err := s.reloadGroupsAs(ctx, in.RequesterId)
+	if err != nil {
+		code, message := groupErrorResult(err, "reload groups")
+		return &proto.ReloadGroupsResponse{Code: code, Error: message}, nil
+	}
+
+	return &proto.ReloadGroupsResponse{Code: 0, Groups: int32(groups), Users: int32(users)}, nil
+}
+
+// HandleReloadGroupsRequest rebuilds groups from the database
+func (s *Service) HandleReloadGroupsRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleReloadGroupsRequest")
+	return s.handleGroupAdminRequest(ctx, in, nil, func(requesterId int32) (any, error) {
+		groups, users, err := s.reloadGroupsAs(ctx, requesterId)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]int{"groups": groups, "users": users}, nil
+	})
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	restproto "github.com/savageking-io/ogbrest/proto"
+	"github.com/savageking-io/ogbuser/db"
+	"github.com/savageking-io/ogbuser/kafka"
+	"github.com/savageking-io/ogbuser/perm"
+	"github.com/savageking-io/ogbuser/proto"
+	"github.com/savageking-io/ogbuser/sanction"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"strings"
+	"time"
+)
+
+// SanctionExpiryInterval is how often expired sanctions are ended and user.unbanned is published
+const SanctionExpiryInterval = time.Minute
+
+// MaxSanctionNoteLength limits a single note attached to a sanction
+const MaxSanctionNoteLength = 4096
+
+// findLoginBlock returns the active ban or suspension that keeps the user from logging in on the platform
+func (s *Service) findLoginBlock(ctx context.Context, userId int32, platform string) (*schema.SanctionSchema, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+
+	sanctions, err := s.db.LoadActiveSanctions(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return sanction.FindLoginBlock(sanctions, platform, time.Now()), nil
+}
+
+// sanctionMessage describes the sanction for the sanctioned user
+func sanctionMessage(block *schema.SanctionSchema) string {
+	if block.ExpiresAt != nil {
+		return fmt.Sprintf("account is suspended until %s", block.ExpiresAt.UTC().Format(time.RFC3339))
+	}
+	return "account is banned"
+}
+
+// loginBlockedResponse returns a response refusing login if the user is banned or suspended on the platform
+// and nil if login can proceed
+func (s *Service) loginBlockedResponse(ctx context.Context, userId int32, platform string) *restproto.RestApiResponse {
+	block, err := s.findLoginBlock(ctx, userId, platform)
+	if err != nil {
+		log.Errorf("Failed to check sanctions of user %d: %v", userId, err)
+		return &restproto.RestApiResponse{Code: 19006, HttpCode: 500, Error: "failed to check account status"}
+	}
+	if block == nil {
+		return nil
+	}
+
+	log.Infof("Refused login of user %d on %s: sanction %d", userId, platform, block.Id)
+	body, _ := json.Marshal(struct {
+		Type      string     `json:"type"`
+		Scope     string     `json:"scope"`
+		Reason    string     `json:"reason"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}{block.Type, block.Scope, block.Reason, block.ExpiresAt})
+
+	return &restproto.RestApiResponse{
+		Code:     19000,
+		HttpCode: 403,
+		Error:    sanctionMessage(block),
+		Body:     string(body),
+	}
+}
+
+// validateRevokedToken returns a response with the sanction code if the token belongs to a session
+// revoked because of a ban or suspension, so the client can tell the user why they were logged out.
+// Returns nil when the token is simply unknown
+func (s *Service) validateRevokedToken(ctx context.Context, token string) *proto.ValidateTokenResponse {
+	session, err := s.db.GetRevokedUserSession(ctx, token)
+	if err != nil {
+		if !errors.Is(err, db.ErrSessionNotFound) {
+			log.Errorf("Failed to load revoked session: %v", err)
+		}
+		return nil
+	}
+
+	block, err := s.findLoginBlock(ctx, session.UserId, session.PlatformName)
+	if err != nil {
+		log.Errorf("Failed to check sanctions of user %d: %v", session.UserId, err)
+		return nil
+	}
+	if block == nil {
+		return nil
+	}
+
+	return &proto.ValidateTokenResponse{
+		Code:    19000,
+		Error:   sanctionMessage(block),
+		IsValid: false,
+		UserId:  session.UserId,
+	}
+}
+
+// issueSanction stores the sanction, drops the user from the cache if the sanction blocks login
+// and notifies other services
+func (s *Service) issueSanction(ctx context.Context, in *schema.SanctionSchema) (*schema.SanctionSchema, int64, error) {
+	if s.db == nil {
+		return nil, 0, fmt.Errorf("database is not initialized")
+	}
+
+	if err := sanction.Validate(in, time.Now()); err != nil {
+		return nil, 0, err
+	}
+
+	result, revoked, err := s.db.IssueSanction(ctx, in)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if schema.SanctionBlocksLogin(result.Type) {
+		_ = s.users.Delete(result.UserId)
+	}
+
+	log.Infof("Sanction %d (%s %s) issued to user %d by %d, revoked %d sessions",
+		result.Id, result.Type, result.Scope, result.UserId, derefUserId(result.IssuedBy), revoked)
+
+	event := &kafka.UserBannedSchema{
+		SanctionId: result.Id,
+		UserId:     result.UserId,
+		Type:       result.Type,
+		Scope:      result.Scope,
+		Reason:     result.Reason,
+		IssuedBy:   derefUserId(result.IssuedBy),
+		ExpiresAt:  result.ExpiresAt,
+		IssuedAt:   result.CreatedAt,
+	}
+	if err := s.kafka.PublishEvent(ctx, kafka.EventUserBanned, event); err != nil {
+		log.Errorf("Failed to publish %s event for sanction %d: %v", kafka.EventUserBanned, result.Id, err)
+	}
+	s.invalidateUsers(ctx, InvalidationSanction, result.UserId)
+
+	return result, revoked, nil
+}
+
+func (s *Service) revokeSanction(ctx context.Context, sanctionId, actorId int32, reason string) (*schema.SanctionSchema, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+
+	result, err := s.db.RevokeSanction(ctx, sanctionId, actorId, strings.TrimSpace(reason))
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Sanction %d of user %d revoked by %d", result.Id, result.UserId, actorId)
+	s.publishUnbanned(ctx, result, kafka.UnbanCauseRevoked)
+
+	return result, nil
+}
+
+func (s *Service) publishUnbanned(ctx context.Context, ended *schema.SanctionSchema, cause string) {
+	event := &kafka.UserUnbannedSchema{
+		SanctionId: ended.Id,
+		UserId:     ended.UserId,
+		Type:       ended.Type,
+		Scope:      ended.Scope,
+		Cause:      cause,
+		RevokedBy:  derefUserId(ended.RevokedBy),
+		UnbannedAt: time.Now(),
+	}
+	if ended.EndedAt != nil {
+		event.UnbannedAt = *ended.EndedAt
+	}
+	if err := s.kafka.PublishEvent(ctx, kafka.EventUserUnbanned, event); err != nil {
+		log.Errorf("Failed to publish %s event for sanction %d: %v", kafka.EventUserUnbanned, ended.Id, err)
+	}
+	s.invalidateUsers(ctx, InvalidationSanction, ended.UserId)
+}
+
+// WatchSanctionExpiry periodically ends expired sanctions and publishes user.unbanned for each of them
+func (s *Service) WatchSanctionExpiry(ctx context.Context) {
+	ticker := time.NewTicker(SanctionExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			expired, err := s.db.EndExpiredSanctions(ctx)
+			if err != nil {
+				log.Errorf("Failed to end expired sanctions: %v", err)
+				continue
+			}
+			for i := range expired {
+				log.Infof("Sanction %d of user %d expired", expired[i].Id, expired[i].UserId)
+				s.publishUnbanned(ctx, &expired[i], kafka.UnbanCauseExpired)
+			}
+		}
+	}
+}
+
+func sanctionErrorCode(err error) int32 {
+	switch {
+	case errors.Is(err, sanction.ErrInvalidSanction):
+		return 19001
+	case errors.Is(err, ErrPermissionDenied):
+		return 19002
+	case errors.Is(err, db.ErrUserNotFound):
+		return 19003
+	case errors.Is(err, db.ErrSanctionNotFound):
+		return 19004
+	case errors.Is(err, db.ErrSanctionEnded):
+		return 19005
+	}
+	return 19006
+}
+
+func derefUserId(id *int32) int32 {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
+
+func sanctionNoteToProto(note *schema.SanctionNoteSchema) *proto.SanctionNote {
+	return &proto.SanctionNote{
+		Id:        note.Id,
+		AuthorId:  derefUserId(note.AuthorId),
+		Note:      note.Note,
+		CreatedAt: timestamppb.New(note.CreatedAt),
+	}
+}
+
+func sanctionToProto(sanction *schema.SanctionSchema) *proto.Sanction {
+	result := &proto.Sanction{
+		Id:        sanction.Id,
+		UserId:    sanction.UserId,
+		Type:      sanction.Type,
+		Scope:     sanction.Scope,
+		Reason:    sanction.Reason,
+		IssuedBy:  derefUserId(sanction.IssuedBy),
+		ExpiresAt: optionalTimestamp(sanction.ExpiresAt),
+		EndedAt:   optionalTimestamp(sanction.EndedAt),
+		RevokedAt: optionalTimestamp(sanction.RevokedAt),
+		RevokedBy: derefUserId(sanction.RevokedBy),
+		CreatedAt: timestamppb.New(sanction.CreatedAt),
+	}
+	if sanction.RevokeReason != nil {
+		result.RevokeReason = *sanction.RevokeReason
+	}
+	for i := range sanction.Notes {
+		result.Notes = append(result.Notes, sanctionNoteToProto(&sanction.Notes[i]))
+	}
+	return result
+}
+
+// IssueSanction bans, suspends or mutes the user. Requester must have global manage_users with write access
+func (s *Service) IssueSanction(ctx context.Context, in *proto.IssueSanctionRequest) (*proto.IssueSanctionResponse, error) {
+	log.Tracef("IssueSanction")
+
+	if err := s.requireGlobalPermission(ctx, in.RequesterId, PermissionManageUsers, perm.AccessWrite); err != nil {
+		return &proto.IssueSanctionResponse{Code: sanctionErrorCode(err), Error: err.Error()}, nil
+	}
+
+	request := &schema.SanctionSchema{
+		UserId:   in.UserId,
+		Type:     in.Type,
+		Scope:    in.Scope,
+		Reason:   in.Reason,
+		IssuedBy: &in.RequesterId,
+	}
+	if in.ExpiresAt != nil {
+		expiresAt := in.ExpiresAt.AsTime()
+		request.ExpiresAt = &expiresAt
+	}
+
+	result, revoked, err := s.issueSanction(ctx, request)
+	if err != nil {
+		code := sanctionErrorCode(err)
+		if code == 19006 {
+			log.Errorf("Failed to sanction user %d: %v", in.UserId, err)
+		}
+		return &proto.IssueSanctionResponse{Code: code, Error: err.Error()}, nil
+	}
+
+	return &proto.IssueSanctionResponse{
+		Code:            0,
+		Sanction:        sanctionToProto(result),
+		RevokedSessions: revoked,
+	}, nil
+}
+
+// RevokeSanction ends the sanction early. Requester must have global manage_users with write access
+func (s *Service) RevokeSanction(ctx context.Context, in *proto.RevokeSanctionRequest) (*proto.RevokeSanctionResponse, error) {
+	log.Tracef("RevokeSanction")
+
+	if err := s.requireGlobalPermission(ctx, in.RequesterId, PermissionManageUsers, perm.AccessWrite); err != nil {
+		return &proto.RevokeSanctionResponse{Code: sanctionErrorCode(err), Error: err.Error()}, nil
+	}
+
+	result, err := s.revokeSanction(ctx, in.SanctionId, in.RequesterId, in.Reason)
+	if err != nil {
+		code := sanctionErrorCode(err)
+		if code == 19006 {
+			log.Errorf("Failed to revoke sanction %d: %v", in.SanctionId, err)
+		}
+		return &proto.RevokeSanctionResponse{Code: code, Error: err.Error()}, nil
+	}
+
+	return &proto.RevokeSanctionResponse{Code: 0, Sanction: sanctionToProto(result)}, nil
+}
+
+// ListSanctions returns sanction history of the user. Requester must have global manage_users with read access
+func (s *Service) ListSanctions(ctx context.Context, in *proto.ListSanctionsRequest) (*proto.ListSanctionsResponse, error) {
+	log.Tracef("ListSanctions")
+
+	if err := s.requireGlobalPermission(ctx, in.RequesterId, PermissionManageUsers, perm.AccessRead); err != nil {
+		return &proto.ListSanctionsResponse{Code: sanctionErrorCode(err), Error: err.Error()}, nil
+	}
+
+	if s.db == nil {
+		return &proto.ListSanctionsResponse{Code: 19006, Error: "database is not initialized"}, nil
+	}
+
+	var sanctions []schema.SanctionSchema
+	var err error
+	if in.ActiveOnly {
+		sanctions, err = s.db.LoadActiveSanctions(ctx, in.UserId)
+	} else {
+		sanctions, err = s.db.LoadSanctions(ctx, in.UserId)
+	}
+	if err != nil {
+		log.Errorf("Failed to load sanctions of user %d: %v", in.UserId, err)
+		return &proto.ListSanctionsResponse{Code: 19006, Error: err.Error()}, nil
+	}
+
+	result := &proto.ListSanctionsResponse{Code: 0}
+	for i := range sanctions {
+		result.Sanctions = append(result.Sanctions, sanctionToProto(&sanctions[i]))
+	}
+	return result, nil
+}
+
+// AddSanctionNote attaches a note to the sanction, e.g. outcome of an appeal.
+// Requester must have global manage_users with write access
+func (s *Service) AddSanctionNote(ctx context.Context, in *proto.AddSanctionNoteRequest) (*proto.AddSanctionNoteResponse, error) {
+	log.Tracef("AddSanctionNote")
+
+	if err := s.requireGlobalPermission(ctx, in.RequesterId, PermissionManageUsers, perm.AccessWrite); err != nil {
+		return &proto.AddSanctionNoteResponse{Code: sanctionErrorCode(err), Error: err.Error()}, nil
+	}
+
+	note := strings.TrimSpace(in.Note)
+	if note == "" || len(note) > MaxSanctionNoteLength {
+		return &proto.AddSanctionNoteResponse{Code: 19001, Error: "note must be between 1 and 4096 characters"}, nil
+	}
+
+	if s.db == nil {
+		return &proto.AddSanctionNoteResponse{Code: 19006, Error: "database is not initialized"}, nil
+	}
+
+	result, err := s.db.AddSanctionNote(ctx, in.SanctionId, in.RequesterId, note)
+	if err != nil {
+		code := sanctionErrorCode(err)
+		if code == 19006 {
+			log.Errorf("Failed to add note to sanction %d: %v", in.SanctionId, err)
+		}
+		return &proto.AddSanctionNoteResponse{Code: code, Error: err.Error()}, nil
+	}
+
+	return &proto.AddSanctionNoteResponse{Code: 0, Note: sanctionNoteToProto(result)}, nil
+}
+
+// CheckSanction tells other services whether the user is blocked from a feature, e.g. chat
+func (s *Service) CheckSanction(ctx context.Context, in *proto.CheckSanctionRequest) (*proto.CheckSanctionResponse, error) {
+	log.Tracef("CheckSanction")
+
+	if in.UserId == 0 {
+		return &proto.CheckSanctionResponse{Code: 19003, Error: "invalid user id"}, nil
+	}
+
+	if s.db == nil {
+		return &proto.CheckSanctionResponse{Code: 19006, Error: "database is not initialized"}, nil
+	}
+
+	sanctions, err := s.db.LoadActiveSanctions(ctx, in.UserId)
+	if err != nil {
+		log.Errorf("Failed to load sanctions of user %d: %v", in.UserId, err)
+		return &proto.CheckSanctionResponse{Code: 19006, Error: err.Error()}, nil
+	}
+
+	mute := sanction.FindMute(sanctions, in.Feature, time.Now())
+	if mute == nil {
+		return &proto.CheckSanctionResponse{Code: 0, Sanctioned: false}, nil
+	}
+
+	return &proto.CheckSanctionResponse{Code: 0, Sanctioned: true, Sanction: sanctionToProto(mute)}, nil
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	restproto "github.com/savageking-io/ogbrest/proto"
+	"github.com/savageking-io/ogbuser/db"
+	"github.com/savageking-io/ogbuser/perm"
+	"github.com/savageking-io/ogbuser/proto"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+// searchUsers checks that requester may list users and runs the search
+func (s *Service) searchUsers(ctx context.Context, requesterId int32, filter *schema.UserSearchSchema) ([]schema.UserSearchResultSchema, string, error) {
+	if err := s.requireGlobalPermission(ctx, requesterId, PermissionManageUsers, perm.AccessRead); err != nil {
+		return nil, "", err
+	}
+
+	if s.db == nil {
+		return nil, "", fmt.Errorf("database is not initialized")
+	}
+
+	if filter.Platform != "" && !schema.IsKnownPlatform(filter.Platform) {
+		return nil, "", ErrUnsupportedPlatform
+	}
+
+	return s.db.SearchUsers(ctx, filter)
+}
+
+func searchErrorCode(err error) (int32, int32) {
+	switch {
+	case errors.Is(err, db.ErrInvalidSort), errors.Is(err, ErrUnsupportedPlatform):
+		return 20000, 400
+	case errors.Is(err, ErrPermissionDenied):
+		return 20002, 403
+	case errors.Is(err, db.ErrInvalidCursor):
+		return 20003, 400
+	}
+	return 20004, 500
+}
+
+func userSummaryToProto(u *schema.UserSearchResultSchema) *proto.UserSummary {
+	result := &proto.UserSummary{
+		Id:        u.Id,
+		Username:  u.Username,
+		IsGuest:   u.IsGuest,
+		CreatedAt: timestamppb.New(u.CreatedAt),
+		DeletedAt: optionalTimestamp(u.DeletedAt),
+		GroupIds:  u.GroupIds,
+	}
+	if u.Email != nil {
+		result.Email = *u.Email
+	}
+	for i := range u.Platforms {
+		result.Platforms = append(result.Platforms, platformToProto(&u.Platforms[i]))
+	}
+	return result
+}
+
+// SearchUsers lists users matching filters page by page. Requester must have global manage_users with read access
+func (s *Service) SearchUsers(ctx context.Context, in *proto.SearchUsersRequest) (*proto.SearchUsersResponse, error) {
+	log.Tracef("SearchUsers")
+
+	filter := &schema.UserSearchSchema{
+		Username:       in.Username,
+		Email:          in.Email,
+		Platform:       in.Platform,
+		PlatformUserId: in.PlatformUserId,
+		GroupId:        in.GroupId,
+		IncludeDeleted: in.IncludeDeleted,
+		SortBy:         in.SortBy,
+		Descending:     in.Descending,
+		Cursor:         in.Cursor,
+		Limit:          int(in.Limit),
+	}
+	if in.CreatedAfter != nil {
+		createdAfter := in.CreatedAfter.AsTime()
+		filter.CreatedAfter = &createdAfter
+	}
+	if in.CreatedBefore != nil {
+		createdBefore := in.CreatedBefore.AsTime()
+		filter.CreatedBefore = &createdBefore
+	}
+
+	users, nextCursor, err := s.searchUsers(ctx, in.RequesterId, filter)
+	if err != nil {
+		code, _ := searchErrorCode(err)
+		if code == 20004 {
+			log.Errorf("Failed to search users: %v", err)
+		}
+		return &proto.SearchUsersResponse{Code: code, Error: err.Error()}, nil
+	}
+
+	result := &proto.SearchUsersResponse{Code: 0, NextCursor: nextCursor}
+	for i := range users {
+		result.Users = append(result.Users, userSummaryToProto(&users[i]))
+	}
+	return result, nil
+}
+
+// HandleSearchUsersRequest lists users for support staff. Body contains the same filters as SearchUsers RPC,
+// e.g. {"username": "jan", "platform": "steam", "sort_by": "created_at", "descending": true, "limit": 20}
+func (s *Service) HandleSearchUsersRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleSearchUsersRequest")
+
+	requesterId, err := s.getRequestUserId(ctx, in)
+	if err != nil {
+		log.Debugf("Failed to authenticate request: %v", err)
+		return &restproto.RestApiResponse{Code: 20001, HttpCode: 401, Error: "unauthorized"}, nil
+	}
+
+	request := struct {
+		Username       string     `json:"username"`
+		Email          string     `json:"email"`
+		Platform       string     `json:"platform"`
+		PlatformUserId string     `json:"platform_user_id"`
+		GroupId        int32      `json:"group_id"`
+		CreatedAfter   *time.Time `json:"created_after"`
+		CreatedBefore  *time.Time `json:"created_before"`
+		IncludeDeleted bool       `json:"include_deleted"`
+		SortBy         string     `json:"sort_by"`
+		Descending     bool       `json:"descending"`
+		Cursor         string     `json:"cursor"`
+		Limit          int        `json:"limit"`
+	}{}
+	if in.Body != "" {
+		if err := json.Unmarshal([]byte(in.Body), &request); err != nil {
+			log.Debugf("Failed to unmarshal request body: %v", err)
+			return &restproto.RestApiResponse{Code: 20000, HttpCode: 400, Error: "failed to parse request"}, nil
+		}
+	}
+
+	users, nextCursor, err := s.searchUsers(ctx, requesterId, &schema.UserSearchSchema{
+		Username:       request.Username,
+		Email:          request.Email,
+		Platform:       request.Platform,
+		PlatformUserId: request.PlatformUserId,
+		GroupId:        request.GroupId,
+		CreatedAfter:   request.CreatedAfter,
+		CreatedBefore:  request.CreatedBefore,
+		IncludeDeleted: request.IncludeDeleted,
+		SortBy:         request.SortBy,
+		Descending:     request.Descending,
+		Cursor:         request.Cursor,
+		Limit:          request.Limit,
+	})
+	if err != nil {
+		code, httpCode := searchErrorCode(err)
+		if httpCode == 500 {
+			log.Errorf("Failed to search users: %v", err)
+		}
+		return &restproto.RestApiResponse{Code: code, HttpCode: httpCode, Error: err.Error()}, nil
+	}
+
+	type platformResponse struct {
+		Platform       string `json:"platform"`
+		PlatformUserId string `json:"platform_user_id"`
+	}
+	type userResponse struct {
+		Id        int32              `json:"id"`
+		Username  string             `json:"username"`
+		Email     *string            `json:"email"`
+		IsGuest   bool               `json:"is_guest"`
+		CreatedAt time.Time          `json:"created_at"`
+		DeletedAt *time.Time         `json:"deleted_at"`
+		Platforms []platformResponse `json:"platforms"`
+		GroupIds  []int32            `json:"group_ids"`
+	}
+	response := struct {
+		Users      []userResponse `json:"users"`
+		NextCursor string         `json:"next_cursor"`
+	}{Users: []userResponse{}, NextCursor: nextCursor}
+	for _, u := range users {
+		item := userResponse{
+			Id:        u.Id,
+			Username:  u.Username,
+			Email:     u.Email,
+			IsGuest:   u.IsGuest,
+			CreatedAt: u.CreatedAt,
+			DeletedAt: u.DeletedAt,
+			Platforms: []platformResponse{},
+			GroupIds:  append([]int32{}, u.GroupIds...),
+		}
+		for _, platform := range u.Platforms {
+			item.Platforms = append(item.Platforms, platformResponse{platform.PlatformName, platform.PlatformUserId})
+		}
+		response.Users = append(response.Users, item)
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		return nil, err
+	}
+
+	return &restproto.RestApiResponse{Code: 0, HttpCode: 200, Body: string(body)}, nil
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testService returns a service connected to OGBUSER_TEST_POSTGRES, a key=value connection string, inside
+// a new migrated schema that is dropped when the test ends. The returned connection works in the same schema
+// and is meant for setting up data. Skips the test when the variable isn't set
+func testService(t *testing.T, config *ServiceConfig) (*Service, *sqlx.DB) {
+	dsn := os.Getenv("OGBUSER_TEST_POSTGRES")
+	if dsn == "" {
+		t.Skip("OGBUSER_TEST_POSTGRES is not set")
+	}
+
+	admin, err := sqlx.Connect("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := fmt.Sprintf("ogbuser_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + name); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = admin.Exec(`DROP SCHEMA ` + name + ` CASCADE`)
+		_ = admin.Close()
+	})
+
+	for _, pair := range strings.Fields(dsn) {
+		key, value, _ := strings.Cut(pair, "=")
+		switch key {
+		case "host":
+			config.Postgres.Hostname = value
+		case "port":
+			port, err := strconv.ParseUint(value, 10, 16)
+			if err != nil {
+				t.Fatalf("OGBUSER_TEST_POSTGRES port: %v", err)
+			}
+			config.Postgres.Port = uint16(port)
+		case "user":
+			config.Postgres.Username = value
+		case "password":
+			config.Postgres.Password = value
+		case "dbname":
+			config.Postgres.Database = value
+		case "sslmode":
+			config.Postgres.SslMode = value != "disable"
+		}
+	}
+	if config.Postgres.Port == 0 {
+		config.Postgres.Port = 5432
+	}
+	config.Postgres.Schema = name
+
+	s := NewService(config, nil)
+	if err := s.ConnectToDatabase(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.db.MigrateUp(context.Background()); err != nil {
+		t.Fatalf("MigrateUp() error = %v", err)
+	}
+	s.users.SetDb(s.db)
+	if err := s.InitGroups(); err != nil {
+		t.Fatal(err)
+	}
+	s.users.SetGroups(s.groups)
+
+	conn, err := sqlx.Connect("postgres", dsn+" search_path="+name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return s, conn
+}
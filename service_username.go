@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	restproto "github.com/savageking-io/ogbrest/proto"
+	"github.com/savageking-io/ogbuser/db"
+	"github.com/savageking-io/ogbuser/naming"
+	"github.com/savageking-io/ogbuser/proto"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+// changeUsername validates the new username against naming policy, renames the user and updates the cache
+func (s *Service) changeUsername(ctx context.Context, userId int32, username string) (*schema.UsernameHistorySchema, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("database is not initialized")
+	}
+
+	if err := s.usernames.Validate(username); err != nil {
+		return nil, err
+	}
+
+	cooldown := time.Duration(s.config.Usernames.CooldownDays) * 24 * time.Hour
+	hold := time.Duration(s.config.Usernames.HoldDays) * 24 * time.Hour
+	change, err := s.db.RenameUser(ctx, userId, username, cooldown, hold)
+	if err != nil {
+		return nil, err
+	}
+
+	s.users.Rename(userId, change.NewUsername)
+	log.Infof("User %d renamed [%s] -> [%s]", userId, change.OldUsername, change.NewUsername)
+
+	return change, nil
+}
+
+// nextUsernameChange returns the earliest time the user can rename again after the change
+func (s *Service) nextUsernameChange(change *schema.UsernameHistorySchema) time.Time {
+	return change.CreatedAt.Add(time.Duration(s.config.Usernames.CooldownDays) * 24 * time.Hour)
+}
+
+func usernameErrorCode(err error) (int32, int32) {
+	var cooldownErr *db.RenameCooldownError
+	switch {
+	case errors.Is(err, naming.ErrInvalidUsername):
+		return 18002, 400
+	case errors.Is(err, naming.ErrReservedUsername):
+		return 18003, 400
+	case errors.Is(err, naming.ErrProfaneUsername):
+		return 18004, 400
+	case errors.Is(err, db.ErrUsernameTaken):
+		return 18005, 409
+	case errors.Is(err, db.ErrUsernameHeld):
+		return 18006, 409
+	case errors.As(err, &cooldownErr):
+		return 18007, 429
+	case errors.Is(err, db.ErrUsernameUnchanged):
+		return 18008, 400
+	case errors.Is(err, db.ErrUserNotFound):
+		return 18009, 404
+	}
+	return 18010, 500
+}
+
+func (s *Service) ChangeUsername(ctx context.Context, in *proto.ChangeUsernameRequest) (*proto.ChangeUsernameResponse, error) {
+	log.Tracef("ChangeUsername")
+
+	if in.UserId == 0 {
+		return &proto.ChangeUsernameResponse{Code: 18000, Error: "invalid user id"}, nil
+	}
+
+	change, err := s.changeUsername(ctx, in.UserId, in.Username)
+	if err != nil {
+		code, _ := usernameErrorCode(err)
+		if code == 18010 {
+			log.Errorf("Failed to rename user %d: %v", in.UserId, err)
+		}
+		response := &proto.ChangeUsernameResponse{Code: code, Error: err.Error()}
+		var cooldownErr *db.RenameCooldownError
+		if errors.As(err, &cooldownErr) {
+			response.NextChangeAt = timestamppb.New(cooldownErr.NextChangeAt)
+		}
+		return response, nil
+	}
+
+	return &proto.ChangeUsernameResponse{
+		Code:         0,
+		Username:     change.NewUsername,
+		OldUsername:  change.OldUsername,
+		NextChangeAt: timestamppb.New(s.nextUsernameChange(change)),
+	}, nil
+}
+
+// HandleChangeUsernameRequest renames the requesting user.
+// Body: {"username": "new_name"}
+func (s *Service) HandleChangeUsernameRequest(ctx context.Context, in *restproto.RestApiRequest) (*restproto.RestApiResponse, error) {
+	log.Tracef("HandleChangeUsernameRequest")
+
+	userId, err := s.getRequestUserId(ctx, in)
+	if err != nil {
+		log.Debugf("Failed to authenticate request: %v", err)
+		return &restproto.RestApiResponse{Code: 18001, HttpCode: 401, Error: "unauthorized"}, nil
+	}
+
+	request := struct {
+		Username string `json:"username"`
+	}{}
+	if err := json.Unmarshal([]byte(in.Body), &request); err != nil {
+		log.Debugf("Failed to unmarshal request body: %v", err)
+		return &restproto.RestApiResponse{Code: 18000, HttpCode: 400, Error: "failed to parse request"}, nil
+	}
+
+	change, err := s.changeUsername(ctx, userId, request.Username)
+	if err != nil {
+		code, httpCode := usernameErrorCode(err)
+		if httpCode == 500 {
+			log.Errorf("Failed to rename user %d: %v", userId, err)
+		}
+		return &restproto.RestApiResponse{Code: code, HttpCode: httpCode, Error: err.Error()}, nil
+	}
+
+	body, err := json.Marshal(struct {
+		Username     string `json:"username"`
+		OldUsername  string `json:"old_username"`
+		NextChangeAt string `json:"next_change_at"`
+	}{change.NewUsername, change.OldUsername, s.nextUsernameChange(change).UTC().Format(time.RFC3339)})
+	if err != nil {
+		return nil, err
+	}
+
+	return &restproto.RestApiResponse{Code: 0, HttpCode: 200, Body: string(body)}, nil
+}
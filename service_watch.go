@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/savageking-io/ogbuser/db"
+	"github.com/savageking-io/ogbuser/kafka"
+	"github.com/savageking-io/ogbuser/proto"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"sync"
+	"time"
+)
+
+// invalidationBuffer is how many invalidations a watcher can fall behind before it's disconnected
+const invalidationBuffer = 256
+
+// Reasons of permission invalidations
+const (
+	InvalidationMembership = "membership"
+	InvalidationGroup      = "group"
+	InvalidationSanction   = "sanction"
+	InvalidationAccount    = "account"
+	InvalidationReload     = "reload"
+	InvalidationSubscribed = "subscribed" // First message of every stream
+)
+
+// replicaInvalidation is an invalidation sent to other replicas. Origin tells a replica its own invalidations
+// apart, which it has already handled
+type replicaInvalidation struct {
+	Origin string `json:"origin"`
+	kafka.PermissionsInvalidatedSchema
+}
+
+var ErrWatcherBehind = errors.New("watcher fell behind, cached permissions must be dropped")
+
+// invalidationHub fans permission invalidations out to WatchPermissions streams
+type invalidationHub struct {
+	subscribers map[chan *proto.PermissionInvalidation]struct{}
+	mutex       sync.Mutex
+}
+
+// newInstanceId returns a random id of this process among replicas of the service
+func newInstanceId() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		log.Fatalf("Failed to generate instance id: %v", err)
+	}
+	return hex.EncodeToString(id)
+}
+
+func newInvalidationHub() *invalidationHub {
+	return &invalidationHub{
+		subscribers: make(map[chan *proto.PermissionInvalidation]struct{}),
+	}
+}
+
+func (h *invalidationHub) subscribe() chan *proto.PermissionInvalidation {
+	events := make(chan *proto.PermissionInvalidation, invalidationBuffer)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.subscribers[events] = struct{}{}
+	return events
+}
+
+func (h *invalidationHub) unsubscribe(events chan *proto.PermissionInvalidation) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if _, ok := h.subscribers[events]; ok {
+		delete(h.subscribers, events)
+		close(events)
+	}
+}
+
+// broadcast never blocks. Subscribers with a full buffer are dropped and their channel is closed, so they
+// can tell they missed invalidations
+func (h *invalidationHub) broadcast(invalidation *proto.PermissionInvalidation) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	for events := range h.subscribers {
+		select {
+		case events <- invalidation:
+		default:
+			delete(h.subscribers, events)
+			close(events)
+		}
+	}
+}
+
+// invalidateUsers tells watchers that cached permission results of the users are stale
+func (s *Service) invalidateUsers(ctx context.Context, reason string, userIds ...int32) {
+	if len(userIds) == 0 {
+		return
+	}
+	s.publishInvalidation(ctx, &proto.PermissionInvalidation{UserIds: userIds, Reason: reason})
+}
+
+// invalidateGroups tells watchers that permissions of the groups and every group inheriting from them changed
+func (s *Service) invalidateGroups(ctx context.Context, reason string, groupIds ...int32) {
+	if len(groupIds) == 0 {
+		return
+	}
+	s.publishInvalidation(ctx, &proto.PermissionInvalidation{GroupIds: s.inheritingGroups(groupIds...), Reason: reason})
+}
+
+// invalidateAll tells watchers that every cached permission result is stale
+func (s *Service) invalidateAll(ctx context.Context, reason string) {
+	s.publishInvalidation(ctx, &proto.PermissionInvalidation{All: true, Reason: reason})
+}
+
+func (s *Service) publishInvalidation(ctx context.Context, invalidation *proto.PermissionInvalidation) {
+	now := time.Now()
+	invalidation.InvalidatedAt = timestamppb.New(now)
+	s.invalidations.broadcast(invalidation)
+
+	event := &kafka.PermissionsInvalidatedSchema{
+		UserIds:       invalidation.UserIds,
+		GroupIds:      invalidation.GroupIds,
+		All:           invalidation.All,
+		Reason:        invalidation.Reason,
+		InvalidatedAt: now,
+	}
+	if err := s.kafka.PublishEvent(ctx, kafka.EventPermissionsInvalidated, event); err != nil {
+		log.Errorf("Failed to publish %s event (%s): %v", kafka.EventPermissionsInvalidated, invalidation.Reason, err)
+	}
+	s.replicateInvalidation(ctx, event)
+}
+
+// replicateInvalidation sends the invalidation to other replicas, which update their caches and pass it on
+// to their own watchers. An invalidation listing too many ids to fit a notification is split
+func (s *Service) replicateInvalidation(ctx context.Context, event *kafka.PermissionsInvalidatedSchema) {
+	if s.db == nil {
+		return
+	}
+
+	payload, err := json.Marshal(replicaInvalidation{Origin: s.instanceId, PermissionsInvalidatedSchema: *event})
+	if err != nil {
+		log.Errorf("Failed to encode invalidation (%s) for replicas: %v", event.Reason, err)
+		return
+	}
+	if ids := len(event.UserIds) + len(event.GroupIds); len(payload) > db.MaxNotifyPayload && ids > 1 {
+		first, second := *event, *event
+		if len(event.UserIds) > 1 {
+			half := len(event.UserIds) / 2
+			first.UserIds, second.UserIds = event.UserIds[:half], event.UserIds[half:]
+		} else {
+			first.UserIds, second.UserIds = event.UserIds, nil
+		}
+		half := len(event.GroupIds) / 2
+		first.GroupIds, second.GroupIds = event.GroupIds[:half], event.GroupIds[half:]
+		s.replicateInvalidation(ctx, &first)
+		s.replicateInvalidation(ctx, &second)
+		return
+	}
+
+	if err := s.db.NotifyPermissionsInvalidated(ctx, string(payload)); err != nil {
+		log.Errorf("Failed to send invalidation (%s) to replicas: %v", event.Reason, err)
+	}
+}
+
+// receiveInvalidation applies an invalidation sent by another replica. Listed users are dropped from the
+// cache to be loaded again, and groups are rebuilt when any of them changed. The invalidation isn't
+// published again
+func (s *Service) receiveInvalidation(ctx context.Context, payload string) {
+	var received replicaInvalidation
+	if err := json.Unmarshal([]byte(payload), &received); err != nil {
+		log.Errorf("Failed to decode invalidation from a replica: %v", err)
+		return
+	}
+	if received.Origin == s.instanceId {
+		return
+	}
+
+	for _, userId := range received.UserIds {
+		_ = s.users.Delete(userId)
+	}
+	if received.All || len(received.GroupIds) > 0 {
+		if _, _, err := s.rebuildGroups(ctx); err != nil {
+			log.Errorf("Failed to reload groups after invalidation (%s) from a replica: %v", received.Reason, err)
+		}
+	}
+
+	s.invalidations.broadcast(&proto.PermissionInvalidation{
+		UserIds:       received.UserIds,
+		GroupIds:      received.GroupIds,
+		All:           received.All,
+		Reason:        received.Reason,
+		InvalidatedAt: timestamppb.New(received.InvalidatedAt),
+	})
+}
+
+// WatchReplicaInvalidations applies invalidations published by other replicas. Invalidations sent while
+// the listener was disconnected are lost, so after it reconnects groups are rebuilt and everything is
+// invalidated. The listener is restarted if it fails
+func (s *Service) WatchReplicaInvalidations(ctx context.Context) {
+	onReconnect := func() {
+		if _, _, err := s.rebuildGroups(ctx); err != nil {
+			log.Errorf("Failed to reload groups after reconnecting to replicas: %v", err)
+		}
+		s.invalidations.broadcast(&proto.PermissionInvalidation{All: true, Reason: InvalidationReload, InvalidatedAt: timestamppb.Now()})
+	}
+
+	for {
+		err := s.db.ListenPermissionInvalidations(ctx, func(payload string) { s.receiveInvalidation(ctx, payload) }, onReconnect)
+		if err != nil {
+			log.Errorf("Failed to listen for invalidations of replicas: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Minute):
+		}
+	}
+}
+
+// WatchPermissions streams permission invalidations until the subscriber disconnects. The stream starts with
+// an invalidation of everything. Subscribers that fall behind are disconnected with ResourceExhausted and
+// have to drop their cache before subscribing again
+func (s *Service) WatchPermissions(in *proto.WatchPermissionsRequest, stream grpc.ServerStreamingServer[proto.PermissionInvalidation]) error {
+	log.Infof("Service %q is watching permissions", in.Service)
+
+	events := s.invalidations.subscribe()
+	defer s.invalidations.unsubscribe(events)
+
+	// Anything cached before the subscription may be stale, and receiving this tells the subscriber that
+	// no later invalidation will be missed
+	subscribed := &proto.PermissionInvalidation{All: true, Reason: InvalidationSubscribed, InvalidatedAt: timestamppb.Now()}
+	if err := stream.Send(subscribed); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			log.Infof("Service %q stopped watching permissions", in.Service)
+			return nil
+		case invalidation, ok := <-events:
+			if !ok {
+				log.Warnf("Service %q fell behind watching permissions and was disconnected", in.Service)
+				return status.Error(codes.ResourceExhausted, ErrWatcherBehind.Error())
+			}
+			if err := stream.Send(invalidation); err != nil {
+				return err
+			}
+		}
+	}
+}
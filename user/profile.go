@@ -0,0 +1,145 @@
+package user
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/savageking-io/ogbuser/schema"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MaxDisplayNameLength = 64
+	MaxAvatarUrlLength   = 512
+	MaxAttributesSize    = 16 * 1024
+)
+
+var ErrInvalidProfile = errors.New("invalid profile")
+
+var localeRegexp = regexp.MustCompile(`^[a-zA-Z]{2,3}([-_][a-zA-Z0-9]{2,8})*$`)
+
+// GetProfile returns a copy of the cached profile or nil if the profile wasn't loaded yet
+func (u *User) GetProfile() *schema.ProfileSchema {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+	if u.profile == nil {
+		return nil
+	}
+	profile := *u.profile
+	profile.Username = u.raw.Username
+	return &profile
+}
+
+func (u *User) SetProfile(profile *schema.ProfileSchema) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.profile = profile
+}
+
+// LoadProfile returns cached profile or requests it from the database
+func (u *User) LoadProfile(ctx context.Context) (*schema.ProfileSchema, error) {
+	if profile := u.GetProfile(); profile != nil {
+		return profile, nil
+	}
+
+	if u.db == nil {
+		return nil, fmt.Errorf("DB is not initialized")
+	}
+
+	profile, err := u.db.LoadProfile(ctx, u.GetId())
+	if err != nil {
+		return nil, err
+	}
+
+	u.SetProfile(profile)
+	return u.GetProfile(), nil
+}
+
+// UpdateProfile validates and stores the update, then refreshes cached profile
+func (u *User) UpdateProfile(ctx context.Context, update *schema.ProfileUpdateSchema) (*schema.ProfileSchema, error) {
+	if u.db == nil {
+		return nil, fmt.Errorf("DB is not initialized")
+	}
+
+	if err := ValidateProfileUpdate(update); err != nil {
+		return nil, err
+	}
+
+	profile, err := u.db.SaveProfile(ctx, u.GetId(), update)
+	if err != nil {
+		return nil, err
+	}
+
+	u.SetProfile(profile)
+	return u.GetProfile(), nil
+}
+
+// ValidateProfileUpdate checks and normalizes provided fields. Empty strings clear the field
+func ValidateProfileUpdate(update *schema.ProfileUpdateSchema) error {
+	if update == nil {
+		return fmt.Errorf("%w: empty update", ErrInvalidProfile)
+	}
+
+	if update.DisplayName != nil {
+		name := strings.TrimSpace(*update.DisplayName)
+		if utf8.RuneCountInString(name) > MaxDisplayNameLength {
+			return fmt.Errorf("%w: display name is longer than %d characters", ErrInvalidProfile, MaxDisplayNameLength)
+		}
+		for _, r := range name {
+			if unicode.IsControl(r) {
+				return fmt.Errorf("%w: display name contains control characters", ErrInvalidProfile)
+			}
+		}
+		update.DisplayName = &name
+	}
+
+	if update.AvatarUrl != nil && *update.AvatarUrl != "" {
+		if len(*update.AvatarUrl) > MaxAvatarUrlLength {
+			return fmt.Errorf("%w: avatar url is longer than %d characters", ErrInvalidProfile, MaxAvatarUrlLength)
+		}
+		parsed, err := url.Parse(*update.AvatarUrl)
+		if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+			return fmt.Errorf("%w: avatar url must be an absolute http(s) url", ErrInvalidProfile)
+		}
+	}
+
+	if update.Locale != nil && *update.Locale != "" {
+		if !localeRegexp.MatchString(*update.Locale) {
+			return fmt.Errorf("%w: invalid locale", ErrInvalidProfile)
+		}
+		locale := strings.ReplaceAll(*update.Locale, "_", "-")
+		update.Locale = &locale
+	}
+
+	if update.Timezone != nil && *update.Timezone != "" {
+		if _, err := time.LoadLocation(*update.Timezone); err != nil || *update.Timezone == "Local" {
+			return fmt.Errorf("%w: invalid timezone", ErrInvalidProfile)
+		}
+	}
+
+	if update.Country != nil && *update.Country != "" {
+		country := strings.ToUpper(*update.Country)
+		if len(country) != 2 || country[0] < 'A' || country[0] > 'Z' || country[1] < 'A' || country[1] > 'Z' {
+			return fmt.Errorf("%w: country must be ISO 3166-1 alpha-2 code", ErrInvalidProfile)
+		}
+		update.Country = &country
+	}
+
+	if update.Attributes != nil {
+		if len(*update.Attributes) > MaxAttributesSize {
+			return fmt.Errorf("%w: attributes are larger than %d bytes", ErrInvalidProfile, MaxAttributesSize)
+		}
+		var attributes map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(*update.Attributes), &attributes); err != nil {
+			return fmt.Errorf("%w: attributes must be a JSON object", ErrInvalidProfile)
+		}
+	}
+
+	return nil
+}
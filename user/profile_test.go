@@ -0,0 +1,65 @@
+package user
+
+import (
+	"errors"
+	"github.com/savageking-io/ogbuser/schema"
+	"strings"
+	"testing"
+)
+
+func TestValidateProfileUpdate(t *testing.T) {
+	value := func(s string) *string { return &s }
+	tests := []struct {
+		name    string
+		update  *schema.ProfileUpdateSchema
+		want    *schema.ProfileUpdateSchema
+		wantErr bool
+	}{
+		{"Nil update", nil, nil, true},
+		{"Trims display name", &schema.ProfileUpdateSchema{DisplayName: value("  Player  ")}, &schema.ProfileUpdateSchema{DisplayName: value("Player")}, false},
+		{"Long display name", &schema.ProfileUpdateSchema{DisplayName: value(strings.Repeat("я", MaxDisplayNameLength+1))}, nil, true},
+		{"Display name with control characters", &schema.ProfileUpdateSchema{DisplayName: value("Play\ner")}, nil, true},
+		{"Avatar url", &schema.ProfileUpdateSchema{AvatarUrl: value("https://cdn.localhost/a.png")}, &schema.ProfileUpdateSchema{AvatarUrl: value("https://cdn.localhost/a.png")}, false},
+		{"Relative avatar url", &schema.ProfileUpdateSchema{AvatarUrl: value("/a.png")}, nil, true},
+		{"Avatar url with other scheme", &schema.ProfileUpdateSchema{AvatarUrl: value("ftp://cdn.localhost/a.png")}, nil, true},
+		{"Clears avatar url", &schema.ProfileUpdateSchema{AvatarUrl: value("")}, &schema.ProfileUpdateSchema{AvatarUrl: value("")}, false},
+		{"Normalizes locale", &schema.ProfileUpdateSchema{Locale: value("en_US")}, &schema.ProfileUpdateSchema{Locale: value("en-US")}, false},
+		{"Invalid locale", &schema.ProfileUpdateSchema{Locale: value("english!")}, nil, true},
+		{"Timezone", &schema.ProfileUpdateSchema{Timezone: value("Europe/Berlin")}, &schema.ProfileUpdateSchema{Timezone: value("Europe/Berlin")}, false},
+		{"Unknown timezone", &schema.ProfileUpdateSchema{Timezone: value("Mars/Olympus")}, nil, true},
+		{"Local timezone", &schema.ProfileUpdateSchema{Timezone: value("Local")}, nil, true},
+		{"Uppercases country", &schema.ProfileUpdateSchema{Country: value("de")}, &schema.ProfileUpdateSchema{Country: value("DE")}, false},
+		{"Invalid country", &schema.ProfileUpdateSchema{Country: value("DEU")}, nil, true},
+		{"Attributes", &schema.ProfileUpdateSchema{Attributes: value(`{"level": 3}`)}, &schema.ProfileUpdateSchema{Attributes: value(`{"level": 3}`)}, false},
+		{"Attributes aren't an object", &schema.ProfileUpdateSchema{Attributes: value(`[1, 2]`)}, nil, true},
+		{"Large attributes", &schema.ProfileUpdateSchema{Attributes: value(`{"a": "` + strings.Repeat("a", MaxAttributesSize) + `"}`)}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateProfileUpdate(tt.update)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidProfile) {
+					t.Errorf("ValidateProfileUpdate() error = %v, want %v", err, ErrInvalidProfile)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ValidateProfileUpdate() error = %v", err)
+			}
+			got := []*string{tt.update.DisplayName, tt.update.AvatarUrl, tt.update.Locale, tt.update.Timezone, tt.update.Country, tt.update.Attributes}
+			want := []*string{tt.want.DisplayName, tt.want.AvatarUrl, tt.want.Locale, tt.want.Timezone, tt.want.Country, tt.want.Attributes}
+			for i := range got {
+				if (got[i] == nil) != (want[i] == nil) || (got[i] != nil && *got[i] != *want[i]) {
+					t.Errorf("ValidateProfileUpdate() field %d = %s, want %s", i, deref(got[i]), deref(want[i]))
+				}
+			}
+		})
+	}
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return "<nil>"
+	}
+	return *s
+}
@@ -9,27 +9,110 @@ import (
 	"github.com/savageking-io/ogbuser/schema"
 	"github.com/savageking-io/ogbuser/token"
 	log "github.com/sirupsen/logrus"
+	"sync"
+	"time"
 )
 
 type User struct {
-	raw      *schema.UserSchema
-	db       *db.Database
-	perms    *perm.Perm
-	groups   *group.GroupsData
-	sessions []*schema.UserSessionSchema
+	raw          *schema.UserSchema
+	db           *db.Database
+	perms        *perm.Perm       // Effective permissions of all groups. nil until first requested
+	permVersions map[int32]uint64 // Versions of groups perms were built from
+	groups       *group.GroupsData
+	expiries     map[int32]time.Time // Expiry of time-limited memberships by group id
+	sessions     []*schema.UserSessionSchema
+	profile      *schema.ProfileSchema
+	mutex        sync.RWMutex
 }
 
 func NewUser(db *db.Database, data *schema.UserSchema) *User {
 	return &User{
 		raw:    data,
 		db:     db,
-		perms:  perm.NewPerm(),
 		groups: group.NewGroupsData(),
 	}
 }
 
 func (u *User) AddGroup(group *group.Group) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
 	u.groups.Add(group)
+	u.perms = nil
+}
+
+// SetGroups replaces groups of the user, e.g. after membership has changed. Expiries hold the end of
+// time-limited memberships by group id, groups missing from it are permanent
+func (u *User) SetGroups(groups []*group.Group, expiries map[int32]time.Time) {
+	data := group.NewGroupsData()
+	for _, g := range groups {
+		data.Add(g)
+	}
+
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.groups = data
+	u.expiries = expiries
+	u.perms = nil
+}
+
+// GetGroups returns groups the user belongs to ordered by id. Groups of expired memberships are skipped
+// even before the membership is swept from the database
+func (u *User) GetGroups() []*group.Group {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+	groups := u.groups.GetAll()
+	if len(u.expiries) == 0 {
+		return groups
+	}
+
+	now := time.Now()
+	active := groups[:0]
+	for _, g := range groups {
+		if expiresAt, ok := u.expiries[g.GetId()]; ok && !now.Before(expiresAt) {
+			continue
+		}
+		active = append(active, g)
+	}
+	return active
+}
+
+// InGroup reports whether the user belongs to any of the groups
+func (u *User) InGroup(groupIds ...int32) bool {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+	for _, id := range groupIds {
+		if _, ok := u.groups.Get(id); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// GetMembershipExpiry returns when membership of the user in the group ends, nil for permanent membership
+func (u *User) GetMembershipExpiry(groupId int32) *time.Time {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
+	if expiresAt, ok := u.expiries[groupId]; ok {
+		return &expiresAt
+	}
+	return nil
+}
+
+// SuperuserGroup returns the first special group of the user, or nil when the user is not a superuser
+func (u *User) SuperuserGroup() *group.Group {
+	for _, g := range u.GetGroups() {
+		if g.IsSpecial() {
+			return g
+		}
+	}
+	return nil
+}
+
+// InvalidatePermissions drops cached effective permissions so they are rebuilt on the next check
+func (u *User) InvalidatePermissions() {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.perms = nil
 }
 
 func (u *User) GetId() int32 {
@@ -37,15 +120,39 @@ func (u *User) GetId() int32 {
 }
 
 func (u *User) GetUsername() string {
+	u.mutex.RLock()
+	defer u.mutex.RUnlock()
 	return u.raw.Username
 }
 
+// setUsername is used by UsersData on rename so the username index changes together with the user
+func (u *User) setUsername(username string) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	u.raw.Username = username
+}
+
+// GetPassword returns password hash or an empty string if user has no password (e.g. guests)
 func (u *User) GetPassword() string {
-	return u.raw.Password
+	if u.raw.Password == nil {
+		return ""
+	}
+	return *u.raw.Password
+}
+
+func (u *User) HasPassword() bool {
+	return u.raw.Password != nil && *u.raw.Password != ""
 }
 
 func (u *User) GetEmail() string {
-	return u.raw.Email
+	if u.raw.Email == nil {
+		return ""
+	}
+	return *u.raw.Email
+}
+
+func (u *User) IsGuest() bool {
+	return u.raw.IsGuest
 }
 
 func (u *User) GetCreatedAt() string {
@@ -73,6 +180,27 @@ func (u *User) LoadByUsername(ctx context.Context, username string) error {
 	return nil
 }
 
+// LoadByGuestSecret will load a guest account bound to the provided secret hash
+// return ErrUserNotFound if no guest owns the secret
+func (u *User) LoadByGuestSecret(ctx context.Context, secretHash string) error {
+	log.Tracef("User::LoadByGuestSecret")
+	if u.db == nil {
+		return fmt.Errorf("DB is not initialized")
+	}
+
+	result, err := u.db.LoadUserByGuestSecret(ctx, secretHash)
+	if err != nil {
+		return err
+	}
+
+	if result == nil {
+		return fmt.Errorf("nil user schema without an error")
+	}
+
+	u.raw = result
+	return nil
+}
+
 func (u *User) LoadBySteamId(ctx context.Context, steamId string) error {
 	log.Tracef("User::LoadBySteamId: %s", steamId)
 	if u.db == nil {
@@ -122,8 +250,8 @@ func (u *User) InitializeSession(ctx context.Context, inPlatform string) (*schem
 	return session, nil
 }
 
-// LoadGroups will return a list of groups that this user belongs to
-func (u *User) LoadGroups(ctx context.Context) ([]int32, error) {
+// LoadGroups will return memberships of this user that are not expired
+func (u *User) LoadGroups(ctx context.Context) ([]schema.GroupMemberSchema, error) {
 	log.Tracef("User::LoadGroups")
 	if u.db == nil {
 		return nil, fmt.Errorf("DB is not initialized")
@@ -133,13 +261,60 @@ func (u *User) LoadGroups(ctx context.Context) ([]int32, error) {
 		return nil, fmt.Errorf("user is not initialized")
 	}
 
-	return u.db.GetUserGroupIds(ctx, u.raw.Id)
+	return u.db.GetUserMemberships(ctx, u.raw.Id)
 }
 
+// HasPermission returns the permission as granted by all groups of the user together
 func (u *User) HasPermission(ctx context.Context, permission string, domain string) (*perm.Permission, error) {
-	if u.perms == nil {
-		return nil, fmt.Errorf("permissions are not initialized")
+	return u.Permissions().GetPermission(domain, permission), nil
+}
+
+// HasResourcePermission returns the permission as granted to the user for the resource. Every domain in which
+// the user is related to the resource is taken into account
+func (u *User) HasResourcePermission(ctx context.Context, permission string, resource perm.Resource, resolver perm.MembershipResolver) (*perm.Permission, error) {
+	return u.Permissions().Check(ctx, u.GetId(), permission, "", resource, resolver)
+}
+
+// Permissions returns effective permissions of the user: a union of effective permissions of every group,
+// where an access bit is granted if any group grants it. The result is cached until membership changes or
+// one of the groups is initialized again
+func (u *User) Permissions() *perm.Perm {
+	groups := u.GetGroups()
+
+	u.mutex.RLock()
+	perms, versions := u.perms, u.permVersions
+	u.mutex.RUnlock()
+	if perms != nil && permissionsCurrent(groups, versions) {
+		return perms
 	}
 
-	return u.perms.GetPermission(domain, permission), nil
+	perms = perm.NewPerm()
+	versions = make(map[int32]uint64, len(groups))
+	for _, g := range groups {
+		groupPerms, version := g.Snapshot()
+		perms.Merge(groupPerms)
+		versions[g.GetId()] = version
+	}
+
+	u.mutex.Lock()
+	u.perms = perms
+	u.permVersions = versions
+	u.mutex.Unlock()
+
+	log.Debugf("Built permissions of user %d from %d groups: %d permissions", u.GetId(), len(groups), perms.Count())
+	return perms
+}
+
+// permissionsCurrent reports whether permissions built from versions still match the groups
+func permissionsCurrent(groups []*group.Group, versions map[int32]uint64) bool {
+	if len(groups) != len(versions) {
+		return false
+	}
+	for _, g := range groups {
+		_, version := g.Snapshot()
+		if built, ok := versions[g.GetId()]; !ok || built != version {
+			return false
+		}
+	}
+	return true
 }
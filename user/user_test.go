@@ -0,0 +1,42 @@
+package user
+
+import (
+	"github.com/savageking-io/ogbuser/group"
+	"github.com/savageking-io/ogbuser/schema"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUser_GetGroups(t *testing.T) {
+	groups := []*group.Group{
+		group.NewGroupFromSchema(nil, &schema.GroupSchema{Id: 1, Name: "players"}),
+		group.NewGroupFromSchema(nil, &schema.GroupSchema{Id: 2, Name: "vip"}),
+		group.NewGroupFromSchema(nil, &schema.GroupSchema{Id: 3, Name: "moderators"}),
+	}
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		expiries map[int32]time.Time
+		want     []int32
+	}{
+		{"Permanent", nil, []int32{1, 2, 3}},
+		{"Not expired yet", map[int32]time.Time{2: now.Add(time.Hour)}, []int32{1, 2, 3}},
+		{"Expired", map[int32]time.Time{2: now.Add(-time.Second)}, []int32{1, 3}},
+		{"Mixed", map[int32]time.Time{1: now.Add(-time.Hour), 3: now.Add(time.Hour)}, []int32{2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := NewUser(nil, &schema.UserSchema{Id: 10})
+			u.SetGroups(groups, tt.expiries)
+			var got []int32
+			for _, g := range u.GetGroups() {
+				got = append(got, g.GetId())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetGroups() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,21 +2,27 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"github.com/savageking-io/ogbuser/db"
+	"github.com/savageking-io/ogbuser/group"
+	"github.com/savageking-io/ogbuser/schema"
+	log "github.com/sirupsen/logrus"
 	"sync"
+	"time"
 )
 
 type UsersData struct {
-	users        map[int32]User
+	users        map[int32]*User
 	usernameToId map[string]int32
-	mutex        sync.Mutex
+	mutex        sync.RWMutex
 	db           *db.Database
+	groups       *group.GroupsData
 }
 
 func NewUsersData(db *db.Database) *UsersData {
 	return &UsersData{
-		users:        make(map[int32]User),
+		users:        make(map[int32]*User),
 		usernameToId: make(map[string]int32),
 		db:           db,
 	}
@@ -26,6 +32,88 @@ func (u *UsersData) SetDb(db *db.Database) {
 	u.db = db
 }
 
+// SetGroups sets groups known to the service. Users loaded by GetById are attached to them
+func (u *UsersData) SetGroups(groups *group.GroupsData) {
+	u.groups = groups
+}
+
+// AttachGroups loads group membership of the user and attaches groups known to the service,
+// replacing groups the user had before
+func (u *UsersData) AttachGroups(ctx context.Context, user *User) error {
+	if u.groups == nil {
+		return fmt.Errorf("groups are not initialized")
+	}
+
+	memberships, err := user.LoadGroups(ctx)
+	if err != nil {
+		return err
+	}
+
+	var groups []*group.Group
+	expiries := make(map[int32]time.Time)
+	for _, membership := range memberships {
+		userGroup, exists := u.groups.Get(membership.GroupId)
+		if !exists {
+			log.Errorf("UsersData::AttachGroups: Group %d not found for user %d", membership.GroupId, user.GetId())
+			continue
+		}
+		groups = append(groups, userGroup)
+		if membership.ExpiresAt != nil {
+			expiries[membership.GroupId] = *membership.ExpiresAt
+		}
+	}
+	user.SetGroups(groups, expiries)
+
+	return nil
+}
+
+// ReloadGroups refreshes membership of a cached user after it changed in the database.
+// Users that are not cached are ignored
+func (u *UsersData) ReloadGroups(ctx context.Context, id int32) error {
+	user, ok := u.GetCached(id)
+	if !ok {
+		return nil
+	}
+	return u.AttachGroups(ctx, user)
+}
+
+// ReloadAllGroups attaches every cached user to groups known to the service again, e.g. after groups
+// were replaced. Each user switches over on its own, so permission checks of others are not blocked.
+// Returns the number of users reloaded
+func (u *UsersData) ReloadAllGroups(ctx context.Context) (int, error) {
+	u.mutex.RLock()
+	users := make([]*User, 0, len(u.users))
+	for _, user := range u.users {
+		users = append(users, user)
+	}
+	u.mutex.RUnlock()
+
+	var failed []error
+	for _, user := range users {
+		if err := u.AttachGroups(ctx, user); err != nil {
+			failed = append(failed, fmt.Errorf("user %d: %w", user.GetId(), err))
+		}
+	}
+	return len(users) - len(failed), errors.Join(failed...)
+}
+
+// InvalidatePermissions drops cached permissions of users in any of the groups, or of every cached user
+// when no group is given
+func (u *UsersData) InvalidatePermissions(groupIds ...int32) {
+	u.mutex.RLock()
+	users := make([]*User, 0, len(u.users))
+	for _, user := range u.users {
+		users = append(users, user)
+	}
+	u.mutex.RUnlock()
+
+	for _, user := range users {
+		if len(groupIds) == 0 || user.InGroup(groupIds...) {
+			user.InvalidatePermissions()
+		}
+	}
+}
+
 func (u *UsersData) Add(user *User) error {
 	if user == nil {
 		return fmt.Errorf("user is nil")
@@ -38,7 +126,11 @@ func (u *UsersData) Add(user *User) error {
 	}
 	defer u.mutex.Unlock()
 	u.mutex.Lock()
-	u.users[int32(user.GetId())] = *user
+	if existing, ok := u.users[user.GetId()]; ok && existing.GetUsername() != user.GetUsername() {
+		// Username changed since the user was cached, e.g. after a guest upgrade
+		delete(u.usernameToId, existing.GetUsername())
+	}
+	u.users[int32(user.GetId())] = user
 	u.usernameToId[user.GetUsername()] = user.GetId()
 	return nil
 }
@@ -46,16 +138,25 @@ func (u *UsersData) Add(user *User) error {
 func (u *UsersData) Delete(id int32) error {
 	defer u.mutex.Unlock()
 	u.mutex.Lock()
-	if _, ok := u.users[id]; ok {
+	if existing, ok := u.users[id]; ok {
 		delete(u.users, id)
+		delete(u.usernameToId, existing.GetUsername())
 		return nil
 	}
 	return db.ErrUserNotFound
 }
 
+// GetCached returns the user only if it's already in the cache
+func (u *UsersData) GetCached(id int32) (*User, bool) {
+	defer u.mutex.RUnlock()
+	u.mutex.RLock()
+	user, ok := u.users[id]
+	return user, ok
+}
+
 func (u *UsersData) GetById(id int32) (*User, error) {
-	if user, ok := u.users[id]; ok {
-		return &user, nil
+	if user, ok := u.GetCached(id); ok {
+		return user, nil
 	}
 
 	if u.db == nil {
@@ -72,14 +173,37 @@ func (u *UsersData) GetById(id int32) (*User, error) {
 	}
 
 	newUser := NewUser(u.db, userSchema)
+	if u.groups != nil {
+		if err := u.AttachGroups(context.Background(), newUser); err != nil {
+			return nil, err
+		}
+	}
 	if err := u.Add(newUser); err != nil {
 		return nil, err
 	}
 	return u.GetById(id)
 }
 
+// Rename updates username of the cached user and moves it in the username index,
+// so the old username no longer resolves. Users that are not cached are ignored
+func (u *UsersData) Rename(id int32, username string) {
+	defer u.mutex.Unlock()
+	u.mutex.Lock()
+	user, ok := u.users[id]
+	if !ok {
+		return
+	}
+	if u.usernameToId[user.GetUsername()] == id {
+		delete(u.usernameToId, user.GetUsername())
+	}
+	user.setUsername(username)
+	u.usernameToId[username] = id
+}
+
 func (u *UsersData) GetByUsername(username string) (*User, error) {
+	u.mutex.RLock()
 	userId, ok := u.usernameToId[username]
+	u.mutex.RUnlock()
 	if !ok {
 		return nil, db.ErrUserNotFound
 	}
@@ -88,5 +212,45 @@ func (u *UsersData) GetByUsername(username string) (*User, error) {
 		return nil, fmt.Errorf("invalid user id - can't be zero")
 	}
 
-	return u.GetById(userId)
+	user, err := u.GetById(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	// Guard against a stale index entry pointing to a renamed user
+	if user.GetUsername() != username {
+		return nil, db.ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+// GetProfiles returns profiles of cached users that have their profile loaded
+// and the list of ids that have to be requested from the database
+func (u *UsersData) GetProfiles(ids []int32) (map[int32]*schema.ProfileSchema, []int32) {
+	found := make(map[int32]*schema.ProfileSchema, len(ids))
+	var missing []int32
+
+	defer u.mutex.RUnlock()
+	u.mutex.RLock()
+	for _, id := range ids {
+		if user, ok := u.users[id]; ok {
+			if profile := user.GetProfile(); profile != nil {
+				found[id] = profile
+				continue
+			}
+		}
+		missing = append(missing, id)
+	}
+	return found, missing
+}
+
+// SetProfile stores profile on the cached user. Profiles of users that are not cached are ignored
+func (u *UsersData) SetProfile(profile *schema.ProfileSchema) {
+	if profile == nil {
+		return
+	}
+	if user, ok := u.GetCached(profile.UserId); ok {
+		user.SetProfile(profile)
+	}
 }
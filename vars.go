@@ -5,6 +5,7 @@ import (
 	steam "github.com/savageking-io/ogbsteam/client"
 	"github.com/savageking-io/ogbuser/db"
 	"github.com/savageking-io/ogbuser/kafka"
+	"github.com/savageking-io/ogbuser/naming"
 	"github.com/savageking-io/ogbuser/token"
 )
 
@@ -23,6 +24,12 @@ type ServiceConfig struct {
 	Crypto      CryptoConfig                   `yaml:"crypto"`
 	Kafka       kafka.Config                   `yaml:"kafka"`
 	SteamClient steam.Config                   `yaml:"steam_client"`
+	Guest       GuestConfig                    `yaml:"guest"`
+	Usernames   naming.Config                  `yaml:"usernames"`
+	Accounts    AccountsConfig                 `yaml:"accounts"`
+	Erasure     ErasureConfig                  `yaml:"erasure"`
+	Groups      GroupsConfig                   `yaml:"groups"`
+	Policy      PolicyConfig                   `yaml:"policy"`
 }
 
 type RpcConfig struct {
@@ -30,6 +37,34 @@ type RpcConfig struct {
 	Port     uint16 `yaml:"port"`
 }
 
+type GuestConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	UsernamePrefix string `yaml:"username_prefix"` // Generated usernames look like <prefix><random hex>
+	GroupId        int32  `yaml:"group_id"`        // Group new guests are added to. 0 to skip
+}
+
+type AccountsConfig struct {
+	RestoreDays          int    `yaml:"restore_days"`           // How long a deleted user can be restored
+	RetentionDays        int    `yaml:"retention_days"`         // Deleted users are purged after this many days. Never less than restore_days
+	PurgeMode            string `yaml:"purge_mode"`             // "anonymize" or "delete"
+	PurgeIntervalMinutes int    `yaml:"purge_interval_minutes"` // How often the purge job runs. 0 disables it
+	PurgeBatchSize       int    `yaml:"purge_batch_size"`       // Users purged in a single transaction
+}
+
+type ErasureConfig struct {
+	Services []string `yaml:"services"` // Services that must acknowledge every erasure request
+}
+
+type GroupsConfig struct {
+	ListenChanges bool `yaml:"listen_changes"` // Reload groups when the database notifies about changes
+}
+
+type PolicyConfig struct {
+	File                  string `yaml:"file"`                    // Policy file applied on start. Empty disables it
+	ReloadIntervalSeconds int    `yaml:"reload_interval_seconds"` // How often the file is checked for changes. 0 applies it on start only
+	Prune                 bool   `yaml:"prune"`                   // Delete groups that are not declared in the file
+}
+
 type CryptoConfig struct {
 	Argon ArgonConfig  `yaml:"argon"`
 	JWT   token.Config `yaml:"jwt"`